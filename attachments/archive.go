@@ -0,0 +1,160 @@
+// Package attachments keeps copies of the files posted on Discord, whose CDN links stop working
+// once the message is deleted, so the logs and moderation tools can still show them.
+package attachments
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/models"
+)
+
+// ErrNoStore attachments are archived before the store was set up
+var ErrNoStore = errors.New("the attachment store isn't set up")
+
+// DefaultStore where attachments are archived, set up by Init
+var DefaultStore Store
+
+// MaxSize Files bigger than this are left on Discord
+var MaxSize = 100 * 1024 * 1024
+
+var downloadClient = &http.Client{
+	Timeout: 2 * time.Minute,
+}
+
+// Init set up the store configured in the environment
+func Init() {
+	DefaultStore = NewStoreFromEnv()
+	switch store := DefaultStore.(type) {
+	case *S3Store:
+		log.Printf("Archiving attachments into bucket %s at %s", store.Bucket, store.Endpoint)
+	case *LocalStore:
+		log.Printf("Archiving attachments into %s", store.Dir)
+	}
+}
+
+// storeKey where a file is kept in the store, by its hash, so the same file is only kept once
+func storeKey(hash string) string {
+	return "attachments/" + hash[:2] + "/" + hash
+}
+
+// mimeType the type of a file, by its content, or its name when the content doesn't tell
+func mimeType(filename string, data []byte) string {
+	detected := http.DetectContentType(data)
+	if detected == "application/octet-stream" || detected == "text/plain; charset=utf-8" {
+		if byName := mime.TypeByExtension(filepath.Ext(filename)); byName != "" {
+			return byName
+		}
+	}
+	return detected
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := downloadClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download returned %s", resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, int64(MaxSize)+1))
+}
+
+// ArchiveMessage archive all of a message's attachments
+func ArchiveMessage(db *mgo.Database, msg *discordgo.Message) {
+	for _, att := range msg.Attachments {
+		_, err := Archive(db, msg, att)
+		if err != nil {
+			log.Printf("Failed to archive attachment %s of message %s, %s", att.Filename, msg.ID, err)
+		}
+	}
+}
+
+// Archive download an attachment into the store, unless a file with the same content is already there
+func Archive(db *mgo.Database, msg *discordgo.Message, att *discordgo.MessageAttachment) (models.ArchivedAttachment, error) {
+	col := db.C("archived_attachments")
+
+	aa := models.ArchivedAttachment{}
+	err := col.Find(bson.M{"attachment_id": att.ID}).One(&aa)
+	if err == nil {
+		return aa, nil
+	}
+	if err != mgo.ErrNotFound {
+		return aa, err
+	}
+
+	if DefaultStore == nil {
+		return aa, ErrNoStore
+	}
+	if att.Size > MaxSize {
+		return aa, fmt.Errorf("the file is %d bytes, over the limit of %d", att.Size, MaxSize)
+	}
+
+	data, err := download(att.URL)
+	if err != nil {
+		return aa, err
+	}
+	if len(data) > MaxSize {
+		return aa, fmt.Errorf("the file is over the limit of %d bytes", MaxSize)
+	}
+
+	hash := sha256Hex(data)
+	contentType := mimeType(att.Filename, data)
+	key := storeKey(hash)
+
+	n, err := col.Find(bson.M{"hash": hash}).Count()
+	if err != nil {
+		return aa, err
+	}
+	if n == 0 {
+		err = DefaultStore.Put(key, contentType, data)
+		if err != nil {
+			return aa, err
+		}
+	}
+
+	aa = models.ArchivedAttachment{
+		AttachmentID: att.ID,
+		MessageID:    msg.ID,
+		ChannelID:    msg.ChannelID,
+		GuildID:      msg.GuildID,
+		Filename:     att.Filename,
+		MimeType:     contentType,
+		Size:         len(data),
+		Hash:         hash,
+		Key:          key,
+		ArchivedAt:   time.Now(),
+	}
+	if msg.Author != nil {
+		aa.AuthorID = msg.Author.ID
+		aa.AuthorName = msg.Author.Username + "#" + msg.Author.Discriminator
+	}
+	err = col.Insert(aa)
+	return aa, err
+}
+
+// ForMessage the archived attachments of a message, in the order they were posted
+func ForMessage(db *mgo.Database, messageID string) ([]models.ArchivedAttachment, error) {
+	aas := []models.ArchivedAttachment{}
+	err := db.C("archived_attachments").Find(bson.M{"message_id": messageID}).Sort("_id").All(&aas)
+	return aas, err
+}
+
+// Open read an archived attachment from the store
+func Open(aa models.ArchivedAttachment) (io.ReadCloser, error) {
+	if DefaultStore == nil {
+		return nil, ErrNoStore
+	}
+	return DefaultStore.Get(aa.Key)
+}
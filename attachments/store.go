@@ -0,0 +1,217 @@
+package attachments
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound the store doesn't have the file
+var ErrNotFound = errors.New("file not found in the attachment store")
+
+// Store Somewhere archived files are kept
+type Store interface {
+	Put(key, contentType string, data []byte) error
+	Get(key string) (io.ReadCloser, error)
+}
+
+// LocalStore Keeps files in a directory on disk
+type LocalStore struct {
+	Dir string
+}
+
+func (ls *LocalStore) path(key string) string {
+	return filepath.Join(ls.Dir, filepath.FromSlash(key))
+}
+
+// Put write a file, replacing it if it's already there
+func (ls *LocalStore) Put(key, contentType string, data []byte) error {
+	path := ls.path(key)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	// Written under a temporary name first, so a crash can't leave half a file behind
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get open a file
+func (ls *LocalStore) Get(key string) (io.ReadCloser, error) {
+	f, err := os.Open(ls.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// S3Store Keeps files in a bucket of an S3 compatible service, addressed by path so it works with any of them
+type S3Store struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	Client *http.Client
+}
+
+// Put upload a file, replacing it if it's already there
+func (s3 *S3Store) Put(key, contentType string, data []byte) error {
+	resp, err := s3.do("PUT", key, contentType, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload returned %s: %s", resp.Status, body)
+	}
+	return nil
+}
+
+// Get download a file
+func (s3 *S3Store) Get(key string) (io.ReadCloser, error) {
+	resp, err := s3.do("GET", key, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download returned %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (s3 *S3Store) do(method, key, contentType string, data []byte) (*http.Response, error) {
+	url := strings.TrimRight(s3.Endpoint, "/") + "/" + s3.Bucket + "/" + key
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s3.sign(req, data, time.Now().UTC())
+
+	client := s3.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signingKey the AWS Signature Version 4 key for a day, region and service
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// sign add an AWS Signature Version 4 to a request
+func (s3 *S3Store) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		headers = append([]string{"content-type"}, headers...)
+		values["content-type"] = ct
+	}
+	canonicalHeaders := ""
+	for _, h := range headers {
+		canonicalHeaders += h + ":" + strings.TrimSpace(values[h]) + "\n"
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s3.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey(s3.SecretKey, date, s3.Region, "s3"), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s3.AccessKey, scope, signedHeaders, signature))
+}
+
+// NewStoreFromEnv the store configured in the environment.
+// DELUBOT_S3_BUCKET selects an S3 compatible store, along with DELUBOT_S3_ENDPOINT, DELUBOT_S3_REGION,
+// DELUBOT_S3_ACCESS_KEY and DELUBOT_S3_SECRET_KEY, otherwise files are kept in DELUBOT_ARCHIVE_DIR, ./archive by default.
+func NewStoreFromEnv() Store {
+	bucket := os.Getenv("DELUBOT_S3_BUCKET")
+	if bucket != "" {
+		s3 := &S3Store{
+			Endpoint:  os.Getenv("DELUBOT_S3_ENDPOINT"),
+			Region:    os.Getenv("DELUBOT_S3_REGION"),
+			Bucket:    bucket,
+			AccessKey: os.Getenv("DELUBOT_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("DELUBOT_S3_SECRET_KEY"),
+			Client: &http.Client{
+				Timeout: time.Minute,
+			},
+		}
+		if s3.Endpoint == "" {
+			s3.Endpoint = "https://s3.amazonaws.com"
+		}
+		if s3.Region == "" {
+			s3.Region = "us-east-1"
+		}
+		return s3
+	}
+
+	dir := os.Getenv("DELUBOT_ARCHIVE_DIR")
+	if dir == "" {
+		dir = "archive"
+	}
+	return &LocalStore{Dir: dir}
+}
@@ -0,0 +1,115 @@
+package attachments
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_SigningKey(t *testing.T) {
+	// The example from the AWS Signature Version 4 documentation
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if hex.EncodeToString(key) != want {
+		t.Fatalf("got %x, want %s", key, want)
+	}
+}
+
+func Test_LocalStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &LocalStore{Dir: dir}
+
+	_, err = store.Get("attachments/ab/missing")
+	if err != ErrNotFound {
+		t.Fatalf("got %v for a missing file, want ErrNotFound", err)
+	}
+
+	err = store.Put("attachments/ab/abcdef", "image/png", []byte("png data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := store.Get("attachments/ab/abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, _ := ioutil.ReadAll(r)
+	if string(data) != "png data" {
+		t.Fatalf("got %q back", data)
+	}
+}
+
+func Test_S3Store(t *testing.T) {
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") || !strings.Contains(auth, "/us-east-1/s3/aws4_request") {
+			t.Errorf("bad authorization %q", auth)
+		}
+		switch r.Method {
+		case "PUT":
+			data, _ := ioutil.ReadAll(r.Body)
+			if r.Header.Get("x-amz-content-sha256") != sha256Hex(data) {
+				t.Errorf("bad payload hash")
+			}
+			objects[r.URL.Path] = data
+		case "GET":
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	store := &S3Store{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    "delubot",
+		AccessKey: "access",
+		SecretKey: "secret",
+	}
+
+	err := store.Put("attachments/ab/abcdef", "image/png", []byte("png data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["/delubot/attachments/ab/abcdef"]; !ok {
+		t.Fatal("the file wasn't uploaded into the bucket")
+	}
+
+	r, err := store.Get("attachments/ab/abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, _ := ioutil.ReadAll(r)
+	if string(data) != "png data" {
+		t.Fatalf("got %q back", data)
+	}
+
+	_, err = store.Get("attachments/ab/missing")
+	if err != ErrNotFound {
+		t.Fatalf("got %v for a missing file, want ErrNotFound", err)
+	}
+}
+
+func Test_MimeType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if got := mimeType("image.bin", png); got != "image/png" {
+		t.Errorf("got %s for a png", got)
+	}
+	if got := mimeType("clip.webm", []byte{0x01, 0x02, 0x03}); got != "video/webm" {
+		t.Errorf("got %s for a webm by name", got)
+	}
+}
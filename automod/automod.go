@@ -0,0 +1,252 @@
+// Package automod checks messages against the rules a guild set up for them,
+// the bot decides what to do with the ones that break a rule.
+package automod
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/models"
+)
+
+// How long a guild's rules are used before they're read again
+var rulesCacheTTL = 5 * time.Minute
+
+// The longest a rule can count repeats or attachments over
+var MaxWindow = 10 * time.Minute
+
+// How many recent messages are kept of each person
+var maxRecent = 50
+
+var inviteRE = regexp.MustCompile(`(?i)(discord\.gg|discord(?:app)?\.com/invite)/[\w-]+`)
+
+var rulesCache = struct {
+	sync.Mutex
+	byGuild  map[string][]models.AutomodRule
+	loadedAt map[string]time.Time
+}{byGuild: make(map[string][]models.AutomodRule), loadedAt: make(map[string]time.Time)}
+
+var patternCache = struct {
+	sync.Mutex
+	byPattern map[string]*regexp.Regexp
+}{byPattern: make(map[string]*regexp.Regexp)}
+
+// Message What automod looks at in a message
+type Message struct {
+	Content     string
+	Mentions    int
+	Attachments int
+	AccountAge  time.Duration
+	Time        time.Time
+}
+
+// Rules the automod rules of a guild
+func Rules(db *mgo.Database, guildID string) ([]models.AutomodRule, error) {
+	rulesCache.Lock()
+	defer rulesCache.Unlock()
+
+	if loadedAt, ok := rulesCache.loadedAt[guildID]; ok && time.Since(loadedAt) < rulesCacheTTL {
+		return rulesCache.byGuild[guildID], nil
+	}
+
+	rules := []models.AutomodRule{}
+	err := db.C("automod_rules").Find(bson.M{"guild_id": guildID}).Sort("name").All(&rules)
+	if err != nil {
+		return nil, err
+	}
+	rulesCache.byGuild[guildID] = rules
+	rulesCache.loadedAt[guildID] = time.Now()
+	return rules, nil
+}
+
+// Forget drop the cached rules of a guild, after they changed
+func Forget(guildID string) {
+	rulesCache.Lock()
+	defer rulesCache.Unlock()
+	delete(rulesCache.loadedAt, guildID)
+}
+
+// wordsPattern matches any of the words on their own, words in scripts without spaces match anywhere
+func wordsPattern(words []string) string {
+	quoted := []string{}
+	for _, w := range words {
+		quoted = append(quoted, regexp.QuoteMeta(w))
+	}
+	return `(?i)(?:^|\W)(` + strings.Join(quoted, "|") + `)(?:$|\W)`
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	patternCache.Lock()
+	defer patternCache.Unlock()
+
+	if re, ok := patternCache.byPattern[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.byPattern[pattern] = re
+	return re, nil
+}
+
+// Validate whether a rule can be checked, and does something
+func Validate(rule models.AutomodRule) error {
+	switch rule.Type {
+	case models.AutomodWords:
+		if len(rule.Words) == 0 {
+			return errors.New("Give the words to look for, separated by commas")
+		}
+	case models.AutomodRegex:
+		if rule.Pattern == "" {
+			return errors.New("Give the regex to look for")
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("The regex doesn't work, %s", err)
+		}
+	case models.AutomodInvites:
+	case models.AutomodMentions, models.AutomodRepeat, models.AutomodAttachments, models.AutomodNewAccount:
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("Give a number for %s", rule.Type)
+		}
+		if rule.Window > MaxWindow {
+			return fmt.Errorf("Counting can't go back further than %s", MaxWindow)
+		}
+	default:
+		return fmt.Errorf("There's no rule type `%s`", rule.Type)
+	}
+
+	if len(rule.Actions) == 0 {
+		return errors.New("The rule doesn't do anything")
+	}
+	for _, action := range rule.Actions {
+		switch action {
+		case models.AutomodDelete, models.AutomodWarn, models.AutomodMute, models.AutomodLog:
+		default:
+			return fmt.Errorf("There's no action `%s`", action)
+		}
+	}
+	return nil
+}
+
+// Check why a message breaks a rule, "" if it doesn't.
+// Recent are the author's messages in the guild, the latest one being the message itself.
+func Check(rule models.AutomodRule, msg Message, recent []Message) string {
+	switch rule.Type {
+	case models.AutomodWords, models.AutomodRegex:
+		pattern := rule.Pattern
+		if rule.Type == models.AutomodWords {
+			pattern = wordsPattern(rule.Words)
+		}
+		re, err := compile(pattern)
+		if err != nil {
+			return ""
+		}
+		if match := re.FindStringSubmatch(msg.Content); match != nil {
+			found := match[0]
+			if len(match) > 1 && match[1] != "" {
+				found = match[1]
+			}
+			return fmt.Sprintf("contains `%s`", found)
+		}
+	case models.AutomodInvites:
+		if match := inviteRE.FindString(msg.Content); match != "" {
+			return fmt.Sprintf("invite link %s", match)
+		}
+	case models.AutomodMentions:
+		if msg.Mentions >= rule.Threshold {
+			return fmt.Sprintf("%d mentions", msg.Mentions)
+		}
+	case models.AutomodRepeat:
+		if strings.TrimSpace(msg.Content) == "" {
+			return ""
+		}
+		n := 0
+		for _, prev := range within(recent, msg.Time, rule.Window) {
+			if strings.EqualFold(strings.TrimSpace(prev.Content), strings.TrimSpace(msg.Content)) {
+				n++
+			}
+		}
+		if n >= rule.Threshold {
+			return fmt.Sprintf("the same message %d times", n)
+		}
+	case models.AutomodAttachments:
+		if msg.Attachments == 0 {
+			return ""
+		}
+		n := 0
+		for _, prev := range within(recent, msg.Time, rule.Window) {
+			n += prev.Attachments
+		}
+		if n >= rule.Threshold {
+			return fmt.Sprintf("%d attachments", n)
+		}
+	case models.AutomodNewAccount:
+		if msg.AccountAge < time.Duration(rule.Threshold)*24*time.Hour {
+			return fmt.Sprintf("account is %d days old", int(msg.AccountAge/(24*time.Hour)))
+		}
+	}
+	return ""
+}
+
+// within the messages sent in the window up to a time, a minute if the rule has no window
+func within(recent []Message, now time.Time, window time.Duration) []Message {
+	if window <= 0 {
+		window = time.Minute
+	}
+	res := []Message{}
+	for _, msg := range recent {
+		if now.Sub(msg.Time) < window {
+			res = append(res, msg)
+		}
+	}
+	return res
+}
+
+// Tracker Remembers everyone's recent messages, to catch them repeating themselves
+type Tracker struct {
+	sync.Mutex
+	recent map[string][]Message
+}
+
+// NewTracker a tracker that remembers nothing yet
+func NewTracker() *Tracker {
+	return &Tracker{recent: make(map[string][]Message)}
+}
+
+// Add remember a message, returning the recent messages of the same key including it
+func (t *Tracker) Add(key string, msg Message) []Message {
+	t.Lock()
+	defer t.Unlock()
+
+	recent := []Message{}
+	for _, prev := range t.recent[key] {
+		if msg.Time.Sub(prev.Time) < MaxWindow {
+			recent = append(recent, prev)
+		}
+	}
+	recent = append(recent, msg)
+	if len(recent) > maxRecent {
+		recent = recent[len(recent)-maxRecent:]
+	}
+	t.recent[key] = recent
+
+	// Forget the people who went quiet, now and then
+	if len(t.recent) > 10000 {
+		for k, msgs := range t.recent {
+			if msg.Time.Sub(msgs[len(msgs)-1].Time) >= MaxWindow {
+				delete(t.recent, k)
+			}
+		}
+	}
+
+	res := make([]Message, len(recent))
+	copy(res, recent)
+	return res
+}
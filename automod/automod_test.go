@@ -0,0 +1,97 @@
+package automod
+
+import (
+	"testing"
+	"time"
+
+	"github.com/w8kerr/delubot/models"
+)
+
+func Test_Check(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := 365 * 24 * time.Hour
+
+	tests := []struct {
+		name string
+		rule models.AutomodRule
+		msg  Message
+		want bool
+	}{
+		{"word", models.AutomodRule{Type: models.AutomodWords, Words: []string{"scam"}}, Message{Content: "this is a SCAM!"}, true},
+		{"word inside another", models.AutomodRule{Type: models.AutomodWords, Words: []string{"scam"}}, Message{Content: "scampi for dinner"}, false},
+		{"japanese word", models.AutomodRule{Type: models.AutomodWords, Words: []string{"詐欺"}}, Message{Content: "これは詐欺です"}, true},
+		{"regex", models.AutomodRule{Type: models.AutomodRegex, Pattern: `free\s+nitro`}, Message{Content: "get free  nitro here"}, true},
+		{"invite", models.AutomodRule{Type: models.AutomodInvites}, Message{Content: "join discord.gg/abc-123"}, true},
+		{"no invite", models.AutomodRule{Type: models.AutomodInvites}, Message{Content: "join us on discord"}, false},
+		{"mentions", models.AutomodRule{Type: models.AutomodMentions, Threshold: 5}, Message{Mentions: 6}, true},
+		{"few mentions", models.AutomodRule{Type: models.AutomodMentions, Threshold: 5}, Message{Mentions: 2}, false},
+		{"new account", models.AutomodRule{Type: models.AutomodNewAccount, Threshold: 7}, Message{AccountAge: 2 * 24 * time.Hour}, true},
+		{"old account", models.AutomodRule{Type: models.AutomodNewAccount, Threshold: 7}, Message{AccountAge: old}, false},
+	}
+
+	for _, test := range tests {
+		test.msg.Time = now
+		got := Check(test.rule, test.msg, []Message{test.msg})
+		if (got != "") != test.want {
+			t.Errorf("%s: got %q", test.name, got)
+		}
+	}
+}
+
+func Test_Tracker(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	repeat := models.AutomodRule{Type: models.AutomodRepeat, Threshold: 3, Window: time.Minute}
+	attachments := models.AutomodRule{Type: models.AutomodAttachments, Threshold: 5, Window: time.Minute}
+
+	tracker := NewTracker()
+	var recent []Message
+	for i := 0; i < 3; i++ {
+		msg := Message{Content: "buy now", Time: now.Add(time.Duration(i) * 10 * time.Second)}
+		recent = tracker.Add("guild:user", msg)
+		got := Check(repeat, msg, recent)
+		if (got != "") != (i == 2) {
+			t.Errorf("repeat %d: got %q", i+1, got)
+		}
+	}
+
+	// Long enough after, the earlier repeats don't count anymore
+	msg := Message{Content: "buy now", Time: now.Add(5 * time.Minute)}
+	recent = tracker.Add("guild:user", msg)
+	if got := Check(repeat, msg, recent); got != "" {
+		t.Errorf("repeat after the window: got %q", got)
+	}
+
+	// Someone else saying the same isn't a repeat of theirs
+	other := tracker.Add("guild:other", msg)
+	if len(other) != 1 {
+		t.Errorf("got %d recent messages of someone else", len(other))
+	}
+
+	tracker.Add("guild:poster", Message{Attachments: 3, Time: now})
+	msg = Message{Attachments: 2, Time: now.Add(20 * time.Second)}
+	recent = tracker.Add("guild:poster", msg)
+	if got := Check(attachments, msg, recent); got == "" {
+		t.Error("5 attachments in a minute weren't caught")
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	valid := models.AutomodRule{Type: models.AutomodRegex, Pattern: "spam+", Actions: []string{models.AutomodDelete}}
+	if err := Validate(valid); err != nil {
+		t.Errorf("valid rule: %s", err)
+	}
+
+	invalid := []models.AutomodRule{
+		{Type: models.AutomodRegex, Pattern: "(", Actions: []string{models.AutomodDelete}},
+		{Type: models.AutomodWords, Actions: []string{models.AutomodDelete}},
+		{Type: models.AutomodMentions, Actions: []string{models.AutomodDelete}},
+		{Type: models.AutomodInvites},
+		{Type: models.AutomodInvites, Actions: []string{"explode"}},
+		{Type: "vibes", Actions: []string{models.AutomodDelete}},
+	}
+	for _, rule := range invalid {
+		if err := Validate(rule); err == nil {
+			t.Errorf("invalid rule %+v passed", rule)
+		}
+	}
+}
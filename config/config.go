@@ -9,6 +9,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -59,6 +62,29 @@ type TweetSyncConfig struct {
 	ChannelID        string `json:"channel_id" bson:"channel_id"`
 	ControlChannelID string `json:"control_channel_id" bson:"control_channel_id"`
 	SinceID          int64  `json:"since_id" bson:"since_id"`
+	Source           string `json:"source" bson:"source"`
+	FeedURL          string `json:"feed_url" bson:"feed_url"`
+	Cursor           string `json:"cursor" bson:"cursor"`
+	Paused           bool   `json:"paused" bson:"paused"`
+}
+
+// FeedSource the type of feed this sync follows, Twitter unless configured otherwise
+func (ts *TweetSyncConfig) FeedSource() string {
+	if ts.Source == "" {
+		return models.FeedSourceTwitter
+	}
+	return ts.Source
+}
+
+// FeedCursor the position in the feed that has already been mirrored
+func (ts *TweetSyncConfig) FeedCursor() string {
+	if ts.FeedSource() == models.FeedSourceTwitter {
+		if ts.SinceID == 0 {
+			return ""
+		}
+		return strconv.FormatInt(ts.SinceID, 10)
+	}
+	return ts.Cursor
 }
 
 type TweetUpdate struct {
@@ -90,12 +116,68 @@ type CopyPipeline struct {
 
 	YoutubeVideoTitle string `json:"youtube_video_title" bson:"youtube_video_title"`
 	YoutubeLivechatID string `json:"youtube_livechat_id" bson:"youtube_livechat_id"`
+
+	// The Youtube account to copy with, any account in turn if empty
+	PosterEmail string `json:"poster_email" bson:"poster_email"`
+
+	// Which live chat messages a relay posts, by author role and by keyword
+	RelayRoles    []string `json:"relay_roles" bson:"relay_roles"`
+	RelayKeywords []string `json:"relay_keywords" bson:"relay_keywords"`
+}
+
+// Copy pipeline types
+const (
+	CopyToYoutube   = "youtube"
+	CopyFromYoutube = "youtube_relay"
+)
+
+// YoutubeChannelConfig A Youtube channel whose streams go on a guild's schedule
+type YoutubeChannelConfig struct {
+	ChannelID string `json:"channel_id" bson:"channel_id"`
+	Name      string `json:"name" bson:"name"`
+}
+
+// StreamNotifyConfig A channel that opted in to stream notifications
+type StreamNotifyConfig struct {
+	GuildID       string   `json:"guild_id" bson:"guild_id"`
+	ChannelID     string   `json:"channel_id" bson:"channel_id"`
+	RoleID        string   `json:"role_id" bson:"role_id"`
+	Events        []string `json:"events" bson:"events"`
+	MinutesBefore int      `json:"minutes_before" bson:"minutes_before"`
+}
+
+// Wants whether the channel should be notified of the event
+func (snc *StreamNotifyConfig) Wants(event string) bool {
+	for _, e := range snc.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
 }
 
+// MirrorConfig A channel whose messages are copied into another channel.
+// With Webhook the copies keep the author's name and avatar, otherwise the bot posts them with the author's name in front.
+type MirrorConfig struct {
+	SourceChannelID string    `json:"source_channel_id" bson:"source_channel_id"`
+	DestChannelID   string    `json:"dest_channel_id" bson:"dest_channel_id"`
+	Webhook         bool      `json:"webhook" bson:"webhook"`
+	CreatedBy       string    `json:"created_by" bson:"created_by"`
+	CreatedAt       time.Time `json:"created_at" bson:"created_at"`
+}
+
+// YoutubeCredential A Youtube account the bot can post with.
+// Tokens are never printed, accounts linked with ytlink store them encrypted.
 type YoutubeCredential struct {
 	Email        string `json:"email" bson:"email"`
-	OauthToken   string `json:"oauth_token" bson:"oauth_token"`
-	RefreshToken string `json:"refresh_token" bson:"refresh_token"`
+	OauthToken   string `json:"-" bson:"oauth_token"`
+	RefreshToken string `json:"-" bson:"refresh_token"`
+
+	EncryptedOauthToken   string    `json:"-" bson:"encrypted_oauth_token,omitempty"`
+	EncryptedRefreshToken string    `json:"-" bson:"encrypted_refresh_token,omitempty"`
+	Expiry                time.Time `json:"expiry" bson:"expiry,omitempty"`
+	LinkedBy              string    `json:"linked_by" bson:"linked_by,omitempty"`
+	LinkedAt              time.Time `json:"linked_at" bson:"linked_at,omitempty"`
 }
 
 var GrantRoles = map[string]RoleConfig{
@@ -110,6 +192,12 @@ var GrantRoles = map[string]RoleConfig{
 
 var SyncSheets = map[string]string{}
 
+var YoutubeChannels = map[string][]YoutubeChannelConfig{
+	"755437328515989564": { // DFS
+		{ChannelID: "UC7YXqPO3eUnxbJ6rN0z2z1Q", Name: "Delutaya"},
+	},
+}
+
 var RoleGrantEnabled = map[string]bool{}
 var RoleRemoveEnabled = map[string]bool{}
 
@@ -135,6 +223,10 @@ var GoogleSecret string
 
 var YoutubeCredentials []YoutubeCredential
 
+// Where Youtube accounts are linked, configurable so linking can be tried against a stub
+var GoogleDeviceCodeURL = "https://oauth2.googleapis.com/device/code"
+var GoogleTokenURL = "https://oauth2.googleapis.com/token"
+
 var EightBallEnabled bool
 
 var Loc *time.Location
@@ -145,6 +237,9 @@ var CreatorID = "204752740503650304"
 
 var TweetSyncChannels = []TweetSyncConfig{}
 
+// Guards TweetSyncChannels, the commands change it while the scanners read it
+var tweetSyncMu sync.RWMutex
+
 var TweetUpdates = make(map[string]TweetUpdate)
 
 var Extractions = make(map[string]Extraction)
@@ -153,25 +248,55 @@ var CopyPipelines = []CopyPipeline{}
 
 var DoubleTL = false
 
+var StreamNotifications = []StreamNotifyConfig{}
+
+var Mirrors = []MirrorConfig{}
+
+// ModLogEnabled whether deletes and edits are posted to a guild's log channel
+var ModLogEnabled = map[string]bool{}
+
+// ModLogIgnored the channels of a guild whose deletes and edits aren't posted to the log channel
+var ModLogIgnored = map[string][]string{}
+
+// ArchiveEnabled whether attachments posted in a guild are downloaded into the attachment store
+var ArchiveEnabled = map[string]bool{
+	"755437328515989564": true, // DFS
+}
+
+// LogRetention how many days message logs are kept, by channel or guild ID, 0 keeps them forever
+var LogRetention = make(map[string]int)
+
+// Guards LogRetention, the logs command changes it while messages are being logged
+var logRetentionMu sync.RWMutex
+
 type BotConfig struct {
-	ModeratorRoles         map[string][]string   `json:"moderator_roles" bson:"moderator_roles"`
-	StaffRoles             map[string][]string   `json:"staff_roles" bson:"staff_roles"`
-	GrantRoles             map[string]RoleConfig `json:"grant_roles" bson:"grant_roles"`
-	SyncSheets             map[string]string     `json:"sync_sheets" bson:"sync_sheets"`
-	RoleGrantEnabled       map[string]bool       `json:"role_grant_enabled" bson:"role_grant_enabled"`
-	RoleRemoveEnabled      map[string]bool       `json:"role_remove_enabled" bson:"role_remove_enabled"`
-	TimeFormat             string                `json:"time_format" bson:"time_format"`
-	DateFormat             string                `json:"date_format" bson:"date_format"`
-	GoogleCredentials      bson.M                `json:"-" bson:"google_credentials"`
-	GoogleCredentialsAlt1  bson.M                `json:"-" bson:"google_credentials_alt1"`
-	GoogleOauthCredentials bson.M                `json:"-" bson:"google_oauth_credentials"`
-	GoogleClientID         string                `json:"-" bson:"google_client_id"`
-	GoogleSecret           string                `json:"-" bson:"google_secret"`
-	YoutubeCredentials     []YoutubeCredential   `json:"-" bson:"youtube_credentials"`
-	EightBallEnabled       bool                  `json:"eight_ball_enabled" bson:"eight_ball_enabled"`
-	TweetSyncChannels      []TweetSyncConfig     `json:"tweet_sync_channels" bson:"tweet_sync_channels"`
-	CopyPipelines          []CopyPipeline        `json:"copy_pipelines" bson:"copy_pipelines"`
-	DoubleTL               bool                  `json:"double_tl" bson:"double_tl"`
+	ModeratorRoles         map[string][]string               `json:"moderator_roles" bson:"moderator_roles"`
+	StaffRoles             map[string][]string               `json:"staff_roles" bson:"staff_roles"`
+	GrantRoles             map[string]RoleConfig             `json:"grant_roles" bson:"grant_roles"`
+	SyncSheets             map[string]string                 `json:"sync_sheets" bson:"sync_sheets"`
+	RoleGrantEnabled       map[string]bool                   `json:"role_grant_enabled" bson:"role_grant_enabled"`
+	RoleRemoveEnabled      map[string]bool                   `json:"role_remove_enabled" bson:"role_remove_enabled"`
+	TimeFormat             string                            `json:"time_format" bson:"time_format"`
+	DateFormat             string                            `json:"date_format" bson:"date_format"`
+	GoogleCredentials      bson.M                            `json:"-" bson:"google_credentials"`
+	GoogleCredentialsAlt1  bson.M                            `json:"-" bson:"google_credentials_alt1"`
+	GoogleOauthCredentials bson.M                            `json:"-" bson:"google_oauth_credentials"`
+	GoogleClientID         string                            `json:"-" bson:"google_client_id"`
+	GoogleSecret           string                            `json:"-" bson:"google_secret"`
+	YoutubeCredentials     []YoutubeCredential               `json:"-" bson:"youtube_credentials"`
+	GoogleDeviceCodeURL    string                            `json:"google_device_code_url" bson:"google_device_code_url"`
+	GoogleTokenURL         string                            `json:"google_token_url" bson:"google_token_url"`
+	YoutubeChannels        map[string][]YoutubeChannelConfig `json:"youtube_channels" bson:"youtube_channels"`
+	EightBallEnabled       bool                              `json:"eight_ball_enabled" bson:"eight_ball_enabled"`
+	TweetSyncChannels      []TweetSyncConfig                 `json:"tweet_sync_channels" bson:"tweet_sync_channels"`
+	CopyPipelines          []CopyPipeline                    `json:"copy_pipelines" bson:"copy_pipelines"`
+	DoubleTL               bool                              `json:"double_tl" bson:"double_tl"`
+	StreamNotifications    []StreamNotifyConfig              `json:"stream_notifications" bson:"stream_notifications"`
+	Mirrors                []MirrorConfig                    `json:"mirrors" bson:"mirrors"`
+	LogRetention           map[string]int                    `json:"log_retention" bson:"log_retention"`
+	ModLogEnabled          map[string]bool                   `json:"mod_log_enabled" bson:"mod_log_enabled"`
+	ModLogIgnored          map[string][]string               `json:"mod_log_ignored" bson:"mod_log_ignored"`
+	ArchiveEnabled         map[string]bool                   `json:"archive_enabled" bson:"archive_enabled"`
 }
 
 // Get Load the config object
@@ -215,10 +340,37 @@ func LoadConfig() error {
 	GoogleClientID = config.GoogleClientID
 	GoogleSecret = config.GoogleSecret
 	YoutubeCredentials = config.YoutubeCredentials
+	if config.GoogleDeviceCodeURL != "" {
+		GoogleDeviceCodeURL = config.GoogleDeviceCodeURL
+	}
+	if config.GoogleTokenURL != "" {
+		GoogleTokenURL = config.GoogleTokenURL
+	}
+	if config.YoutubeChannels != nil {
+		YoutubeChannels = config.YoutubeChannels
+	}
 	EightBallEnabled = config.EightBallEnabled
+	tweetSyncMu.Lock()
 	TweetSyncChannels = config.TweetSyncChannels
+	tweetSyncMu.Unlock()
 	CopyPipelines = config.CopyPipelines
 	DoubleTL = config.DoubleTL
+	StreamNotifications = config.StreamNotifications
+	Mirrors = config.Mirrors
+	if config.LogRetention != nil {
+		logRetentionMu.Lock()
+		LogRetention = config.LogRetention
+		logRetentionMu.Unlock()
+	}
+	if config.ModLogEnabled != nil {
+		ModLogEnabled = config.ModLogEnabled
+	}
+	if config.ModLogIgnored != nil {
+		ModLogIgnored = config.ModLogIgnored
+	}
+	if config.ArchiveEnabled != nil {
+		ArchiveEnabled = config.ArchiveEnabled
+	}
 
 	if GrantRoles == nil {
 		GrantRoles = make(map[string]RoleConfig)
@@ -556,6 +708,133 @@ func SetRoleGrantEnabled(guildID string, enabled bool) error {
 	return nil
 }
 
+// ModLogChannel the channel a guild's deletes and edits in a channel are posted to, "" if they aren't posted
+func ModLogChannel(guildID, channelID string) string {
+	logChannel, ok := LogChannels[guildID]
+	if !ok || !ModLogEnabled[guildID] || channelID == logChannel {
+		return ""
+	}
+	for _, ignored := range ModLogIgnored[guildID] {
+		if ignored == channelID {
+			return ""
+		}
+	}
+	return logChannel
+}
+
+func SetModLogEnabled(guildID string, enabled bool) error {
+	key := fmt.Sprintf("mod_log_enabled.%s", guildID)
+	update := bson.M{
+		key: enabled,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	ModLogEnabled[guildID] = enabled
+	return nil
+}
+
+// SetModLogIgnored stop or resume posting a channel's deletes and edits to the log channel
+func SetModLogIgnored(guildID, channelID string, ignored bool) error {
+	channels := []string{}
+	for _, existing := range ModLogIgnored[guildID] {
+		if existing != channelID {
+			channels = append(channels, existing)
+		}
+	}
+	if ignored {
+		channels = append(channels, channelID)
+	}
+
+	key := fmt.Sprintf("mod_log_ignored.%s", guildID)
+	update := bson.M{
+		key: channels,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	ModLogIgnored[guildID] = channels
+	return nil
+}
+
+// SetArchiveEnabled start or stop archiving the attachments posted in a guild
+func SetArchiveEnabled(guildID string, enabled bool) error {
+	key := fmt.Sprintf("archive_enabled.%s", guildID)
+	update := bson.M{
+		key: enabled,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	ArchiveEnabled[guildID] = enabled
+	return nil
+}
+
+// LogRetentionDays how many days messages in a channel are logged for, the channel's own setting before the guild's
+func LogRetentionDays(guildID, channelID string) int {
+	logRetentionMu.RLock()
+	defer logRetentionMu.RUnlock()
+
+	if days, ok := LogRetention[channelID]; ok {
+		return days
+	}
+	return LogRetention[guildID]
+}
+
+// GetLogRetention the log retention set for a channel or guild itself, and whether there is one
+func GetLogRetention(id string) (int, bool) {
+	logRetentionMu.RLock()
+	defer logRetentionMu.RUnlock()
+
+	days, ok := LogRetention[id]
+	return days, ok
+}
+
+// SetLogRetention set how many days the logs of a channel or guild are kept, 0 keeps them forever
+func SetLogRetention(id string, days int) error {
+	key := fmt.Sprintf("log_retention.%s", id)
+	update := bson.M{
+		key: days,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	logRetentionMu.Lock()
+	LogRetention[id] = days
+	logRetentionMu.Unlock()
+	return nil
+}
+
+// RemoveLogRetention let a channel fall back to its guild's log retention
+func RemoveLogRetention(id string) error {
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	err := db.C("config").Update(bson.M{}, bson.M{"$unset": bson.M{fmt.Sprintf("log_retention.%s", id): ""}})
+	if err != nil {
+		return err
+	}
+
+	logRetentionMu.Lock()
+	delete(LogRetention, id)
+	logRetentionMu.Unlock()
+	return nil
+}
+
 func SetRoleRemoveEnabled(guildID string, enabled bool) error {
 	key := fmt.Sprintf("role_remove_enabled.%s", guildID)
 	update := bson.M{
@@ -600,7 +879,230 @@ func SetDoubleTLEnabled(enabled bool) error {
 	return nil
 }
 
+// GuildYoutubeChannels the Youtube channels tracked for the given guild
+func GuildYoutubeChannels(guildID string) []YoutubeChannelConfig {
+	return YoutubeChannels[guildID]
+}
+
+// AllYoutubeChannels every tracked Youtube channel across all guilds, without duplicates
+func AllYoutubeChannels() []YoutubeChannelConfig {
+	seen := make(map[string]bool)
+	res := []YoutubeChannelConfig{}
+	for _, ycs := range YoutubeChannels {
+		for _, yc := range ycs {
+			if !seen[yc.ChannelID] {
+				seen[yc.ChannelID] = true
+				res = append(res, yc)
+			}
+		}
+	}
+	return res
+}
+
+func AddYoutubeChannel(guildID string, yc YoutubeChannelConfig) error {
+	for _, existing := range YoutubeChannels[guildID] {
+		if existing.ChannelID == yc.ChannelID {
+			return errors.New("Already tracking that channel")
+		}
+	}
+
+	return setGuildYoutubeChannels(guildID, append(YoutubeChannels[guildID], yc))
+}
+
+func RemoveYoutubeChannel(guildID, channelID string) error {
+	res := []YoutubeChannelConfig{}
+	for _, yc := range YoutubeChannels[guildID] {
+		if yc.ChannelID != channelID {
+			res = append(res, yc)
+		}
+	}
+	if len(res) == len(YoutubeChannels[guildID]) {
+		return errors.New("Not tracking that channel")
+	}
+
+	return setGuildYoutubeChannels(guildID, res)
+}
+
+func setGuildYoutubeChannels(guildID string, ycs []YoutubeChannelConfig) error {
+	key := fmt.Sprintf("youtube_channels.%s", guildID)
+	update := bson.M{
+		key: ycs,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	YoutubeChannels[guildID] = ycs
+	return nil
+}
+
+// GetYoutubeCredential the credentials of a Youtube account, if it's linked
+func GetYoutubeCredential(email string) *YoutubeCredential {
+	for _, cred := range YoutubeCredentials {
+		if cred.Email == email {
+			return &cred
+		}
+	}
+	return nil
+}
+
+// SetYoutubeCredential store a Youtube account's credentials, replacing the ones it had
+func SetYoutubeCredential(cred YoutubeCredential) error {
+	creds := []YoutubeCredential{}
+	replaced := false
+	for _, existing := range YoutubeCredentials {
+		if existing.Email == cred.Email {
+			creds = append(creds, cred)
+			replaced = true
+		} else {
+			creds = append(creds, existing)
+		}
+	}
+	if !replaced {
+		creds = append(creds, cred)
+	}
+	return updateYoutubeCredentials(creds)
+}
+
+// RemoveYoutubeCredential forget a Youtube account
+func RemoveYoutubeCredential(email string) (bool, error) {
+	creds := []YoutubeCredential{}
+	for _, existing := range YoutubeCredentials {
+		if existing.Email != email {
+			creds = append(creds, existing)
+		}
+	}
+	if len(creds) == len(YoutubeCredentials) {
+		return false, nil
+	}
+	return true, updateYoutubeCredentials(creds)
+}
+
+func updateYoutubeCredentials(creds []YoutubeCredential) error {
+	update := bson.M{
+		"youtube_credentials": creds,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	YoutubeCredentials = creds
+	return nil
+}
+
+// GetStreamNotifyConfig the stream notification settings of a channel, if it opted in
+func GetStreamNotifyConfig(channelID string) *StreamNotifyConfig {
+	for _, snc := range StreamNotifications {
+		if snc.ChannelID == channelID {
+			return &snc
+		}
+	}
+
+	return nil
+}
+
+// SetStreamNotifyConfig add or replace the stream notification settings of a channel
+func SetStreamNotifyConfig(snc StreamNotifyConfig) error {
+	res := []StreamNotifyConfig{}
+	for _, existing := range StreamNotifications {
+		if existing.ChannelID != snc.ChannelID {
+			res = append(res, existing)
+		}
+	}
+	res = append(res, snc)
+
+	return setStreamNotifications(res)
+}
+
+func RemoveStreamNotifyConfig(channelID string) error {
+	res := []StreamNotifyConfig{}
+	for _, existing := range StreamNotifications {
+		if existing.ChannelID != channelID {
+			res = append(res, existing)
+		}
+	}
+	if len(res) == len(StreamNotifications) {
+		return errors.New("Channel isn't getting stream notifications")
+	}
+
+	return setStreamNotifications(res)
+}
+
+func setStreamNotifications(sncs []StreamNotifyConfig) error {
+	update := bson.M{
+		"stream_notifications": sncs,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	StreamNotifications = sncs
+	return nil
+}
+
+// GetMirrors the mirrors copying messages out of a channel
+func GetMirrors(sourceChannelID string) []MirrorConfig {
+	res := []MirrorConfig{}
+	for _, mc := range Mirrors {
+		if mc.SourceChannelID == sourceChannelID {
+			res = append(res, mc)
+		}
+	}
+	return res
+}
+
+// SetMirror add or replace the mirror between two channels
+func SetMirror(mc MirrorConfig) error {
+	res := []MirrorConfig{}
+	for _, existing := range Mirrors {
+		if existing.SourceChannelID != mc.SourceChannelID || existing.DestChannelID != mc.DestChannelID {
+			res = append(res, existing)
+		}
+	}
+	res = append(res, mc)
+
+	return setMirrors(res)
+}
+
+// RemoveMirror stop copying messages from one channel into another
+func RemoveMirror(sourceChannelID, destChannelID string) error {
+	res := []MirrorConfig{}
+	for _, existing := range Mirrors {
+		if existing.SourceChannelID != sourceChannelID || existing.DestChannelID != destChannelID {
+			res = append(res, existing)
+		}
+	}
+	if len(res) == len(Mirrors) {
+		return errors.New("Channel isn't mirrored there")
+	}
+
+	return setMirrors(res)
+}
+
+func setMirrors(mcs []MirrorConfig) error {
+	update := bson.M{
+		"mirrors": mcs,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	Mirrors = mcs
+	return nil
+}
+
 func SetTweetSyncSinceID(handle, channelID string, sinceID int64) error {
+	tweetSyncMu.Lock()
+	defer tweetSyncMu.Unlock()
+
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	session.SetMode(mgo.Strong, false)
@@ -628,8 +1130,110 @@ func SetTweetSyncSinceID(handle, channelID string, sinceID int64) error {
 	return nil
 }
 
+// SetTweetSyncCursor save the feed position of a sync, as a since ID for Twitter or an opaque cursor for other feeds
+func SetTweetSyncCursor(ts *TweetSyncConfig, cursor string) error {
+	if ts.FeedSource() == models.FeedSourceTwitter {
+		sinceID, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			return err
+		}
+		return SetTweetSyncSinceID(ts.Handle, ts.ChannelID, sinceID)
+	}
+
+	return updateTweetSyncChannels(func(tss []TweetSyncConfig) ([]TweetSyncConfig, error) {
+		for i, c := range tss {
+			if c.Handle == ts.Handle && c.ChannelID == ts.ChannelID {
+				tss[i].Cursor = cursor
+			}
+		}
+		return tss, nil
+	})
+}
+
+// AddTweetSyncChannel start following a new feed
+func AddTweetSyncChannel(ts TweetSyncConfig) error {
+	return updateTweetSyncChannels(func(tss []TweetSyncConfig) ([]TweetSyncConfig, error) {
+		for _, c := range tss {
+			if strings.EqualFold(c.Handle, ts.Handle) && c.ChannelID == ts.ChannelID {
+				return tss, errors.New("That handle is already synced to that channel")
+			}
+		}
+		return append(tss, ts), nil
+	})
+}
+
+// RemoveTweetSyncChannel stop following a feed
+func RemoveTweetSyncChannel(handle, channelID string) error {
+	return updateTweetSyncChannels(func(tss []TweetSyncConfig) ([]TweetSyncConfig, error) {
+		res := []TweetSyncConfig{}
+		for _, c := range tss {
+			if c.Handle != handle || c.ChannelID != channelID {
+				res = append(res, c)
+			}
+		}
+		if len(res) == len(tss) {
+			return tss, errors.New("No such Tweet sync")
+		}
+		return res, nil
+	})
+}
+
+// SetTweetSyncPaused pause or resume a feed, so it stays paused across restarts
+func SetTweetSyncPaused(handle, channelID string, paused bool) error {
+	return updateTweetSyncChannels(func(tss []TweetSyncConfig) ([]TweetSyncConfig, error) {
+		for i, c := range tss {
+			if c.Handle == handle && c.ChannelID == channelID {
+				tss[i].Paused = paused
+			}
+		}
+		return tss, nil
+	})
+}
+
+// updateTweetSyncChannels apply a change to the Tweet syncs as currently saved in the DB,
+// since the scanners update them concurrently
+func updateTweetSyncChannels(update func([]TweetSyncConfig) ([]TweetSyncConfig, error)) error {
+	tweetSyncMu.Lock()
+	defer tweetSyncMu.Unlock()
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+	configCol := db.C("config")
+
+	config := BotConfig{}
+	err := configCol.Find(bson.M{}).One(&config)
+	if err != nil {
+		return err
+	}
+
+	tss, err := update(config.TweetSyncChannels)
+	if err != nil {
+		return err
+	}
+
+	err = configCol.Update(bson.M{}, bson.M{"$set": bson.M{"tweet_sync_channels": tss}})
+	if err != nil {
+		return err
+	}
+
+	TweetSyncChannels = tss
+	return nil
+}
+
+// GetTweetSyncChannels a copy of the Tweet syncs, safe to use while they change
+func GetTweetSyncChannels() []TweetSyncConfig {
+	tweetSyncMu.RLock()
+	defer tweetSyncMu.RUnlock()
+
+	res := make([]TweetSyncConfig, len(TweetSyncChannels))
+	copy(res, TweetSyncChannels)
+	return res
+}
+
 func MaybeGetTweetConfig(channelID string) *TweetSyncConfig {
-	for _, c := range TweetSyncChannels {
+	for _, c := range GetTweetSyncChannels() {
 		if c.ControlChannelID == channelID {
 			return &c
 		}
@@ -639,8 +1243,10 @@ func MaybeGetTweetConfig(channelID string) *TweetSyncConfig {
 }
 
 func SetCopyPipeline(cp CopyPipeline) error {
-	if GetCopyPipeline(cp.ChannelID, cp.YoutubeVideoID) != nil {
-		return errors.New("Already copying to that chat")
+	for _, existing := range CopyPipelines {
+		if existing.ChannelID == cp.ChannelID && existing.YoutubeVideoID == cp.YoutubeVideoID && existing.Type == cp.Type {
+			return errors.New("Already copying to that chat")
+		}
 	}
 
 	if !cp.OID.Valid() {
@@ -664,11 +1270,11 @@ func SetCopyPipeline(cp CopyPipeline) error {
 	return nil
 }
 
-func RemoveCopyPipeline(channelID string) ([]CopyPipeline, error) {
+func RemoveCopyPipeline(channelID, pipelineType string) ([]CopyPipeline, error) {
 	res := []CopyPipeline{}
 	removed := []CopyPipeline{}
 	for i, cp := range CopyPipelines {
-		if cp.ChannelID == channelID {
+		if cp.ChannelID == channelID && cp.Type == pipelineType {
 			removed = append(removed, CopyPipelines[i])
 		} else {
 			res = append(res, CopyPipelines[i])
@@ -688,6 +1294,38 @@ func RemoveCopyPipeline(channelID string) ([]CopyPipeline, error) {
 	return removed, nil
 }
 
+// RemoveCopyPipelineByID remove one copy pipeline, for when it stops by itself
+func RemoveCopyPipelineByID(oid bson.ObjectId) error {
+	res := []CopyPipeline{}
+	for i, cp := range CopyPipelines {
+		if cp.OID != oid {
+			res = append(res, CopyPipelines[i])
+		}
+	}
+
+	update := bson.M{
+		"copy_pipelines": res,
+	}
+
+	err := UpdateConfig(update)
+	if err != nil {
+		return err
+	}
+
+	CopyPipelines = res
+	return nil
+}
+
+// HasCopyPipeline whether a copy pipeline is still configured
+func HasCopyPipeline(oid bson.ObjectId) bool {
+	for _, cp := range CopyPipelines {
+		if cp.OID == oid {
+			return true
+		}
+	}
+	return false
+}
+
 func GetCopyPipeline(channelID, videoID string) *CopyPipeline {
 	for _, cp := range CopyPipelines {
 		if cp.ChannelID == channelID && cp.YoutubeVideoID == videoID {
@@ -15,11 +15,13 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 
+	"github.com/w8kerr/delubot/attachments"
 	"github.com/w8kerr/delubot/config"
 	"github.com/w8kerr/delubot/mongo"
 	"github.com/w8kerr/delubot/sheetsync"
 	"github.com/w8kerr/delubot/tl"
 	"github.com/w8kerr/delubot/tweetsync"
+	"github.com/w8kerr/delubot/youtubesvc"
 )
 
 // Version is a constant that stores the Disgord version information.
@@ -78,6 +80,16 @@ func main() {
 	}
 
 	config.LoadConfig()
+	attachments.Init()
+
+	// Joining members are needed to put the mute role back on people who rejoin to shake it off.
+	// Server Members is a privileged intent, Discord refuses the connection unless it's turned on for the bot
+	// in the developer portal, so it's only asked for with DELUBOT_MEMBERS_INTENT=1
+	if os.Getenv("DELUBOT_MEMBERS_INTENT") == "1" {
+		Session.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsAllWithoutPrivileged | discordgo.IntentsGuildMembers)
+	} else {
+		log.Println("Server Members intent not requested, people who rejoin while muted won't be muted again")
+	}
 
 	// Open a websocket connection to Discord
 	err = Session.Open()
@@ -97,6 +109,7 @@ func main() {
 	}
 
 	tweetsync.InitTimelines(Session)
+	go tweetsync.Verifier(Session)
 
 	channels, err := Session.GuildChannels("755437328515989564")
 	for _, channel := range channels {
@@ -106,10 +119,21 @@ func main() {
 	sheetsync.Init(Session)
 	go sheetsync.Sweeper()
 
-	// youtubesvc.InitSweeper(Session)
-	// go youtubesvc.Sweeper()
+	youtubesvc.InitSweeper(Session)
+	go youtubesvc.Sweeper()
+
+	go Router.InitScheduleBoards(Session)
+	go Router.InitStreamNotifier(Session)
+	go Router.InitMuteExpiry(Session)
+	go youtubesvc.Archiver()
+	Router.MigrateDoubleTL(Session)
+	go Router.InitCopyPipelines(Session)
 
-	go Router.InitScanForUpdates(Session)
+	// Optionally serve the stream schedule for calendar apps, e.g. DELUBOT_ICS_ADDR=localhost:8080
+	icsAddr := os.Getenv("DELUBOT_ICS_ADDR")
+	if icsAddr != "" {
+		go Router.ServeScheduleICS(icsAddr)
+	}
 
 	// go clock.RunClockChannel(Session)
 	// go clock.RunClockName(Session)
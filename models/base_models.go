@@ -186,11 +186,81 @@ type YoutubeStreamRecord struct {
 	PostTitle       string        `json:"post_title" bson:"post_title"`
 	PostLink        string        `json:"post_link" bson:"post_link"`
 	PostPlan        int           `json:"post_plan" bson:"post_plan"`
+	ChannelID       string        `json:"channel_id" bson:"channel_id"`
+	ChannelTitle    string        `json:"channel_title" bson:"channel_title"`
 	YoutubeID       string        `json:"youtube_id" bson:"youtube_id"`
 	Completed       bool          `json:"completed" bson:"completed"`
 	ScheduledTime   time.Time     `json:"scheduled_time" bson:"scheduled_time"`
 	StreamTitle     string        `json:"stream_title" bson:"stream_title"`
 	StreamThumbnail string        `json:"stream_thumbnail" bson:"stream_thumbnail"`
+	StartedAt       time.Time     `json:"started_at" bson:"started_at"`
+	EndedAt         time.Time     `json:"ended_at" bson:"ended_at"`
+	Viewers         int           `json:"viewers" bson:"viewers,omitempty"`
+	ViewCount       int           `json:"view_count" bson:"view_count,omitempty"`
+}
+
+// IsLive Whether the stream has started and not finished yet
+func (rec *YoutubeStreamRecord) IsLive() bool {
+	return !rec.StartedAt.IsZero() && rec.EndedAt.IsZero()
+}
+
+// StreamArchive What happened during a stream, kept after the stream is over
+type StreamArchive struct {
+	VideoID       string        `json:"_id" bson:"_id"`
+	ChannelID     string        `json:"channel_id" bson:"channel_id"`
+	ChannelTitle  string        `json:"channel_title" bson:"channel_title"`
+	Title         string        `json:"title" bson:"title"`
+	TitleChanges  []TitleChange `json:"title_changes" bson:"title_changes"`
+	ScheduledTime time.Time     `json:"scheduled_time" bson:"scheduled_time"`
+	StartedAt     time.Time     `json:"started_at" bson:"started_at"`
+	EndedAt       time.Time     `json:"ended_at" bson:"ended_at"`
+	Duration      int           `json:"duration" bson:"duration"`
+	PeakViewers   int           `json:"peak_viewers" bson:"peak_viewers"`
+	ViewCount     int           `json:"view_count" bson:"view_count"`
+	UpdatedAt     time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+// TitleChange A title a stream had before it was renamed
+type TitleChange struct {
+	Title     string    `json:"title" bson:"title"`
+	ChangedAt time.Time `json:"changed_at" bson:"changed_at"`
+}
+
+// Stream notification events
+const (
+	StreamEventScheduled = "scheduled"
+	StreamEventSoon      = "soon"
+	StreamEventLive      = "live"
+	StreamEventEnded     = "ended"
+)
+
+// StreamNotification Record of a stream notification that was sent, so it is never sent twice
+type StreamNotification struct {
+	OID       bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	ChannelID string        `json:"channel_id" bson:"channel_id"`
+	VideoID   string        `json:"video_id" bson:"video_id"`
+	Event     string        `json:"event" bson:"event"`
+	MessageID string        `json:"message_id" bson:"message_id"`
+	SentAt    time.Time     `json:"sent_at" bson:"sent_at"`
+}
+
+// LiveStream A stream seen live on a guild's schedule, kept until its end is announced
+type LiveStream struct {
+	OID       bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	GuildID   string        `json:"guild_id" bson:"guild_id"`
+	VideoID   string        `json:"video_id" bson:"video_id"`
+	SeenAt    time.Time     `json:"seen_at" bson:"seen_at"`
+	ExpiresAt time.Time     `json:"expires_at" bson:"expires_at"`
+}
+
+// ScheduleBoard A message in a channel that is kept updated with the stream schedule
+type ScheduleBoard struct {
+	OID       bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	GuildID   string        `json:"guild_id" bson:"guild_id"`
+	ChannelID string        `json:"channel_id" bson:"channel_id"`
+	MessageID string        `json:"message_id" bson:"message_id"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" bson:"updated_at"`
 }
 
 // SyncedTweet Record of a tweet that was echoed from Twitter into Discord
@@ -201,30 +271,93 @@ type SyncedTweet struct {
 	ControlChannelID string        `json:"control_channel_id" bson:"control_channel_id"`
 	ControlMessageID string        `json:"control_message_id" bson:"control_message_id"`
 	Tweet            twitter.Tweet `json:"tweet" bson:"tweet"`
+	Post             FeedPost      `json:"post" bson:"post"`
 	Translation      string        `json:"translation" bson:"translation"`
+	QuoteTranslation string        `json:"quote_translation" bson:"quote_translation"`
+	ReplyText        string        `json:"reply_text" bson:"reply_text"`
 	CreatedAt        time.Time     `json:"created_at" bson:"created_at"`
 	UpdatedAt        time.Time     `json:"updated_at" bson:"updated_at"`
 	Translators      []string      `json:"translators" bson:"translators"`
 	HumanTranslated  bool          `json:"human_translated" bson:"human_translated"`
+	Replay           bool          `json:"replay" bson:"replay"` // Mirrored by a backfill or repost rather than the live feed
+	VerifiedAt       time.Time     `json:"verified_at" bson:"verified_at"`
+	Deleted          bool          `json:"deleted" bson:"deleted"`
+	DeletedAt        time.Time     `json:"deleted_at" bson:"deleted_at"`
+	EditedText       string        `json:"edited_text" bson:"edited_text"` // The upstream text, if it changed after the post was mirrored
+	EditedAt         time.Time     `json:"edited_at" bson:"edited_at"`
+}
+
+// PostID The upstream ID of the synced post, whichever feed it came from
+func (st *SyncedTweet) PostID() string {
+	if st.Post.ID != "" {
+		return st.Post.ID
+	}
+	return st.Tweet.IDStr
+}
+
+// Text The original text of the synced post, whichever feed it came from
+func (st *SyncedTweet) Text() string {
+	if st.Post.Text != "" {
+		return st.Post.Text
+	}
+	return st.Tweet.FullText
 }
 
 // WatchedVideo Record a video that should be scanned for new comments
 type WatchedVideo struct {
-	OID       bson.ObjectId `json:"_id" bson:"_id,omitempty"`
-	VideoID   string        `json:"video_id" bson:"video_id"`
-	LastScan  time.Time     `json:"last_scan" bson:"last_scan"`
-	ChannelID string        `json:"channel_id" bson:"channel_id"`
+	OID              bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	VideoID          string        `json:"video_id" bson:"video_id"`
+	VideoTitle       string        `json:"video_title" bson:"video_title"`
+	YoutubeChannelID string        `json:"youtube_channel_id" bson:"youtube_channel_id"`
+	LastScan         time.Time     `json:"last_scan" bson:"last_scan"`
+	LastFullScan     time.Time     `json:"last_full_scan" bson:"last_full_scan"`
+	ChannelID        string        `json:"channel_id" bson:"channel_id"`
+	Keywords         []string      `json:"keywords" bson:"keywords"`
+	TalentOnly       bool          `json:"talent_only" bson:"talent_only"`
+	NewThreadsOnly   bool          `json:"new_threads_only" bson:"new_threads_only"`
+	CreatedBy        string        `json:"created_by" bson:"created_by"`
+	CreatedAt        time.Time     `json:"created_at" bson:"created_at"`
 }
 
 type YoutubeComment struct {
+	ID                    string
 	AuthorDisplayName     string
 	AuthorProfileImageURL string
+	AuthorChannelID       string
+	ParentID              string
 	Text                  string
 	ReplyDisplayName      string
 	ReplyText             string
+	PublishedAt           time.Time
 	UpdatedAt             time.Time
 }
 
+// IsReply whether the comment is a reply rather than the start of a thread
+func (c *YoutubeComment) IsReply() bool {
+	return c.ParentID != ""
+}
+
+// SeenComment A comment a watch already went through, so it's never posted twice.
+// ReplyCount is only kept for threads, so their replies are only fetched when there are new ones.
+type SeenComment struct {
+	OID        bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	WatchID    bson.ObjectId `json:"watch_id" bson:"watch_id"`
+	CommentID  string        `json:"comment_id" bson:"comment_id"`
+	ReplyCount int64         `json:"reply_count" bson:"reply_count"`
+	SeenAt     time.Time     `json:"seen_at" bson:"seen_at"`
+}
+
+// MirroredMessage Where a message was copied to by a mirror, so edits and deletes can follow it
+type MirroredMessage struct {
+	OID             bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	SourceChannelID string        `json:"source_channel_id" bson:"source_channel_id"`
+	SourceMessageID string        `json:"source_message_id" bson:"source_message_id"`
+	DestChannelID   string        `json:"dest_channel_id" bson:"dest_channel_id"`
+	DestMessageID   string        `json:"dest_message_id" bson:"dest_message_id"`
+	WebhookID       string        `json:"webhook_id" bson:"webhook_id"`
+	CreatedAt       time.Time     `json:"created_at" bson:"created_at"`
+}
+
 type Sticky struct {
 	OID             bson.ObjectId `json:"_id" bson:"_id,omitempty"`
 	AuthorName      string        `json:"author_name" bson:"author_name"`
@@ -248,3 +381,100 @@ type BTableOptions struct {
 	SortDir     int
 	Active      bool
 }
+
+// UserTimezone The time zone a user reads and types times in
+type UserTimezone struct {
+	UserID    string    `json:"_id" bson:"_id"`
+	Zone      string    `json:"zone" bson:"zone"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// ArchivedAttachment A copy of a file posted on Discord, kept in the attachment store after the message is gone.
+// Files with the same content share one stored copy, keyed by their hash.
+type ArchivedAttachment struct {
+	OID          bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	AttachmentID string        `json:"attachment_id" bson:"attachment_id"`
+	MessageID    string        `json:"message_id" bson:"message_id"`
+	ChannelID    string        `json:"channel_id" bson:"channel_id"`
+	GuildID      string        `json:"guild_id" bson:"guild_id"`
+	AuthorID     string        `json:"author_id" bson:"author_id"`
+	AuthorName   string        `json:"author_name" bson:"author_name"`
+	Filename     string        `json:"filename" bson:"filename"`
+	MimeType     string        `json:"mime_type" bson:"mime_type"`
+	Size         int           `json:"size" bson:"size"`
+	Hash         string        `json:"hash" bson:"hash"`
+	Key          string        `json:"key" bson:"key"`
+	ArchivedAt   time.Time     `json:"archived_at" bson:"archived_at"`
+}
+
+// Moderation actions recorded as cases
+const (
+	CaseWarn    = "warn"
+	CaseMute    = "mute"
+	CaseUnmute  = "unmute"
+	CaseTimeout = "timeout"
+	CaseKick    = "kick"
+	CaseBan     = "ban"
+)
+
+// ModCase A moderation action taken against someone, numbered per guild.
+// Mutes and timeouts with a duration expire at ExpiresAt, Active until then.
+type ModCase struct {
+	OID           bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	GuildID       string        `json:"guild_id" bson:"guild_id"`
+	CaseNumber    int           `json:"case_number" bson:"case_number"`
+	Action        string        `json:"action" bson:"action"`
+	UserID        string        `json:"user_id" bson:"user_id"`
+	UserName      string        `json:"user_name" bson:"user_name"`
+	ModeratorID   string        `json:"moderator_id" bson:"moderator_id"`
+	ModeratorName string        `json:"moderator_name" bson:"moderator_name"`
+	Reason        string        `json:"reason" bson:"reason"`
+	Duration      time.Duration `json:"duration,omitempty" bson:"duration,omitempty"`
+	ExpiresAt     time.Time     `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	Active        bool          `json:"active" bson:"active"`
+	Notified      bool          `json:"notified" bson:"notified"`
+	CreatedAt     time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// CaseCounter The last case number handed out in a guild
+type CaseCounter struct {
+	GuildID string `json:"_id" bson:"_id"`
+	Last    int    `json:"last" bson:"last"`
+}
+
+// What an automod rule looks for in a message
+const (
+	AutomodWords       = "words"
+	AutomodRegex       = "regex"
+	AutomodInvites     = "invites"
+	AutomodMentions    = "mentions"
+	AutomodRepeat      = "repeat"
+	AutomodAttachments = "attachments"
+	AutomodNewAccount  = "newaccount"
+)
+
+// What automod does with a message that breaks a rule
+const (
+	AutomodDelete = "delete"
+	AutomodWarn   = "warn"
+	AutomodMute   = "mute"
+	AutomodLog    = "log"
+)
+
+// AutomodRule A rule messages in a guild are checked against.
+// Threshold is the number of mentions, repeats or attachments that's too many, or how many days old an account has to be.
+// Repeats and attachments are counted over the last Window.
+type AutomodRule struct {
+	OID          bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	GuildID      string        `json:"guild_id" bson:"guild_id"`
+	Name         string        `json:"name" bson:"name"`
+	Type         string        `json:"type" bson:"type"`
+	Words        []string      `json:"words,omitempty" bson:"words,omitempty"`
+	Pattern      string        `json:"pattern,omitempty" bson:"pattern,omitempty"`
+	Threshold    int           `json:"threshold,omitempty" bson:"threshold,omitempty"`
+	Window       time.Duration `json:"window,omitempty" bson:"window,omitempty"`
+	Actions      []string      `json:"actions" bson:"actions"`
+	MuteDuration time.Duration `json:"mute_duration,omitempty" bson:"mute_duration,omitempty"`
+	CreatedBy    string        `json:"created_by" bson:"created_by"`
+	CreatedAt    time.Time     `json:"created_at" bson:"created_at"`
+}
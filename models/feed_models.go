@@ -0,0 +1,47 @@
+/*
+ * Models for posts pulled from social feeds
+ */
+
+package models
+
+import (
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+)
+
+// Feed source types
+const (
+	FeedSourceTwitter = "twitter"
+	FeedSourceRSS     = "rss"
+	FeedSourceNitter  = "nitter"
+)
+
+// FeedAuthor The account that published a feed post
+type FeedAuthor struct {
+	Name      string `json:"name" bson:"name"`
+	Handle    string `json:"handle" bson:"handle"`
+	URL       string `json:"url" bson:"url"`
+	AvatarURL string `json:"avatar_url" bson:"avatar_url"`
+}
+
+// FeedMedia An image or video attached to a feed post
+type FeedMedia struct {
+	Type         string `json:"type" bson:"type"`
+	URL          string `json:"url" bson:"url"`
+	ThumbnailURL string `json:"thumbnail_url" bson:"thumbnail_url"`
+}
+
+// FeedPost A post from any social feed, normalized so it can go through the translate-and-mirror pipeline
+type FeedPost struct {
+	ID        string      `json:"id" bson:"id"`
+	Source    string      `json:"source" bson:"source"`
+	Text      string      `json:"text" bson:"text"`
+	URL       string      `json:"url" bson:"url"`
+	Author    FeedAuthor  `json:"author" bson:"author"`
+	Media     []FeedMedia `json:"media" bson:"media"`
+	CreatedAt time.Time   `json:"created_at" bson:"created_at"`
+	Via       string      `json:"via" bson:"via"`
+
+	Tweet *twitter.Tweet `json:"-" bson:"-"` // Only set for posts that came from the Twitter API
+}
@@ -6,8 +6,10 @@ import (
 	"log"
 	"net"
 	"os"
+	"time"
 
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/sirupsen/logrus"
 )
 
@@ -88,6 +90,66 @@ func ensureIndexes() {
 	fmt.Println("INDEXING:", DB_NAME)
 
 	createNormalIndex("message_logs", []string{"messageid"})
+	createNormalIndex("message_logs", []string{"guildid", "userid", "time"})
+	createNormalIndex("message_logs", []string{"guildid", "channelid", "time"})
+	createTTLIndex("message_logs", "expiresat")
+	createUniqueIndex("stream_notifications", []string{"channel_id", "video_id", "event"})
+	createUniqueIndex("live_streams", []string{"guild_id", "video_id"})
+	createTTLIndex("live_streams", "expires_at")
+	if removeDuplicateWatches() {
+		createUniqueIndex("watched_videos", []string{"channel_id", "video_id"})
+	} else {
+		log.Printf("Not making watches unique until the duplicates are removed, will try again on the next start")
+	}
+	createUniqueIndex("youtube_comments", []string{"watch_id", "comment_id"})
+	createNormalIndex("mirrored_messages", []string{"source_message_id"})
+	createUniqueIndex("archived_attachments", []string{"attachment_id"})
+	createNormalIndex("archived_attachments", []string{"message_id"})
+	createNormalIndex("archived_attachments", []string{"hash"})
+	createUniqueIndex("cases", []string{"guild_id", "case_number"})
+	createNormalIndex("cases", []string{"guild_id", "user_id", "created_at"})
+	createNormalIndex("cases", []string{"active", "expires_at"})
+	createUniqueIndex("automod_rules", []string{"guild_id", "name"})
+}
+
+// removeDuplicateWatches keep only the first watch of a video in a channel, they weren't unique before.
+// Returns whether there are none left.
+func removeDuplicateWatches() bool {
+	db := MDB.DB(DB_NAME)
+	dups := []struct {
+		IDs []bson.ObjectId `bson:"ids"`
+	}{}
+	err := db.C("watched_videos").Pipe([]bson.M{
+		{"$sort": bson.M{"_id": 1}},
+		{"$group": bson.M{
+			"_id":   bson.M{"channel_id": "$channel_id", "video_id": "$video_id"},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}).All(&dups)
+	if err != nil {
+		log.Printf("Failed to look for duplicate watches, %s", err)
+		return false
+	}
+
+	ok := true
+	for _, dup := range dups {
+		extra := dup.IDs[1:]
+		log.Printf("Removing duplicate watches %v", extra)
+		_, err := db.C("youtube_comments").RemoveAll(bson.M{"watch_id": bson.M{"$in": extra}})
+		if err != nil {
+			log.Printf("Failed to remove the comments of duplicate watches %v, %s", extra, err)
+			ok = false
+			continue
+		}
+		_, err = db.C("watched_videos").RemoveAll(bson.M{"_id": bson.M{"$in": extra}})
+		if err != nil {
+			log.Printf("Failed to remove duplicate watches %v, %s", extra, err)
+			ok = false
+		}
+	}
+	return ok
 }
 
 func createNormalIndex(collection string, index []string) {
@@ -103,6 +165,19 @@ func createNormalIndex(collection string, index []string) {
 	}
 }
 
+// createTTLIndex remove documents once the time in the field has passed, documents without it are kept
+func createTTLIndex(collection string, field string) {
+	idx := mgo.Index{
+		Key:         []string{field},
+		Background:  true,
+		ExpireAfter: time.Second,
+	}
+	err := MDB.DB(DB_NAME).C(collection).EnsureIndex(idx)
+	if err != nil {
+		panic(err)
+	}
+}
+
 func createUniqueIndex(collection string, index []string) {
 	idx := mgo.Index{
 		Key:        index,
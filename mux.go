@@ -25,6 +25,7 @@ func init() {
 	Session.AddHandler(Router.OnMessageUpdate)
 	Session.AddHandler(Router.AddReaction)
 	Session.AddHandler(Router.RemoveReaction)
+	Session.AddHandler(Router.OnGuildMemberAdd)
 
 	env := os.Getenv("DELUBOT_ENV")
 
@@ -34,11 +35,29 @@ func init() {
 	} else {
 		// Remote only commands
 		Router.Route("ytcopy", "Copy messages from the channel to a specified Youtube chat", Router.YoutubeCopy, models.AL_DEV)
+		Router.Route("ytrelay", "Relay a Youtube live chat into the channel ('<video>', 'stop')", Router.YoutubeRelay, models.AL_STAFF)
+		Router.Route("ytwatch", "Post new comments on a Youtube video in the channel ('<video> [talent] [threads] [keywords]')", Router.YoutubeWatch, models.AL_STAFF)
+		Router.Route("ytunwatch", "Stop posting comments on a Youtube video in the channel", Router.YoutubeUnwatch, models.AL_STAFF)
+		Router.Route("ytaccounts", "List the Youtube accounts messages are copied with, and their health", Router.YoutubeAccounts, models.AL_DEV)
+		Router.Route("ytlink", "Link a Youtube account to copy messages with, through DMs ('remove <email>' to unlink)", Router.YoutubeLink, models.AL_DEV)
 		Router.Route("endcopy", "Stop copying messages from the channel to Youtube", Router.EndYoutubeCopy, models.AL_DEV)
 		Router.Route("doubletl", "Copy from public live TL channel to members live TL channel", Router.DoubleTL, models.AL_STAFF)
+		Router.Route("mirror", "Copy the channel's messages into another channel, following edits and deletes ('<#channel> [plain]', 'stop <#channel>')", Router.Mirror, models.AL_MOD)
 		Router.Route("clear", "Clear messages from the channel until reaching the replied-to message", Router.ClearUntil, models.AL_STAFF)
 		Router.Route("help", "Display this message.", Router.Help, models.AL_STAFF)
 		Router.Route("mods", "List people with moderator permissions", Router.Mods, models.AL_MOD)
+		Router.Route("logs", "Search the message logs and export them as a file ('user:', 'channel:', 'after:', 'before:', 'retention')", Router.Logs, models.AL_MOD)
+		Router.Route("modlog", "Post deleted and edited messages to the log channel ('on', 'off', 'ignore <#channel>', 'unignore <#channel>')", Router.ModLog, models.AL_MOD)
+		Router.Route("archive", "Archive attachments, and get them back after the message is deleted ('on', 'off', '<message ID>')", Router.Archive, models.AL_MOD)
+		Router.Route("warn", "Warn someone, recording it as a case ('<@user> [reason]')", Router.Warn, models.AL_MOD)
+		Router.Route("mute", "Mute someone with the mute role ('<@user> [duration] [reason]')", Router.Mute, models.AL_MOD)
+		Router.Route("unmute", "Lift someone's mute or timeout ('<@user> [reason]')", Router.Unmute, models.AL_MOD)
+		Router.Route("timeout", "Time someone out for up to 28 days ('<@user> <duration> [reason]')", Router.Timeout, models.AL_MOD)
+		Router.Route("kick", "Kick someone from the server ('<@user> [reason]')", Router.Kick, models.AL_MOD)
+		Router.Route("ban", "Ban someone from the server ('<@user> [reason]')", Router.Ban, models.AL_MOD)
+		Router.Route("case", "Show a moderation case ('<number>')", Router.Case, models.AL_MOD)
+		Router.Route("history", "List someone's moderation cases ('<@user>')", Router.History, models.AL_MOD)
+		Router.Route("automod", "Check messages against rules and delete, warn, mute or log ('add', 'remove', 'test <text>')", Router.AutomodRules, models.AL_MOD)
 		Router.Route("countmembers", "Count the members on the server.", Router.CountMembers, models.AL_STAFF)
 		Router.Route("alpharole", "Display or set the configured Alpha role ('clear' to clear).", Router.AlphaRole, models.AL_MOD)
 		Router.Route("specialrole", "Display or set the configured Special role ('clear' to clear).", Router.SpecialRole, models.AL_MOD)
@@ -55,11 +74,19 @@ func init() {
 		Router.Route("v", "Grant current roles and copy the verification to the role sync spreadsheet", Router.Verify, models.AL_STAFF)
 		Router.Route("vf", "Grant past-month roles without verifying in the spreadsheet", Router.Verify, models.AL_STAFF)
 		Router.Route("vd", "Debug the verify command", Router.VDebug, models.AL_STAFF)
-		Router.Route("addstream", "Add a stream to the schedule manually ('yyyy/mm/dd hh:mm <title>')", Router.AddStream, models.AL_STAFF)
-		Router.Route("addguerrilla", "Add a guerrilla stream to the schedule manually ('yyyy/mm/dd hh:mm <est. time> <title>')", Router.AddGuerrilla, models.AL_STAFF)
-		Router.Route("removestream", "Remove a manually added stream ('yyyy/mm/dd hh:mm')", Router.RemoveStream, models.AL_STAFF)
-		Router.Route("streams", "Display upcoming streams", Router.Streams, models.AL_STAFF)
+		Router.Route("addstream", "Add a stream to the schedule manually ('<time> <title>', in your time zone)", Router.AddStream, models.AL_STAFF)
+		Router.Route("addguerrilla", "Add a guerrilla stream to the schedule manually ('<time> <est. time> <title>', in your time zone)", Router.AddGuerrilla, models.AL_STAFF)
+		Router.Route("removestream", "Remove a manually added stream ('<id>')", Router.RemoveStream, models.AL_STAFF)
+		Router.Route("editstream", "Change a manually added stream ('<id> title|time|guerrilla|video <value>')", Router.EditStream, models.AL_STAFF)
+		Router.Route("manualstreams", "List the manually added streams and their IDs ('past' for past ones)", Router.ManualStreams, models.AL_STAFF)
+		Router.Route("timezone", "Show or set the time zone your stream times are read in ('<zone>', 'reset')", Router.Timezone, models.AL_STAFF)
+		Router.Route("streams", "Display upcoming streams ('ics' for a calendar file)", Router.Streams, models.AL_STAFF)
 		Router.Route("stream", "Display upcoming streams", Router.Stream, models.AL_STAFF)
+		Router.Route("ytchannels", "List, add ('add <channel id> [label]') or remove ('remove <channel id>') the Youtube channels on the schedule", Router.YoutubeChannels, models.AL_STAFF)
+		Router.Route("streamstats", "Show monthly stream totals and streaks from the stream archive", Router.StreamStats, models.AL_STAFF)
+		Router.Route("ytquota", "Show how much of today's Youtube API quota has been spent", Router.YoutubeQuota, models.AL_STAFF)
+		Router.Route("streamnotify", "Notify this channel when streams are scheduled, start soon, go live or end ('on', 'off', 'role', 'before')", Router.StreamNotify, models.AL_MOD)
+		Router.Route("scheduleboard", "Keep an updated stream schedule at the bottom of the channel ('remove' to remove)", Router.ScheduleBoard, models.AL_STAFF)
 		Router.Route("avatar", "Set the bot avatar", Router.Avatar, models.AL_DEV)
 		Router.Route("nickname", "Set the bot nickname", Router.Nickname, models.AL_DEV)
 		// Router.Route("proposal", "Create a sign-off sheet following the message.", Router.Proposal, models.AL_STAFF)
@@ -67,6 +94,7 @@ func init() {
 		Router.Route("headpat", "Give a headpat", Router.Headpat, models.AL_EVERYONE)
 		Router.Route("ttl", "Provide translation for the most recent untranslated tweet in a Twitter feed channel", Router.TweetTranslate, models.AL_STAFF)
 		Router.Route("tedit", "Provide translation for the nth tweet (counting upwards) in a Twitter feed channel", Router.TweetEdit, models.AL_STAFF)
+		Router.Route("tweetsync", "Manage Tweet syncs ('list', 'add', 'remove', 'pause', 'resume', 'backfill', 'repost')", Router.TweetSync, models.AL_STAFF)
 		Router.Route("tl", "Translate from Japanese to English", Router.Translate, models.AL_STAFF)
 		Router.Route("extractmessages", "Delete an entire segment of chat messages, in between two messages that match a given pattern", Router.ExtractMessages, models.AL_MOD)
 		Router.Route("sticky", "Make a message stay at the bottom of the chat", Router.Sticky, models.AL_STAFF)
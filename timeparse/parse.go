@@ -0,0 +1,269 @@
+// Package timeparse reads the times people type into commands,
+// from exact dates to things like "tomorrow 21:00", "in 2h" or "9pm PST".
+package timeparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Zone abbreviations people commonly use, mapped to the zone they mean.
+// "PST" is taken to mean Pacific time whatever the season, which is how people use it.
+var zoneAbbreviations = map[string]string{
+	"JST":  "Asia/Tokyo",
+	"KST":  "Asia/Seoul",
+	"CDT":  "America/Chicago",
+	"PST":  "America/Los_Angeles",
+	"PDT":  "America/Los_Angeles",
+	"PT":   "America/Los_Angeles",
+	"MST":  "America/Denver",
+	"MDT":  "America/Denver",
+	"EST":  "America/New_York",
+	"EDT":  "America/New_York",
+	"ET":   "America/New_York",
+	"GMT":  "Europe/London",
+	"BST":  "Europe/London",
+	"CET":  "Europe/Paris",
+	"CEST": "Europe/Paris",
+	"AEST": "Australia/Sydney",
+	"AEDT": "Australia/Sydney",
+	"UTC":  "UTC",
+	"Z":    "UTC",
+}
+
+// Abbreviations that mean different zones to different people, like China or Central Standard Time,
+// so they're refused rather than guessed
+var ambiguousZones = map[string]string{
+	"CST": "Asia/Shanghai or America/Chicago",
+	"IST": "Asia/Kolkata or Europe/Dublin",
+}
+
+// ambiguousZoneError a zone abbreviation that can't be used because it's ambiguous
+type ambiguousZoneError struct {
+	name     string
+	examples string
+}
+
+func (e *ambiguousZoneError) Error() string {
+	return fmt.Sprintf("'%s' could mean more than one time zone, use a name like %s instead", e.name, e.examples)
+}
+
+var offsetRE = regexp.MustCompile(`^(?:UTC|GMT)?([+-])(\d{1,2})(?::?(\d\d))?$`)
+
+// LoadZone find a time zone by IANA name, common abbreviation or UTC offset
+func LoadZone(name string) (*time.Location, error) {
+	if iana, ok := zoneAbbreviations[strings.ToUpper(name)]; ok {
+		return time.LoadLocation(iana)
+	}
+	if examples, ok := ambiguousZones[strings.ToUpper(name)]; ok {
+		return nil, &ambiguousZoneError{name, examples}
+	}
+
+	if m := offsetRE.FindStringSubmatch(strings.ToUpper(name)); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+
+	// IANA names are case sensitive and never a bare word
+	if strings.Contains(name, "/") {
+		return time.LoadLocation(name)
+	}
+
+	return nil, fmt.Errorf("unknown time zone '%s'", name)
+}
+
+var discordTimestampRE = regexp.MustCompile(`^<t:(-?\d+)(?::[tTdDfFR])?>$`)
+var relativeRE = regexp.MustCompile(`^in (\d+)\s*(d|days?|h|hrs?|hours?|m|mins?|minutes?)(?:\s*(\d+)\s*(m|mins?|minutes?))?$`)
+var clockRE = regexp.MustCompile(`^(\d{1,2})(?::(\d\d))?\s*(am|pm)?$`)
+
+var dateLayouts = []string{
+	"2006/01/02 15:04",
+	"2006-01-02 15:04",
+	"2006/1/2 15:04",
+	"2006/01/02",
+	"2006-01-02",
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Parse read a time typed by a user. Times without an explicit zone are in loc,
+// and a bare time of day that already passed today means tomorrow.
+func Parse(raw string, now time.Time, loc *time.Location) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, errors.New("no time given")
+	}
+
+	if m := discordTimestampRE.FindStringSubmatch(raw); m != nil {
+		unix, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(unix, 0), nil
+	}
+
+	lower := strings.ToLower(raw)
+	if m := relativeRE.FindStringSubmatch(lower); m != nil {
+		d := relativeDuration(m[1], m[2])
+		if m[3] != "" {
+			d += relativeDuration(m[3], m[4])
+		}
+		return now.Add(d), nil
+	}
+
+	// An explicit zone at the end overrides the user's
+	fields := strings.Fields(raw)
+	if len(fields) > 1 {
+		zone, err := LoadZone(fields[len(fields)-1])
+		if err == nil {
+			loc = zone
+			fields = fields[:len(fields)-1]
+		} else if _, ok := err.(*ambiguousZoneError); ok {
+			return time.Time{}, err
+		}
+	}
+	raw = strings.Join(fields, " ")
+	now = now.In(loc)
+
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, raw, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	// A day word, optionally followed by a time of day
+	day := ""
+	clock := strings.ToLower(raw)
+	if len(fields) > 0 {
+		first := strings.ToLower(fields[0])
+		if first == "today" || first == "tonight" || first == "tomorrow" {
+			day = first
+		} else if _, ok := weekdays[first]; ok {
+			day = first
+		}
+		if day != "" {
+			clock = strings.ToLower(strings.Join(fields[1:], " "))
+		}
+	}
+
+	hour, minute := 0, 0
+	if clock != "" {
+		m := clockRE.FindStringSubmatch(clock)
+		if m == nil {
+			return time.Time{}, fmt.Errorf("I don't understand the time '%s'", raw)
+		}
+		var err error
+		hour, minute, err = clockTime(m[1], m[2], m[3])
+		if err != nil {
+			return time.Time{}, err
+		}
+	} else if day == "" {
+		return time.Time{}, fmt.Errorf("I don't understand the time '%s'", raw)
+	} else if day == "tonight" {
+		hour = 21
+	}
+
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+	switch day {
+	case "":
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+	case "today", "tonight":
+	case "tomorrow":
+		t = t.AddDate(0, 0, 1)
+	default:
+		days := (int(weekdays[day]) - int(now.Weekday()) + 7) % 7
+		if days == 0 && !t.After(now) {
+			days = 7
+		}
+		t = t.AddDate(0, 0, days)
+	}
+
+	return t, nil
+}
+
+// ParsePrefix read a time from the start of a command's arguments, returning whatever follows it.
+// The longest run of words that reads as a time wins, so "tomorrow 21:00 JST Minecraft" leaves "Minecraft".
+func ParsePrefix(raw string, now time.Time, loc *time.Location) (time.Time, string, error) {
+	fields := strings.Fields(raw)
+	maxWords := 5
+	if len(fields) < maxWords {
+		maxWords = len(fields)
+	}
+
+	for n := maxWords; n > 0; n-- {
+		t, err := Parse(strings.Join(fields[:n], " "), now, loc)
+		if err == nil {
+			return t, strings.Join(fields[n:], " "), nil
+		}
+		// Don't fall back to a shorter prefix that leaves the zone out
+		if _, ok := err.(*ambiguousZoneError); ok {
+			return time.Time{}, raw, err
+		}
+	}
+
+	return time.Time{}, raw, errors.New("I don't understand that time")
+}
+
+func relativeDuration(amount, unit string) time.Duration {
+	n, _ := strconv.Atoi(amount)
+	switch unit[0] {
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour
+	case 'h':
+		return time.Duration(n) * time.Hour
+	default:
+		return time.Duration(n) * time.Minute
+	}
+}
+
+func clockTime(hourStr, minuteStr, ampm string) (int, int, error) {
+	hour, _ := strconv.Atoi(hourStr)
+	minute := 0
+	if minuteStr != "" {
+		minute, _ = strconv.Atoi(minuteStr)
+	}
+
+	if ampm != "" {
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("%d%s isn't a time", hour, ampm)
+		}
+		hour = hour % 12
+		if ampm == "pm" {
+			hour += 12
+		}
+	} else if minuteStr == "" {
+		// A bare number is too easily something else, like the start of a title
+		return 0, 0, fmt.Errorf("'%s' needs minutes or am/pm", hourStr)
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("%02d:%02d isn't a time", hour, minute)
+	}
+	return hour, minute, nil
+}
+
+// Discord format a time as a Discord timestamp, which every member sees in their own time zone.
+// Style is one of t, T, d, D, f, F or R (relative).
+func Discord(t time.Time, style string) string {
+	return fmt.Sprintf("<t:%d:%s>", t.Unix(), style)
+}
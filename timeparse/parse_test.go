@@ -0,0 +1,101 @@
+package timeparse
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	pacific, _ := time.LoadLocation("America/Los_Angeles")
+	// A Wednesday afternoon in Tokyo, still Tuesday night in California
+	now := time.Date(2021, 3, 10, 15, 0, 0, 0, tokyo)
+
+	tests := []struct {
+		raw  string
+		want time.Time
+	}{
+		{"2021/03/12 21:00", time.Date(2021, 3, 12, 21, 0, 0, 0, tokyo)},
+		{"2021-03-12 21:00", time.Date(2021, 3, 12, 21, 0, 0, 0, tokyo)},
+		{"tomorrow 21:00", time.Date(2021, 3, 11, 21, 0, 0, 0, tokyo)},
+		{"today 9pm", time.Date(2021, 3, 10, 21, 0, 0, 0, tokyo)},
+		{"tonight", time.Date(2021, 3, 10, 21, 0, 0, 0, tokyo)},
+		{"21:00", time.Date(2021, 3, 10, 21, 0, 0, 0, tokyo)},
+		{"10:00", time.Date(2021, 3, 11, 10, 0, 0, 0, tokyo)},
+		{"12am", time.Date(2021, 3, 11, 0, 0, 0, 0, tokyo)},
+		{"saturday 20:00", time.Date(2021, 3, 13, 20, 0, 0, 0, tokyo)},
+		{"wednesday 10:00", time.Date(2021, 3, 17, 10, 0, 0, 0, tokyo)},
+		{"in 2h", now.Add(2 * time.Hour)},
+		{"in 90m", now.Add(90 * time.Minute)},
+		{"in 1h 30m", now.Add(90 * time.Minute)},
+		{"in 3 days", now.Add(72 * time.Hour)},
+		{"9pm PST", time.Date(2021, 3, 10, 21, 0, 0, 0, pacific)},
+		{"tomorrow 21:00 UTC+2", time.Date(2021, 3, 11, 19, 0, 0, 0, time.UTC)},
+		{"2021/03/12 21:00 America/New_York", time.Date(2021, 3, 13, 2, 0, 0, 0, time.UTC)},
+		{"<t:1615377600:F>", time.Unix(1615377600, 0)},
+		{"<t:1615377600>", time.Unix(1615377600, 0)},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.raw, now, tokyo)
+		if err != nil {
+			t.Errorf("Parse(%q) failed, %s", test.raw, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("Parse(%q) = %s, want %s", test.raw, got, test.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	now := time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC)
+	for _, raw := range []string{"", "Minecraft", "3", "25:00", "13pm", "tomorrow Minecraft", "in a while"} {
+		_, err := Parse(raw, now, time.UTC)
+		if err == nil {
+			t.Errorf("Parse(%q) should have failed", raw)
+		}
+	}
+}
+
+func TestAmbiguousZone(t *testing.T) {
+	now := time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	if _, err := LoadZone("cst"); err == nil {
+		t.Error("LoadZone(cst) should have failed")
+	}
+	if _, err := Parse("9pm IST", now, time.UTC); err == nil || !strings.Contains(err.Error(), "Asia/Kolkata") {
+		t.Errorf("Parse(9pm IST) should ask for a zone name, got %v", err)
+	}
+	// A shorter prefix would leave the zone in the title
+	if _, rest, err := ParsePrefix("tomorrow 21:00 CST Minecraft", now, time.UTC); err == nil {
+		t.Errorf("ParsePrefix with CST should have failed, left %q", rest)
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	now := time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		raw  string
+		want time.Time
+		rest string
+	}{
+		{"2021/03/12 21:00 Minecraft collab", time.Date(2021, 3, 12, 21, 0, 0, 0, time.UTC), "Minecraft collab"},
+		{"tomorrow 21:00 JST 3D reveal", time.Date(2021, 3, 12, 12, 0, 0, 0, time.UTC), "3D reveal"},
+		{"in 2 hours 2h Karaoke", now.Add(2 * time.Hour), "2h Karaoke"},
+		{"<t:1615377600:F> Zatsudan", time.Unix(1615377600, 0), "Zatsudan"},
+	}
+
+	for _, test := range tests {
+		got, rest, err := ParsePrefix(test.raw, now, time.UTC)
+		if err != nil {
+			t.Errorf("ParsePrefix(%q) failed, %s", test.raw, err)
+			continue
+		}
+		if !got.Equal(test.want) || rest != test.rest {
+			t.Errorf("ParsePrefix(%q) = %s, %q, want %s, %q", test.raw, got, rest, test.want, test.rest)
+		}
+	}
+}
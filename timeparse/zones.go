@@ -0,0 +1,42 @@
+package timeparse
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+)
+
+// UserZone the time zone a user set, or the bot's own zone if they didn't set one
+func UserZone(db *mgo.Database, userID string) *time.Location {
+	utz := models.UserTimezone{}
+	err := db.C("user_timezones").FindId(userID).One(&utz)
+	if err != nil {
+		return config.Loc
+	}
+
+	loc, err := LoadZone(utz.Zone)
+	if err != nil {
+		return config.Loc
+	}
+	return loc
+}
+
+// SetUserZone store the time zone a user's times are read in, an empty zone resets it
+func SetUserZone(db *mgo.Database, userID string, zone string) error {
+	if zone == "" {
+		err := db.C("user_timezones").RemoveId(userID)
+		if err == mgo.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	_, err := db.C("user_timezones").UpsertId(userID, bson.M{"$set": bson.M{
+		"zone":       zone,
+		"updated_at": time.Now(),
+	}})
+	return err
+}
@@ -0,0 +1,318 @@
+package tweetsync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/dghubble/go-twitter/twitter"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/tl"
+	"github.com/w8kerr/delubot/utils"
+)
+
+// FeedBackfiller A feed that can look back over posts that were already passed by the cursor
+type FeedBackfiller interface {
+	FetchRange(since, until time.Time) ([]models.FeedPost, error)
+}
+
+// Twitter's epoch for snowflake IDs, in milliseconds
+const twitterEpoch = 1288834974657
+
+// TweetIDTime the time a Tweet was created, read from its snowflake ID
+func TweetIDTime(id int64) time.Time {
+	ms := (id >> 22) + twitterEpoch
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+var tweetIDRE = regexp.MustCompile(`^\d{15,}$`)
+
+// ParseBackfillBound parse one end of a backfill range, either a Tweet ID or a JST date or time.
+// A bare date used as the end of the range covers the whole day.
+func ParseBackfillBound(raw string, isEnd bool) (time.Time, error) {
+	if tweetIDRE.MatchString(raw) {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return TweetIDTime(id), nil
+	}
+
+	t, err := time.ParseInLocation("2006/01/02 15:04", raw, config.Loc)
+	if err == nil {
+		return t, nil
+	}
+
+	t, err = time.ParseInLocation("2006/01/02", raw, config.Loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' isn't a Tweet ID or yyyy/mm/dd date", raw)
+	}
+	if isEnd {
+		t = t.Add(24*time.Hour - time.Millisecond)
+	}
+	return t, nil
+}
+
+func (src *TwitterSource) FetchRange(since, until time.Time) ([]models.FeedPost, error) {
+	posts := []models.FeedPost{}
+
+	// The timeline API only goes back 3200 Tweets, 200 at a time
+	var maxID int64
+	for page := 0; page < 16; page++ {
+		tweets, _, err := src.client.Timelines.UserTimeline(&twitter.UserTimelineParams{
+			ScreenName: src.handle,
+			Count:      200,
+			MaxID:      maxID,
+			TweetMode:  "extended",
+		})
+		if err != nil {
+			return posts, err
+		}
+		if len(tweets) == 0 {
+			break
+		}
+
+		reachedStart := false
+		for i := range tweets {
+			post := TweetToPost(&tweets[i])
+			if post.CreatedAt.Before(since) {
+				reachedStart = true
+				continue
+			}
+			if !post.CreatedAt.After(until) {
+				posts = append(posts, post)
+			}
+		}
+		if reachedStart {
+			break
+		}
+
+		maxID = tweets[len(tweets)-1].ID - 1
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreatedAt.Before(posts[j].CreatedAt)
+	})
+
+	return posts, nil
+}
+
+// FetchPost get a single Tweet by its ID
+func (src *TwitterSource) FetchPost(id int64) (models.FeedPost, error) {
+	tweet, _, err := src.client.Statuses.Show(id, &twitter.StatusShowParams{
+		TweetMode: "extended",
+	})
+	if err != nil {
+		return models.FeedPost{}, err
+	}
+
+	return TweetToPost(tweet), nil
+}
+
+// FetchRange feeds only list their most recent posts, so this can't reach further back than the current page
+func (src *RSSSource) FetchRange(since, until time.Time) ([]models.FeedPost, error) {
+	body, err := fetchFeedBody(src.url)
+	if err != nil {
+		return []models.FeedPost{}, err
+	}
+	defer body.Close()
+
+	posts, err := ParseFeed(body)
+	if err != nil {
+		return []models.FeedPost{}, err
+	}
+
+	return postsInRange(posts, since, until), nil
+}
+
+// FetchRange only covers the first page of the Nitter timeline
+func (src *NitterSource) FetchRange(since, until time.Time) ([]models.FeedPost, error) {
+	body, err := fetchFeedBody(src.instance + "/" + src.handle)
+	if err != nil {
+		return []models.FeedPost{}, err
+	}
+	defer body.Close()
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return []models.FeedPost{}, err
+	}
+
+	return postsInRange(ParseNitterTimeline(string(raw), src.instance), since, until), nil
+}
+
+func postsInRange(posts []models.FeedPost, since, until time.Time) []models.FeedPost {
+	res := []models.FeedPost{}
+	for _, post := range posts {
+		if !post.CreatedAt.Before(since) && !post.CreatedAt.After(until) {
+			res = append(res, post)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+
+	return res
+}
+
+// SyncConfigsForHandle all syncs following the given handle
+func SyncConfigsForHandle(handle string) []*config.TweetSyncConfig {
+	handle = strings.TrimPrefix(handle, "@")
+	res := []*config.TweetSyncConfig{}
+	tss := config.GetTweetSyncChannels()
+	for i := range tss {
+		if strings.EqualFold(tss[i].Handle, handle) {
+			res = append(res, &tss[i])
+		}
+	}
+	return res
+}
+
+// ReplayResult Counts of what a replay did to each channel
+type ReplayResult struct {
+	Posted  int
+	Updated int
+	Failed  int
+}
+
+// Backfill mirror every post in a time range into all syncs of a handle
+func Backfill(ds *discordgo.Session, db *mgo.Database, handle string, since, until time.Time) (ReplayResult, error) {
+	res := ReplayResult{}
+
+	tss := SyncConfigsForHandle(handle)
+	if len(tss) == 0 {
+		return res, fmt.Errorf("no Tweet sync is set up for '%s'", handle)
+	}
+
+	src, err := NewFeedSource(tss[0])
+	if err != nil {
+		return res, err
+	}
+	backfiller, ok := src.(FeedBackfiller)
+	if !ok {
+		return res, fmt.Errorf("%s can't be backfilled", src.Name())
+	}
+
+	posts, err := backfiller.FetchRange(since, until)
+	if err != nil {
+		return res, err
+	}
+
+	for _, post := range posts {
+		for _, ts := range tss {
+			res.add(ReplayPost(ds, db, ts, post))
+		}
+	}
+
+	return res, nil
+}
+
+// Repost mirror a single Tweet again into all syncs of its author
+func Repost(ds *discordgo.Session, db *mgo.Database, tweetID string) (ReplayResult, error) {
+	res := ReplayResult{}
+
+	id, err := strconv.ParseInt(tweetID, 10, 64)
+	if err != nil {
+		return res, fmt.Errorf("'%s' isn't a Tweet ID", tweetID)
+	}
+
+	post, err := NewTwitterSource(TwitterClient(), "").FetchPost(id)
+	if err != nil {
+		return res, err
+	}
+
+	tss := SyncConfigsForHandle(post.Author.Handle)
+	if len(tss) == 0 {
+		return res, fmt.Errorf("no Tweet sync is set up for '%s'", post.Author.Handle)
+	}
+
+	for _, ts := range tss {
+		res.add(ReplayPost(ds, db, ts, post))
+	}
+
+	return res, nil
+}
+
+func (rr *ReplayResult) add(updated bool, err error) {
+	if err != nil {
+		rr.Failed++
+	} else if updated {
+		rr.Updated++
+	} else {
+		rr.Posted++
+	}
+}
+
+// ReplayPost mirror a post into a sync's channel without moving its cursor.
+// If the post was already mirrored there, the existing message is re-translated and edited instead of posting a duplicate.
+func ReplayPost(ds *discordgo.Session, db *mgo.Database, ts *config.TweetSyncConfig, post models.FeedPost) (bool, error) {
+	stCol := db.C("synced_tweets")
+
+	existing := models.SyncedTweet{}
+	err := stCol.Find(bson.M{
+		"channel_id": ts.ChannelID,
+		"$or": []bson.M{
+			{"post.id": post.ID},
+			{"tweet.idstr": post.ID},
+		},
+	}).One(&existing)
+	if err != nil && err != mgo.ErrNotFound {
+		return false, err
+	}
+
+	if err == mgo.ErrNotFound {
+		st, err := MirrorPost(ds, db, ts, post)
+		if err != nil {
+			return false, err
+		}
+		st.Replay = true
+		return false, stCol.Insert(st)
+	}
+
+	existing.Post = post
+	if post.Tweet != nil {
+		existing.Tweet = *post.Tweet
+		existing.QuoteTranslation, existing.ReplyText = tweetContext(db, post.Tweet)
+	}
+	if !existing.HumanTranslated {
+		translation, _, err := tl.DeepLTranslate(post.Text, tl.LangEN)
+		if err != nil {
+			translation = fmt.Sprintf("[Translation error: %s]", err)
+		}
+		existing.Translation = translation
+	}
+	existing.UpdatedAt = time.Now()
+
+	err = stCol.UpdateId(existing.OID, existing)
+	if err != nil {
+		return true, err
+	}
+
+	embeds := SyncedTweetToEmbeds(existing)
+	if existing.MessageID != "" {
+		_, err = utils.ChannelMessageEditEmbeds(ds, existing.ChannelID, existing.MessageID, embeds)
+		if err != nil {
+			log.Printf("Failed to edit replayed post %s, %s", post.ID, err)
+			return true, err
+		}
+	}
+	if existing.ControlMessageID != "" {
+		_, err = utils.ChannelMessageEditEmbeds(ds, existing.ControlChannelID, existing.ControlMessageID, embeds)
+		if err != nil {
+			log.Printf("Failed to edit replayed control post %s, %s", post.ID, err)
+			return true, err
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,34 @@
+package tweetsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/w8kerr/delubot/config"
+)
+
+func Test_ParseBackfillBound(t *testing.T) {
+	config.Loc, _ = time.LoadLocation("Asia/Tokyo")
+
+	// Snowflake of a Tweet posted 2021-03-01 03:00:00.000 UTC
+	since, err := ParseBackfillBound("1366221653406646272", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since.UTC().Format(time.RFC3339) != "2021-03-01T03:00:00Z" {
+		t.Errorf("unexpected snowflake time %s", since.UTC())
+	}
+
+	until, err := ParseBackfillBound("2021/03/01", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !until.Equal(time.Date(2021, 3, 1, 14, 59, 59, 999000000, time.UTC)) {
+		t.Errorf("unexpected end of day %s", until.UTC())
+	}
+
+	_, err = ParseBackfillBound("yesterday", false)
+	if err == nil {
+		t.Errorf("expected an error for an unknown bound")
+	}
+}
@@ -0,0 +1,134 @@
+package tweetsync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/dghubble/go-twitter/twitter"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+)
+
+// Discord merges at most 4 embeds that share a URL into one gallery
+const maxGalleryImages = 4
+
+// Color of the embed of a post that was deleted upstream
+const deletedColor = 10038562
+
+// SyncedTweetToEmbeds render a synced post as its main embed, followed by the extra embeds of its image gallery
+func SyncedTweetToEmbeds(st models.SyncedTweet) []*discordgo.MessageEmbed {
+	if st.Tweet.ID == 0 && st.Post.ID != "" {
+		embed := PostToEmbed(st.Post, st.Translation, st.Translators)
+		AddUpstreamChanges(embed, st)
+		return AddGallery(embed, st.Post.URL, st.Post.Media)
+	}
+
+	post := TweetToPost(&st.Tweet)
+	embed := TweetToEmbed(&st.Tweet, st.Translation, st.Translators)
+	AddReplyContext(embed, &st.Tweet, st.ReplyText)
+	AddQuoteTweet(embed, st.Tweet.QuotedStatus, st.QuoteTranslation)
+	AddUpstreamChanges(embed, st)
+	return AddGallery(embed, post.URL, post.Media)
+}
+
+// AddUpstreamChanges note on the embed that the post was deleted or edited after it was mirrored.
+// The mirrored text is left as it was, so it stays archived.
+func AddUpstreamChanges(embed *discordgo.MessageEmbed, st models.SyncedTweet) {
+	if st.Deleted {
+		embed.Color = deletedColor
+		embed.Description = fmt.Sprintf("🗑 ~~**Deleted upstream**~~ (noticed %s)\n%s", st.DeletedAt.In(config.Loc).Format("2006/01/02 15:04 MST"), embed.Description)
+	}
+
+	if st.EditedText != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "✏ Edited upstream",
+			Value: "```" + truncate(st.EditedText, 900) + "```",
+		})
+	}
+}
+
+// AddGallery show all photos of a post as a gallery, and link any videos with their thumbnails
+func AddGallery(embed *discordgo.MessageEmbed, url string, media []models.FeedMedia) []*discordgo.MessageEmbed {
+	embeds := []*discordgo.MessageEmbed{embed}
+	if len(media) == 0 {
+		return embeds
+	}
+
+	embed.URL = url
+	embed.Image = &discordgo.MessageEmbedImage{
+		URL: media[0].ThumbnailURL,
+	}
+
+	videoLinks := []string{}
+	images := 1
+	for i, m := range media {
+		if m.Type == "video" {
+			videoLinks = append(videoLinks, fmt.Sprintf("[▶ Video %d](%s)", len(videoLinks)+1, m.URL))
+		}
+		if i == 0 || images >= maxGalleryImages {
+			continue
+		}
+		embeds = append(embeds, &discordgo.MessageEmbed{
+			URL: url,
+			Image: &discordgo.MessageEmbedImage{
+				URL: m.ThumbnailURL,
+			},
+		})
+		images++
+	}
+
+	if len(videoLinks) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "🎞 Video",
+			Value: strings.Join(videoLinks, "\n"),
+		})
+	}
+
+	return embeds
+}
+
+// AddReplyContext note which Tweet this one replied to, with its text if we mirrored it
+func AddReplyContext(embed *discordgo.MessageEmbed, tweet *twitter.Tweet, replyText string) {
+	if tweet.InReplyToStatusIDStr == "" {
+		return
+	}
+
+	context := fmt.Sprintf("↪ Replying to [@%s](https://twitter.com/%s/status/%s)", tweet.InReplyToScreenName, tweet.InReplyToScreenName, tweet.InReplyToStatusIDStr)
+	if replyText != "" {
+		context += "\n> " + strings.ReplaceAll(truncate(replyText, 200), "\n", "\n> ")
+	}
+
+	embed.Description = context + "\n" + embed.Description
+}
+
+// AddQuoteTweet show the text of a quoted Tweet along with its own translation
+func AddQuoteTweet(embed *discordgo.MessageEmbed, quoted *twitter.Tweet, translation string) {
+	if quoted == nil || quoted.User == nil {
+		return
+	}
+
+	text := quoted.FullText
+	if text == "" {
+		text = quoted.Text
+	}
+
+	value := fmt.Sprintf("[Status: %s](https://twitter.com/%s/status/%s)\n", quoted.IDStr, quoted.User.ScreenName, quoted.IDStr)
+	if translation != "" {
+		value += truncate(translation, 400) + "\n*TL: DeepL*\n"
+	}
+	value += "```" + truncate(text, 400) + "```"
+
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:  fmt.Sprintf("❝ Quoting %s (@%s)", quoted.User.Name, quoted.User.ScreenName),
+		Value: value,
+	})
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-1]) + "…"
+}
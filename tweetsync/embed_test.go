@@ -0,0 +1,79 @@
+package tweetsync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dghubble/go-twitter/twitter"
+	"github.com/w8kerr/delubot/models"
+)
+
+func Test_SyncedTweetToEmbeds(t *testing.T) {
+	photo := func(url string) twitter.MediaEntity {
+		return twitter.MediaEntity{Type: "photo", MediaURLHttps: url}
+	}
+
+	st := models.SyncedTweet{
+		Tweet: twitter.Tweet{
+			ID:                   2,
+			IDStr:                "2",
+			FullText:             "写真です",
+			CreatedAt:            "Mon Mar 1 12:00:00 +0000 2021",
+			User:                 &twitter.User{Name: "でるた", ScreenName: "delutaya"},
+			InReplyToScreenName:  "delutaya",
+			InReplyToStatusIDStr: "1",
+			ExtendedEntities: &twitter.ExtendedEntity{
+				Media: []twitter.MediaEntity{
+					photo("https://pbs.twimg.com/1.jpg"),
+					photo("https://pbs.twimg.com/2.jpg"),
+					{
+						Type:          "video",
+						MediaURLHttps: "https://pbs.twimg.com/thumb.jpg",
+						VideoInfo: twitter.VideoInfo{
+							Variants: []twitter.VideoVariant{
+								{ContentType: "video/mp4", Bitrate: 256, URL: "https://video.twimg.com/low.mp4"},
+								{ContentType: "video/mp4", Bitrate: 2176, URL: "https://video.twimg.com/high.mp4"},
+							},
+						},
+					},
+				},
+			},
+			QuotedStatus: &twitter.Tweet{
+				IDStr:    "0",
+				FullText: "引用",
+				User:     &twitter.User{Name: "Other", ScreenName: "other"},
+			},
+		},
+		Translation:      "It's a photo",
+		QuoteTranslation: "Quote",
+		ReplyText:        "First tweet",
+		Translators:      []string{"DeepL"},
+	}
+
+	embeds := SyncedTweetToEmbeds(st)
+	if len(embeds) != 3 {
+		t.Fatalf("expected 3 embeds, got %d", len(embeds))
+	}
+	for _, e := range embeds {
+		if e.URL != "https://twitter.com/delutaya/status/2" {
+			t.Errorf("gallery embed has wrong URL %q", e.URL)
+		}
+	}
+	if embeds[0].Image.URL != "https://pbs.twimg.com/1.jpg" || embeds[2].Image.URL != "https://pbs.twimg.com/thumb.jpg" {
+		t.Errorf("unexpected gallery images")
+	}
+
+	main := embeds[0]
+	if !strings.HasPrefix(main.Description, "↪ Replying to [@delutaya](https://twitter.com/delutaya/status/1)\n> First tweet") {
+		t.Errorf("missing reply context: %s", main.Description)
+	}
+	if len(main.Fields) != 2 {
+		t.Fatalf("expected quote and video fields, got %d", len(main.Fields))
+	}
+	if !strings.Contains(main.Fields[0].Value, "Quote") || !strings.Contains(main.Fields[0].Value, "引用") {
+		t.Errorf("unexpected quote field %q", main.Fields[0].Value)
+	}
+	if !strings.Contains(main.Fields[1].Value, "https://video.twimg.com/high.mp4") {
+		t.Errorf("unexpected video field %q", main.Fields[1].Value)
+	}
+}
@@ -0,0 +1,65 @@
+package tweetsync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+	"github.com/dghubble/oauth1"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+)
+
+// FeedSource A public feed that posts can be pulled from and mirrored into Discord
+type FeedSource interface {
+	// Name a short label for the feed, used in logs and embed footers
+	Name() string
+	// Interval how long to wait between polls
+	Interval() time.Duration
+	// Fetch get all posts newer than the cursor, oldest first, along with the cursor of the newest post.
+	// An empty cursor returns no posts, only the cursor of the most recent post.
+	Fetch(cursor string) ([]models.FeedPost, string, error)
+	// PostCursor the cursor that resumes right after a post
+	PostCursor(post models.FeedPost) string
+}
+
+var twitterClient *twitter.Client
+
+// TwitterClient the shared Twitter API client, created on first use
+func TwitterClient() *twitter.Client {
+	if twitterClient != nil {
+		return twitterClient
+	}
+
+	apiKey := os.Getenv("TWITTER_API_KEY")
+	apiSecret := os.Getenv("TWITTER_API_SECRET")
+
+	userToken := os.Getenv("DELU_TWEETSYNC_TOKEN")
+	userSecret := os.Getenv("DELU_TWEETSYNC_SECRET")
+
+	con := oauth1.NewConfig(apiKey, apiSecret)
+	token := oauth1.NewToken(userToken, userSecret)
+	httpClient := con.Client(oauth1.NoContext, token)
+
+	twitterClient = twitter.NewClient(httpClient)
+	return twitterClient
+}
+
+// NewFeedSource create the feed source described by a sync config
+func NewFeedSource(ts *config.TweetSyncConfig) (FeedSource, error) {
+	switch ts.FeedSource() {
+	case models.FeedSourceTwitter:
+		return NewTwitterSource(TwitterClient(), ts.Handle), nil
+	case models.FeedSourceRSS:
+		if ts.FeedURL == "" {
+			return nil, errors.New("RSS feeds need a feed URL")
+		}
+		return NewRSSSource(ts.Handle, ts.FeedURL), nil
+	case models.FeedSourceNitter:
+		return NewNitterSource(ts.FeedURL, ts.Handle), nil
+	default:
+		return nil, fmt.Errorf("Unknown feed source '%s'", ts.Source)
+	}
+}
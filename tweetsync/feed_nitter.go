@@ -0,0 +1,178 @@
+package tweetsync
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/w8kerr/delubot/models"
+)
+
+// DefaultNitterInstance the instance used when a Nitter sync doesn't specify one
+var DefaultNitterInstance = "https://nitter.net"
+
+var NitterTimeFormat = "Jan 2, 2006 · 3:04 PM MST"
+
+// NitterSource Follows a Twitter account by scraping the HTML timeline of a Nitter instance
+type NitterSource struct {
+	instance string
+	handle   string
+}
+
+func NewNitterSource(instance, handle string) *NitterSource {
+	if instance == "" {
+		instance = DefaultNitterInstance
+	}
+	return &NitterSource{
+		instance: strings.TrimSuffix(instance, "/"),
+		handle:   handle,
+	}
+}
+
+func (src *NitterSource) Name() string {
+	return "Nitter @" + src.handle
+}
+
+func (src *NitterSource) Interval() time.Duration {
+	return 60 * time.Second
+}
+
+func (src *NitterSource) PostCursor(post models.FeedPost) string {
+	return post.ID
+}
+
+// Fetch uses the newest status ID as the cursor, like the Twitter API's since ID
+func (src *NitterSource) Fetch(cursor string) ([]models.FeedPost, string, error) {
+	body, err := fetchFeedBody(src.instance + "/" + src.handle)
+	if err != nil {
+		return []models.FeedPost{}, cursor, err
+	}
+	defer body.Close()
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return []models.FeedPost{}, cursor, err
+	}
+
+	posts := ParseNitterTimeline(string(raw), src.instance)
+	if len(posts) == 0 {
+		return posts, cursor, nil
+	}
+
+	newest := posts[len(posts)-1].ID
+
+	// Don't return any posts, just set the most recent one
+	if cursor == "" {
+		return []models.FeedPost{}, newest, nil
+	}
+
+	sinceID, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil {
+		return []models.FeedPost{}, cursor, fmt.Errorf("bad Nitter cursor '%s'", cursor)
+	}
+
+	res := []models.FeedPost{}
+	for _, post := range posts {
+		id, _ := strconv.ParseInt(post.ID, 10, 64)
+		if id > sinceID {
+			res = append(res, post)
+		}
+	}
+	if len(res) == 0 {
+		return res, cursor, nil
+	}
+
+	return res, newest, nil
+}
+
+var nitterLinkRE = regexp.MustCompile(`class="tweet-link" href="/([^/"]+)/status/(\d+)`)
+var nitterContentRE = regexp.MustCompile(`(?s)<div class="tweet-content media-body" dir="auto">(.*?)</div>`)
+var nitterFullnameRE = regexp.MustCompile(`class="fullname" href="[^"]*" title="([^"]*)"`)
+var nitterAvatarRE = regexp.MustCompile(`class="avatar round" src="([^"]+)"`)
+var nitterDateRE = regexp.MustCompile(`class="tweet-date"><a href="[^"]*" title="([^"]+)"`)
+var nitterImageRE = regexp.MustCompile(`class="still-image" href="([^"]+)"`)
+var nitterVideoRE = regexp.MustCompile(`(?s)class="attachment video-container">\s*<img src="([^"]+)"`)
+
+// ParseNitterTimeline scrape the posts out of a Nitter timeline page, oldest first.
+// Pinned posts and retweets are skipped, since they aren't new posts by the account.
+func ParseNitterTimeline(page, instance string) []models.FeedPost {
+	posts := []models.FeedPost{}
+
+	items := strings.Split(page, `<div class="timeline-item`)
+	for _, item := range items[1:] {
+		if strings.Contains(item, `class="pinned"`) || strings.Contains(item, `class="retweet-header"`) {
+			continue
+		}
+
+		link := nitterLinkRE.FindStringSubmatch(item)
+		if link == nil {
+			continue
+		}
+		handle := link[1]
+		id := link[2]
+
+		post := models.FeedPost{
+			ID:     id,
+			Source: models.FeedSourceNitter,
+			URL:    fmt.Sprintf("https://twitter.com/%s/status/%s", handle, id),
+			Author: models.FeedAuthor{
+				Name:   handle,
+				Handle: handle,
+				URL:    fmt.Sprintf("https://twitter.com/%s", handle),
+			},
+			Via:   "Nitter",
+			Media: []models.FeedMedia{},
+		}
+
+		if m := nitterContentRE.FindStringSubmatch(item); m != nil {
+			post.Text = HTMLToText(m[1])
+		}
+		if m := nitterFullnameRE.FindStringSubmatch(item); m != nil {
+			post.Author.Name = html.UnescapeString(m[1])
+		}
+		if m := nitterAvatarRE.FindStringSubmatch(item); m != nil {
+			post.Author.AvatarURL = nitterURL(instance, m[1])
+		}
+		if m := nitterDateRE.FindStringSubmatch(item); m != nil {
+			post.CreatedAt, _ = time.Parse(NitterTimeFormat, html.UnescapeString(m[1]))
+		}
+		for _, m := range nitterImageRE.FindAllStringSubmatch(item, -1) {
+			url := nitterURL(instance, m[1])
+			post.Media = append(post.Media, models.FeedMedia{
+				Type:         "photo",
+				URL:          url,
+				ThumbnailURL: url,
+			})
+		}
+		for _, m := range nitterVideoRE.FindAllStringSubmatch(item, -1) {
+			post.Media = append(post.Media, models.FeedMedia{
+				Type:         "video",
+				URL:          post.URL,
+				ThumbnailURL: nitterURL(instance, m[1]),
+			})
+		}
+
+		posts = append(posts, post)
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		a, _ := strconv.ParseInt(posts[i].ID, 10, 64)
+		b, _ := strconv.ParseInt(posts[j].ID, 10, 64)
+		return a < b
+	})
+
+	return posts
+}
+
+func nitterURL(instance, path string) string {
+	path = html.UnescapeString(path)
+	if strings.HasPrefix(path, "http") {
+		return path
+	}
+	return instance + path
+}
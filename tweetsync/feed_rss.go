@@ -0,0 +1,394 @@
+package tweetsync
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/w8kerr/delubot/models"
+)
+
+var feedHTTPClient = &http.Client{Timeout: 20 * time.Second}
+
+// RSSSource Follows an RSS 2.0 or Atom feed
+type RSSSource struct {
+	label string
+	url   string
+}
+
+func NewRSSSource(label, url string) *RSSSource {
+	return &RSSSource{
+		label: label,
+		url:   url,
+	}
+}
+
+func (src *RSSSource) Name() string {
+	if src.label != "" {
+		return src.label
+	}
+	return src.url
+}
+
+func (src *RSSSource) Interval() time.Duration {
+	return 60 * time.Second
+}
+
+func (src *RSSSource) PostCursor(post models.FeedPost) string {
+	return post.CreatedAt.Format(time.RFC3339)
+}
+
+// Fetch uses the published time of the newest post as the cursor, since feed IDs aren't ordered
+func (src *RSSSource) Fetch(cursor string) ([]models.FeedPost, string, error) {
+	body, err := fetchFeedBody(src.url)
+	if err != nil {
+		return []models.FeedPost{}, cursor, err
+	}
+	defer body.Close()
+
+	posts, err := ParseFeed(body)
+	if err != nil {
+		return []models.FeedPost{}, cursor, err
+	}
+
+	return postsSinceTime(posts, cursor)
+}
+
+func fetchFeedBody(url string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "DeluBot")
+
+	resp, err := feedHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("feed returned %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// postsSinceTime filter posts to those published after a time cursor, oldest first
+func postsSinceTime(posts []models.FeedPost, cursor string) ([]models.FeedPost, string, error) {
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreatedAt.Before(posts[j].CreatedAt)
+	})
+
+	if len(posts) == 0 {
+		return []models.FeedPost{}, cursor, nil
+	}
+
+	newest := posts[len(posts)-1].CreatedAt.Format(time.RFC3339)
+
+	// Don't return any posts, just set the most recent one
+	if cursor == "" {
+		return []models.FeedPost{}, newest, nil
+	}
+
+	since, err := time.Parse(time.RFC3339, cursor)
+	if err != nil {
+		return []models.FeedPost{}, cursor, fmt.Errorf("bad feed cursor '%s'", cursor)
+	}
+
+	res := []models.FeedPost{}
+	for _, post := range posts {
+		if post.CreatedAt.After(since) {
+			res = append(res, post)
+		}
+	}
+	if len(res) == 0 {
+		return res, cursor, nil
+	}
+
+	return res, newest, nil
+}
+
+type rssDoc struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string `xml:"title"`
+		Link  string `xml:"link"`
+		Image struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+
+	// Atom
+	Title   string      `xml:"title"`
+	Author  atomAuthor  `xml:"author"`
+	Icon    string      `xml:"icon"`
+	Logo    string      `xml:"logo"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"creator"`
+	Enclosures  []struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	MediaContents []struct {
+		URL    string `xml:"url,attr"`
+		Medium string `xml:"medium,attr"`
+	} `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Content   string     `xml:"content"`
+	Summary   string     `xml:"summary"`
+	Author    atomAuthor `xml:"author"`
+	Group     struct {
+		Description string `xml:"description"`
+		Thumbnail   struct {
+			URL string `xml:"url,attr"`
+		} `xml:"thumbnail"`
+	} `xml:"group"`
+}
+
+// ParseFeed parse an RSS 2.0 or Atom document into feed posts
+func ParseFeed(r io.Reader) ([]models.FeedPost, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return []models.FeedPost{}, err
+	}
+
+	doc := rssDoc{}
+	err = xml.Unmarshal(raw, &doc)
+	if err != nil {
+		return []models.FeedPost{}, err
+	}
+
+	posts := []models.FeedPost{}
+
+	switch doc.XMLName.Local {
+	case "rss":
+		author := models.FeedAuthor{
+			Name:      doc.Channel.Title,
+			URL:       doc.Channel.Link,
+			AvatarURL: doc.Channel.Image.URL,
+		}
+		for _, item := range doc.Channel.Items {
+			posts = append(posts, rssItemToPost(item, author))
+		}
+	case "feed":
+		author := models.FeedAuthor{
+			Name:      doc.Title,
+			URL:       doc.Author.URI,
+			AvatarURL: doc.Icon,
+		}
+		if author.AvatarURL == "" {
+			author.AvatarURL = doc.Logo
+		}
+		if doc.Author.Name != "" {
+			author.Name = doc.Author.Name
+		}
+		if author.URL == "" {
+			author.URL = atomAlternate(doc.Links)
+		}
+		for _, entry := range doc.Entries {
+			posts = append(posts, atomEntryToPost(entry, author))
+		}
+	default:
+		return posts, fmt.Errorf("unknown feed format '%s'", doc.XMLName.Local)
+	}
+
+	return posts, nil
+}
+
+func rssItemToPost(item rssItem, author models.FeedAuthor) models.FeedPost {
+	createdAt, _ := ParseFeedTime(item.PubDate)
+
+	if item.Creator != "" {
+		author.Name = item.Creator
+	} else if item.Author != "" {
+		author.Name = item.Author
+	}
+
+	text := HTMLToText(item.Description)
+	if text == "" {
+		text = item.Title
+	}
+
+	id := item.GUID
+	if id == "" {
+		id = item.Link
+	}
+
+	post := models.FeedPost{
+		ID:        id,
+		Source:    models.FeedSourceRSS,
+		Text:      text,
+		URL:       item.Link,
+		Author:    author,
+		CreatedAt: createdAt,
+		Media:     HTMLImages(item.Description),
+	}
+
+	for _, enc := range item.Enclosures {
+		post.Media = append(post.Media, models.FeedMedia{
+			Type:         mediaType(enc.Type),
+			URL:          enc.URL,
+			ThumbnailURL: enc.URL,
+		})
+	}
+	for _, mc := range item.MediaContents {
+		if mc.URL == "" {
+			continue
+		}
+		post.Media = append(post.Media, models.FeedMedia{
+			Type:         mediaType(mc.Medium),
+			URL:          mc.URL,
+			ThumbnailURL: mc.URL,
+		})
+	}
+
+	return post
+}
+
+func atomEntryToPost(entry atomEntry, author models.FeedAuthor) models.FeedPost {
+	createdAt, err := ParseFeedTime(entry.Published)
+	if err != nil {
+		createdAt, _ = ParseFeedTime(entry.Updated)
+	}
+
+	if entry.Author.Name != "" {
+		author.Name = entry.Author.Name
+	}
+	if entry.Author.URI != "" {
+		author.URL = entry.Author.URI
+	}
+
+	body := entry.Content
+	if body == "" {
+		body = entry.Summary
+	}
+	if body == "" {
+		body = entry.Group.Description
+	}
+
+	text := HTMLToText(body)
+	if text == "" {
+		text = entry.Title
+	}
+
+	post := models.FeedPost{
+		ID:        entry.ID,
+		Source:    models.FeedSourceRSS,
+		Text:      text,
+		URL:       atomAlternate(entry.Links),
+		Author:    author,
+		CreatedAt: createdAt,
+		Media:     HTMLImages(body),
+	}
+
+	if entry.Group.Thumbnail.URL != "" {
+		post.Media = append(post.Media, models.FeedMedia{
+			Type:         "video",
+			URL:          post.URL,
+			ThumbnailURL: entry.Group.Thumbnail.URL,
+		})
+	}
+
+	return post
+}
+
+func atomAlternate(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+func mediaType(mime string) string {
+	if strings.HasPrefix(mime, "video") {
+		return "video"
+	}
+	return "photo"
+}
+
+var feedTimeFormats = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+}
+
+// ParseFeedTime parse the various timestamp formats found in feeds
+func ParseFeedTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	for _, format := range feedTimeFormats {
+		t, err := time.Parse(format, raw)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format '%s'", raw)
+}
+
+var brRE = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+var tagRE = regexp.MustCompile(`<[^>]*>`)
+var imgRE = regexp.MustCompile(`(?i)<img[^>]+src="([^"]+)"`)
+
+// HTMLToText strip markup from a snippet of HTML, keeping line breaks
+func HTMLToText(raw string) string {
+	text := brRE.ReplaceAllString(raw, "\n")
+	text = tagRE.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(text)
+}
+
+// HTMLImages find all images embedded in a snippet of HTML
+func HTMLImages(raw string) []models.FeedMedia {
+	media := []models.FeedMedia{}
+	for _, m := range imgRE.FindAllStringSubmatch(raw, -1) {
+		url := html.UnescapeString(m[1])
+		media = append(media, models.FeedMedia{
+			Type:         "photo",
+			URL:          url,
+			ThumbnailURL: url,
+		})
+	}
+	return media
+}
@@ -0,0 +1,161 @@
+package tweetsync
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+	<title>Delutaya</title>
+	<link>https://example.com/delutaya</link>
+	<image><url>https://example.com/avatar.png</url></image>
+	<item>
+		<title>Second</title>
+		<link>https://example.com/posts/2</link>
+		<guid>post-2</guid>
+		<pubDate>Tue, 02 Mar 2021 12:00:00 +0000</pubDate>
+		<description>&lt;p&gt;配信します&lt;br&gt;21時から&lt;/p&gt;&lt;img src="https://example.com/2.png"&gt;</description>
+	</item>
+	<item>
+		<title>First</title>
+		<link>https://example.com/posts/1</link>
+		<guid>post-1</guid>
+		<pubDate>Mon, 01 Mar 2021 12:00:00 +0000</pubDate>
+		<dc:creator>でるた</dc:creator>
+		<description>おはよう</description>
+	</item>
+</channel>
+</rss>`
+
+var testAtom = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<title>Delutaya Ch.</title>
+	<author><name>Delutaya</name><uri>https://example.com/channel</uri></author>
+	<entry>
+		<id>yt:video:abc</id>
+		<title>Morning stream</title>
+		<link rel="alternate" href="https://www.youtube.com/watch?v=abc"/>
+		<published>2021-03-01T12:00:00+00:00</published>
+		<media:group>
+			<media:description>Let's talk</media:description>
+			<media:thumbnail url="https://i.ytimg.com/vi/abc/hqdefault.jpg"/>
+		</media:group>
+	</entry>
+</feed>`
+
+var testNitter = `<div class="timeline">
+<div class="timeline-item " data-username="delutaya">
+	<a class="tweet-link" href="/delutaya/status/100#m"></a>
+	<div class="pinned"><span>Pinned Tweet</span></div>
+	<div class="tweet-content media-body" dir="auto">pinned</div>
+</div>
+<div class="timeline-item " data-username="delutaya">
+	<a class="tweet-link" href="/delutaya/status/300#m"></a>
+	<a class="fullname" href="/delutaya" title="でるた">でるた</a>
+	<img class="avatar round" src="/pic/profile_images%2F1%2Fa_bigger.jpg" alt="">
+	<span class="tweet-date"><a href="/delutaya/status/300#m" title="Mar 2, 2021 · 12:00 PM UTC">1h</a></span>
+	<div class="tweet-content media-body" dir="auto">こんばんは &amp; よろしく</div>
+	<a class="still-image" href="/pic/media%2Fx.jpg" target="_blank"><img src="/pic/media%2Fx_small.jpg"></a>
+</div>
+<div class="timeline-item " data-username="someone">
+	<div class="retweet-header"><span>delutaya retweeted</span></div>
+	<a class="tweet-link" href="/someone/status/250#m"></a>
+	<div class="tweet-content media-body" dir="auto">retweeted</div>
+</div>
+<div class="timeline-item " data-username="delutaya">
+	<a class="tweet-link" href="/delutaya/status/200#m"></a>
+	<div class="tweet-content media-body" dir="auto">おはよう</div>
+</div>
+</div>`
+
+func Test_ParseRSS(t *testing.T) {
+	posts, err := ParseFeed(strings.NewReader(testRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(posts))
+	}
+
+	if posts[0].Text != "配信します\n21時から" {
+		t.Errorf("unexpected text %q", posts[0].Text)
+	}
+	if len(posts[0].Media) != 1 || posts[0].Media[0].URL != "https://example.com/2.png" {
+		t.Errorf("unexpected media %v", posts[0].Media)
+	}
+	if posts[0].Author.AvatarURL != "https://example.com/avatar.png" {
+		t.Errorf("unexpected avatar %q", posts[0].Author.AvatarURL)
+	}
+	if posts[1].Author.Name != "でるた" {
+		t.Errorf("unexpected author %q", posts[1].Author.Name)
+	}
+
+	// Priming returns no posts, only the newest cursor
+	res, cursor, err := postsSinceTime(posts, "")
+	if err != nil || len(res) != 0 || cursor != "2021-03-02T12:00:00Z" {
+		t.Errorf("unexpected priming result %v %q %v", res, cursor, err)
+	}
+
+	res, cursor, err = postsSinceTime(posts, "2021-03-01T12:00:00Z")
+	if err != nil || len(res) != 1 || res[0].ID != "post-2" || cursor != "2021-03-02T12:00:00Z" {
+		t.Errorf("unexpected fetch result %v %q %v", res, cursor, err)
+	}
+
+	// Resuming after a post that was sent picks up the ones after it
+	src := NewRSSSource("delu", "")
+	res, _, err = postsSinceTime(posts, src.PostCursor(posts[0]))
+	if err != nil || len(res) != 1 || res[0].ID != posts[1].ID {
+		t.Errorf("unexpected resume result %v %v", res, err)
+	}
+}
+
+func Test_ParseAtom(t *testing.T) {
+	posts, err := ParseFeed(strings.NewReader(testAtom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("expected 1 post, got %d", len(posts))
+	}
+
+	post := posts[0]
+	if post.URL != "https://www.youtube.com/watch?v=abc" || post.Text != "Let's talk" || post.Author.Name != "Delutaya" {
+		t.Errorf("unexpected post %+v", post)
+	}
+	if !post.CreatedAt.Equal(time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time %s", post.CreatedAt)
+	}
+	if len(post.Media) != 1 || post.Media[0].Type != "video" {
+		t.Errorf("unexpected media %v", post.Media)
+	}
+}
+
+func Test_ParseNitter(t *testing.T) {
+	posts := ParseNitterTimeline(testNitter, "https://nitter.example")
+	if len(posts) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(posts))
+	}
+	if posts[0].ID != "200" || posts[1].ID != "300" {
+		t.Errorf("posts out of order, %s %s", posts[0].ID, posts[1].ID)
+	}
+
+	post := posts[1]
+	if post.Text != "こんばんは & よろしく" || post.Author.Name != "でるた" {
+		t.Errorf("unexpected post %+v", post)
+	}
+	if post.URL != "https://twitter.com/delutaya/status/300" {
+		t.Errorf("unexpected URL %q", post.URL)
+	}
+	if post.Author.AvatarURL != "https://nitter.example/pic/profile_images%2F1%2Fa_bigger.jpg" {
+		t.Errorf("unexpected avatar %q", post.Author.AvatarURL)
+	}
+	if len(post.Media) != 1 || post.Media[0].URL != "https://nitter.example/pic/media%2Fx.jpg" {
+		t.Errorf("unexpected media %v", post.Media)
+	}
+	if !post.CreatedAt.Equal(time.Date(2021, 3, 2, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time %s", post.CreatedAt)
+	}
+}
@@ -0,0 +1,143 @@
+package tweetsync
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+	"github.com/w8kerr/delubot/models"
+)
+
+// TwitterSource Follows a user timeline through the Twitter API
+type TwitterSource struct {
+	client *twitter.Client
+	handle string
+}
+
+func NewTwitterSource(tc *twitter.Client, handle string) *TwitterSource {
+	return &TwitterSource{
+		client: tc,
+		handle: handle,
+	}
+}
+
+func (src *TwitterSource) Name() string {
+	return "Twitter @" + src.handle
+}
+
+func (src *TwitterSource) Interval() time.Duration {
+	return 3 * time.Second
+}
+
+func (src *TwitterSource) PostCursor(post models.FeedPost) string {
+	return post.ID
+}
+
+func (src *TwitterSource) Fetch(cursor string) ([]models.FeedPost, string, error) {
+	// Don't return any tweets, just set the most recent one
+	if cursor == "" {
+		trimUser := true
+		tweets, _, err := src.client.Timelines.UserTimeline(&twitter.UserTimelineParams{
+			ScreenName: src.handle,
+			Count:      1,
+			TrimUser:   &trimUser,
+		})
+		if err != nil {
+			return []models.FeedPost{}, "", err
+		}
+		if len(tweets) == 0 {
+			return []models.FeedPost{}, "", errors.New("no tweets returned")
+		}
+
+		return []models.FeedPost{}, tweets[0].IDStr, nil
+	}
+
+	sinceID, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil {
+		return []models.FeedPost{}, cursor, fmt.Errorf("bad Twitter cursor '%s'", cursor)
+	}
+
+	tweets, _, err := src.client.Timelines.UserTimeline(&twitter.UserTimelineParams{
+		ScreenName: src.handle,
+		SinceID:    sinceID,
+		TweetMode:  "extended",
+	})
+	if err != nil {
+		return []models.FeedPost{}, cursor, err
+	}
+
+	sort.Slice(tweets, func(i, j int) bool {
+		return tweets[i].ID < tweets[j].ID
+	})
+
+	posts := []models.FeedPost{}
+	for i := range tweets {
+		posts = append(posts, TweetToPost(&tweets[i]))
+		cursor = tweets[i].IDStr
+	}
+
+	return posts, cursor, nil
+}
+
+// TweetToPost normalize a Tweet into a feed post
+func TweetToPost(tweet *twitter.Tweet) models.FeedPost {
+	createdAt, _ := time.Parse(TwitterTimeFormat, tweet.CreatedAt)
+
+	text := tweet.FullText
+	if text == "" {
+		text = tweet.Text
+	}
+
+	post := models.FeedPost{
+		ID:        tweet.IDStr,
+		Source:    models.FeedSourceTwitter,
+		Text:      text,
+		CreatedAt: createdAt,
+		Via:       tweet.Source,
+		Tweet:     tweet,
+	}
+
+	if tweet.User != nil {
+		post.URL = fmt.Sprintf("https://twitter.com/%s/status/%s", tweet.User.ScreenName, tweet.IDStr)
+		post.Author = models.FeedAuthor{
+			Name:      tweet.User.Name,
+			Handle:    tweet.User.ScreenName,
+			URL:       fmt.Sprintf("https://twitter.com/%s", tweet.User.ScreenName),
+			AvatarURL: strings.Replace(tweet.User.ProfileImageURLHttps, "_normal.", "_400x400.", 1),
+		}
+	}
+
+	if tweet.ExtendedEntities != nil {
+		for _, media := range tweet.ExtendedEntities.Media {
+			fm := models.FeedMedia{
+				Type:         media.Type,
+				URL:          media.MediaURLHttps,
+				ThumbnailURL: media.MediaURLHttps,
+			}
+			if media.Type == "video" || media.Type == "animated_gif" {
+				fm.Type = "video"
+				fm.URL = bestVideoURL(media)
+			}
+			post.Media = append(post.Media, fm)
+		}
+	}
+
+	return post
+}
+
+// bestVideoURL the highest bitrate MP4 of a video, falling back to the Tweet's link to it
+func bestVideoURL(media twitter.MediaEntity) string {
+	url := media.ExpandedURL
+	bitrate := -1
+	for _, v := range media.VideoInfo.Variants {
+		if v.ContentType == "video/mp4" && v.Bitrate > bitrate {
+			url = v.URL
+			bitrate = v.Bitrate
+		}
+	}
+	return url
+}
@@ -0,0 +1,348 @@
+package tweetsync
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/utils"
+)
+
+// Scanner Polls the feed of one sync and mirrors new posts, until it is stopped
+type Scanner struct {
+	ts   config.TweetSyncConfig
+	src  FeedSource
+	stop chan struct{}
+
+	mu          sync.Mutex
+	running     bool
+	retrying    bool
+	lastPoll    time.Time
+	lastError   string
+	lastErrorAt time.Time
+	lastPost    time.Time
+	lag         time.Duration
+}
+
+// ScannerStatus A snapshot of a scanner's state for display
+type ScannerStatus struct {
+	Handle      string
+	ChannelID   string
+	Source      string
+	Running     bool
+	Retrying    bool
+	LastPoll    time.Time
+	LastError   string
+	LastErrorAt time.Time
+	LastPost    time.Time
+	Lag         time.Duration
+}
+
+// How long to wait before trying again to start a scanner that failed to start
+var startRetryInterval = 5 * time.Minute
+
+var scanners = make(map[string]*Scanner)
+var scannersMu sync.Mutex
+
+func scannerKey(handle, channelID string) string {
+	return handle + "/" + channelID
+}
+
+// StartScanner begin polling a sync's feed, replacing any scanner already running for it.
+// If it fails to start, it's listed with the error and tried again until it starts or is stopped.
+func StartScanner(ds *discordgo.Session, ts config.TweetSyncConfig) error {
+	err := startScanner(ds, ts, nil)
+	if err != nil {
+		retryScanner(ds, ts, err)
+	}
+	return err
+}
+
+// errScannerStopped the sync was stopped or removed while a retry was starting it
+var errScannerStopped = errors.New("scanner was stopped while starting")
+
+// startScanner start polling a sync's feed. When a retry is starting it, the new scanner only replaces
+// the retry if that is still listed and running, so a sync removed in the meantime doesn't come back.
+func startScanner(ds *discordgo.Session, ts config.TweetSyncConfig, retry *Scanner) error {
+	fmt.Println("Init Tweetsync - Handle", ts.Handle)
+	fmt.Println("Init Tweetsync - Channel ID", ts.ChannelID)
+
+	src, err := NewFeedSource(&ts)
+	if err != nil {
+		return err
+	}
+
+	// Don't return any posts, just set the most recent one
+	cursor := ts.FeedCursor()
+	if cursor == "" {
+		_, cursor, err = src.Fetch("")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Set most recent post", ts.Handle, ts.ChannelID, cursor)
+		err = config.SetTweetSyncCursor(&ts, cursor)
+		if err != nil {
+			return err
+		}
+	}
+
+	if retry != nil {
+		configured, paused := syncState(ts.Handle, ts.ChannelID)
+		if !configured {
+			RemoveScanner(ts.Handle, ts.ChannelID)
+			return errScannerStopped
+		}
+		if paused {
+			StopScanner(ts.Handle, ts.ChannelID)
+			return errScannerStopped
+		}
+	}
+
+	sc := &Scanner{
+		ts:      ts,
+		src:     src,
+		stop:    make(chan struct{}),
+		running: true,
+	}
+
+	key := scannerKey(ts.Handle, ts.ChannelID)
+	scannersMu.Lock()
+	if retry != nil {
+		retry.mu.Lock()
+		stopped := !retry.running
+		retry.mu.Unlock()
+		if scanners[key] != retry || stopped {
+			scannersMu.Unlock()
+			return errScannerStopped
+		}
+	}
+	if old, ok := scanners[key]; ok {
+		old.halt()
+	}
+	scanners[key] = sc
+	scannersMu.Unlock()
+
+	go sc.Scan(ds, cursor)
+	return nil
+}
+
+// syncState whether a sync is still in the config, and whether it's paused
+func syncState(handle, channelID string) (bool, bool) {
+	for _, ts := range config.GetTweetSyncChannels() {
+		if ts.Handle == handle && ts.ChannelID == channelID {
+			return true, ts.Paused
+		}
+	}
+	return false, false
+}
+
+// retryScanner list a sync that failed to start, and keep trying to start it
+func retryScanner(ds *discordgo.Session, ts config.TweetSyncConfig, err error) {
+	StopScanner(ts.Handle, ts.ChannelID)
+
+	sc := &Scanner{
+		ts:          ts,
+		stop:        make(chan struct{}),
+		running:     true,
+		retrying:    true,
+		lastError:   err.Error(),
+		lastErrorAt: time.Now(),
+	}
+
+	scannersMu.Lock()
+	scanners[scannerKey(ts.Handle, ts.ChannelID)] = sc
+	scannersMu.Unlock()
+
+	go sc.retryStart(ds)
+}
+
+// retryStart try to start the sync now and then, until it starts or the scanner is stopped
+func (sc *Scanner) retryStart(ds *discordgo.Session) {
+	for {
+		select {
+		case <-sc.stop:
+			return
+		case <-time.After(startRetryInterval):
+		}
+
+		err := startScanner(ds, sc.ts, sc)
+		if err == errScannerStopped {
+			return
+		}
+		if err == nil {
+			log.Printf("Started Tweet sync for %s after retrying", sc.ts.Handle)
+			return
+		}
+
+		log.Printf("Failed to start Tweet sync for %s again, %s", sc.ts.Handle, err)
+		sc.mu.Lock()
+		sc.lastError = err.Error()
+		sc.lastErrorAt = time.Now()
+		sc.mu.Unlock()
+	}
+}
+
+// RegisterPausedScanner list a paused sync without polling it
+func RegisterPausedScanner(ts config.TweetSyncConfig) {
+	scannersMu.Lock()
+	defer scannersMu.Unlock()
+
+	scanners[scannerKey(ts.Handle, ts.ChannelID)] = &Scanner{ts: ts}
+}
+
+// StopScanner stop polling a sync's feed, keeping it listed as paused
+func StopScanner(handle, channelID string) {
+	scannersMu.Lock()
+	defer scannersMu.Unlock()
+
+	sc, ok := scanners[scannerKey(handle, channelID)]
+	if !ok {
+		return
+	}
+	sc.halt()
+}
+
+// halt close the scanner's stop channel if it's still running
+func (sc *Scanner) halt() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.running {
+		close(sc.stop)
+		sc.running = false
+	}
+}
+
+// RemoveScanner stop a sync's scanner and forget about it
+func RemoveScanner(handle, channelID string) {
+	StopScanner(handle, channelID)
+
+	scannersMu.Lock()
+	delete(scanners, scannerKey(handle, channelID))
+	scannersMu.Unlock()
+}
+
+// ScannerStatuses the state of every known scanner, sorted by handle
+func ScannerStatuses() []ScannerStatus {
+	scannersMu.Lock()
+	defer scannersMu.Unlock()
+
+	res := []ScannerStatus{}
+	for _, sc := range scanners {
+		sc.mu.Lock()
+		res = append(res, ScannerStatus{
+			Handle:      sc.ts.Handle,
+			ChannelID:   sc.ts.ChannelID,
+			Source:      sc.ts.FeedSource(),
+			Running:     sc.running,
+			Retrying:    sc.retrying && sc.running,
+			LastPoll:    sc.lastPoll,
+			LastError:   sc.lastError,
+			LastErrorAt: sc.lastErrorAt,
+			LastPost:    sc.lastPost,
+			Lag:         sc.lag,
+		})
+		sc.mu.Unlock()
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Handle == res[j].Handle {
+			return res[i].ChannelID < res[j].ChannelID
+		}
+		return res[i].Handle < res[j].Handle
+	})
+
+	return res
+}
+
+// Scan poll the feed and mirror new posts until the scanner is stopped
+func (sc *Scanner) Scan(ds *discordgo.Session, cursor string) {
+	cl := utils.GetChannelLogger(ds, config.ErrorChannel)
+	sleepDuration := sc.src.Interval()
+
+	for {
+		select {
+		case <-sc.stop:
+			log.Printf("Stopped Tweet sync for %s", sc.src.Name())
+			return
+		case <-time.After(sleepDuration):
+		}
+
+		posts, nextCursor, err := sc.src.Fetch(cursor)
+		sc.recordPoll(err)
+		if err != nil {
+			cl.Printf("Failed to get %s feed, %s", sc.src.Name(), err)
+			continue
+		}
+
+		// Save to the DB
+		session := mongo.MDB.Clone()
+		session.SetMode(mgo.Strong, false)
+		db := session.DB(mongo.DB_NAME)
+		stCol := db.C("synced_tweets")
+
+		// Stop at the first post that couldn't be sent and only move the cursor past the ones that were,
+		// so it's picked up again on the next poll
+		for i, post := range posts {
+			st, err := MirrorPost(ds, db, &sc.ts, post)
+			if err != nil {
+				cl.Printf("Failed to mirror %s post, will retry, %s", sc.src.Name(), err)
+				sc.recordPoll(err)
+				if i == 0 {
+					nextCursor = cursor
+				} else {
+					nextCursor = sc.src.PostCursor(posts[i-1])
+				}
+				break
+			}
+
+			err = stCol.Insert(st)
+			if err != nil {
+				cl.Printf("Failed to save mirrored %s post %s, %s", sc.src.Name(), post.ID, err)
+			}
+			sc.recordPost(post.CreatedAt)
+		}
+
+		if nextCursor != cursor {
+			cursor = nextCursor
+			err = config.SetTweetSyncCursor(&sc.ts, cursor)
+			if err != nil {
+				cl.Printf("Failed to save %s feed position, %s", sc.src.Name(), err)
+			}
+		}
+
+		// fmt.Println("Finished echoing tweets")
+		session.Close()
+	}
+}
+
+func (sc *Scanner) recordPoll(err error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.lastPoll = time.Now()
+	if err != nil {
+		sc.lastError = err.Error()
+		sc.lastErrorAt = sc.lastPoll
+	}
+}
+
+// recordPost track how long it took for a post to be mirrored after it was published
+func (sc *Scanner) recordPost(createdAt time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if createdAt.IsZero() {
+		return
+	}
+	sc.lastPost = createdAt
+	sc.lag = time.Since(createdAt)
+}
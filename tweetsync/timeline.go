@@ -3,18 +3,15 @@ package tweetsync
 import (
 	"fmt"
 	"log"
-	"os"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/dghubble/go-twitter/twitter"
-	"github.com/dghubble/oauth1"
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/w8kerr/delubot/config"
 	"github.com/w8kerr/delubot/models"
-	"github.com/w8kerr/delubot/mongo"
 	"github.com/w8kerr/delubot/tl"
 	"github.com/w8kerr/delubot/utils"
 )
@@ -24,140 +21,90 @@ var TwitterTimeFormat = "Mon Jan 2 15:04:05 +0000 2006"
 // InitTimelines initialize all streams for Tweet streaming
 func InitTimelines(ds *discordgo.Session) {
 	fmt.Println("InitTimelines")
-	if len(config.TweetSyncChannels) == 0 {
-		return
-	}
+	for _, ts := range config.GetTweetSyncChannels() {
+		if ts.Paused {
+			RegisterPausedScanner(ts)
+			continue
+		}
 
-	apiKey := os.Getenv("TWITTER_API_KEY")
-	apiSecret := os.Getenv("TWITTER_API_SECRET")
+		err := StartScanner(ds, ts)
+		if err != nil {
+			log.Printf("Failed to start Tweet sync for %s, retrying, %s", ts.Handle, err)
+		}
+	}
+}
 
-	userToken := os.Getenv("DELU_TWEETSYNC_TOKEN")
-	userSecret := os.Getenv("DELU_TWEETSYNC_SECRET")
+// MirrorPost translate a post and send it to the sync's channels, returning the record to save.
+// If either send fails nothing is left posted, so the post can be tried again.
+func MirrorPost(ds *discordgo.Session, db *mgo.Database, ts *config.TweetSyncConfig, post models.FeedPost) (models.SyncedTweet, error) {
+	translation, _, err := tl.DeepLTranslate(post.Text, tl.LangEN)
+	if err != nil {
+		translation = fmt.Sprintf("[Translation error: %s]", err)
+	}
 
-	con := oauth1.NewConfig(apiKey, apiSecret)
-	token := oauth1.NewToken(userToken, userSecret)
-	httpClient := con.Client(oauth1.NoContext, token)
+	st := models.SyncedTweet{
+		Post:            post,
+		Translation:     translation,
+		CreatedAt:       time.Now(),
+		Translators:     []string{"DeepL"},
+		HumanTranslated: false,
+	}
+	if post.Tweet != nil {
+		st.Tweet = *post.Tweet
+		st.QuoteTranslation, st.ReplyText = tweetContext(db, post.Tweet)
+	}
 
-	// Twitter client
-	client := twitter.NewClient(httpClient)
+	if post.Tweet != nil && hasTOSMention(*post.Tweet) {
+		// Ignore this tweet and mark it as already translated so it doesn't interfere with the targeting of the command
+		st.HumanTranslated = true
+		return st, nil
+	}
 
-	for i := range config.TweetSyncChannels {
-		ScanTimeline(ds, client, &config.TweetSyncChannels[i])
+	embeds := SyncedTweetToEmbeds(st)
+	msg, err := utils.ChannelMessageSendEmbeds(ds, ts.ChannelID, embeds)
+	if err != nil {
+		return st, fmt.Errorf("failed to send post %s, %s", post.ID, err)
 	}
-}
+	st.ChannelID = msg.ChannelID
+	st.MessageID = msg.ID
 
-// ScanTimeline polls a stream of Tweets and posts them in the specified channel
-func ScanTimeline(ds *discordgo.Session, tc *twitter.Client, ts *config.TweetSyncConfig) {
-	fmt.Println("Init Tweetsync - Handle", ts.Handle)
-	fmt.Println("Init Tweetsync - Channel ID", ts.ChannelID)
-
-	// Don't return any tweets, just set the most recent one
-	if ts.SinceID == 0 {
-		trimUser := true
-		tweets, _, err := tc.Timelines.UserTimeline(&twitter.UserTimelineParams{
-			ScreenName: ts.Handle,
-			Count:      1,
-			TrimUser:   &trimUser,
-		})
+	if ts.ControlChannelID != "" {
+		cmsg, err := utils.ChannelMessageSendEmbeds(ds, ts.ControlChannelID, embeds)
 		if err != nil {
-			log.Printf("Failed to initialize Tweet stream, %s", err)
-			return
-		}
-		if len(tweets) == 0 {
-			log.Printf("Failed to initialize Tweet stream, no tweets returns")
-			return
+			ds.ChannelMessageDelete(msg.ChannelID, msg.ID)
+			return st, fmt.Errorf("failed to send control post %s, %s", post.ID, err)
 		}
-
-		fmt.Println("Set most recent tweet", ts.Handle, ts.ChannelID, tweets[0].IDStr)
-		config.SetTweetSyncSinceID(ts.Handle, ts.ChannelID, tweets[0].ID)
-		return
+		st.ControlChannelID = cmsg.ChannelID
+		st.ControlMessageID = cmsg.ID
 	}
 
-	go Scan(ds, tc, ts)
+	return st, nil
 }
 
-func Scan(ds *discordgo.Session, tc *twitter.Client, ts *config.TweetSyncConfig) {
-	cl := utils.GetChannelLogger(ds, config.ErrorChannel)
-	sleepDuration := 3 * time.Second
-	sinceID := ts.SinceID
-
-	for {
-		time.Sleep(sleepDuration)
-
-		tweets, _, err := tc.Timelines.UserTimeline(&twitter.UserTimelineParams{
-			ScreenName: ts.Handle,
-			SinceID:    sinceID,
-			TweetMode:  "extended",
-		})
-		if err != nil {
-			cl.Printf("Failed to get Tweet stream, %s", err)
-			continue
+// tweetContext translate the Tweet's quoted Tweet, and find the text of the Tweet it replied to if it was mirrored
+func tweetContext(db *mgo.Database, tweet *twitter.Tweet) (string, string) {
+	quoteTranslation := ""
+	if tweet.QuotedStatus != nil {
+		text := tweet.QuotedStatus.FullText
+		if text == "" {
+			text = tweet.QuotedStatus.Text
 		}
-
-		sort.Slice(tweets, func(i, j int) bool {
-			return tweets[i].ID < tweets[j].ID
-		})
-
-		// fmt.Println("Received", len(tweets), "tweets")
-
-		// Save to the DB
-		session := mongo.MDB.Clone()
-		session.SetMode(mgo.Strong, false)
-		db := session.DB(mongo.DB_NAME)
-		stCol := db.C("synced_tweets")
-
-		for _, tweet := range tweets {
-			translation, _, err := tl.DeepLTranslate(tweet.FullText, tl.LangEN)
-			if err != nil {
-				translation = fmt.Sprintf("[Translation error: %s]", err)
-			}
-
-			st := models.SyncedTweet{
-				Tweet:           tweet,
-				Translation:     translation,
-				CreatedAt:       time.Now(),
-				Translators:     []string{"DeepL"},
-				HumanTranslated: false,
-			}
-
-			if hasTOSMention(tweet) {
-				// Ignore this tweet and mark it as already translated so it doesn't interfere with the targeting of the command
-				st.HumanTranslated = true
-			} else {
-				embed := SyncedTweetToEmbed(st)
-				// msg, err := ds.ChannelMessageSendEmbed(ts.ChannelID, embed)
-				msg, err := ds.ChannelMessageSendEmbed(ts.ChannelID, embed)
-				if err != nil {
-					cl.Printf("Failed to send Tweet %s, %s", tweet.IDStr, err)
-					continue
-				}
-				st.ChannelID = msg.ChannelID
-				st.MessageID = msg.ID
-
-				if ts.ControlChannelID != "" {
-					cmsg, err := ds.ChannelMessageSendEmbed(ts.ControlChannelID, embed)
-					if err != nil {
-						cl.Printf("Failed to send control Tweet %s, %s", tweet.IDStr, err)
-						continue
-					}
-					st.ControlChannelID = cmsg.ChannelID
-					st.ControlMessageID = cmsg.ID
-				}
-			}
-
-			stCol.Insert(st)
-
-			sinceID = tweet.ID
-			err = config.SetTweetSyncSinceID(ts.Handle, ts.ChannelID, tweet.ID)
-			if err != nil {
-				cl.Printf("Failed to send Tweet %s, %s", tweet.IDStr, err)
-				continue
-			}
+		translation, _, err := tl.DeepLTranslate(text, tl.LangEN)
+		if err == nil {
+			quoteTranslation = translation
 		}
+	}
 
-		// fmt.Println("Finished echoing tweets")
-		session.Close()
+	replyText := ""
+	if tweet.InReplyToStatusIDStr != "" {
+		replied := models.SyncedTweet{}
+		err := db.C("synced_tweets").Find(bson.M{"tweet.idstr": tweet.InReplyToStatusIDStr}).One(&replied)
+		if err == nil {
+			replyText = replied.Text()
+		}
 	}
+
+	return quoteTranslation, replyText
 }
 
 func hasTOSMention(t twitter.Tweet) bool {
@@ -172,10 +119,6 @@ func hasTOSMention(t twitter.Tweet) bool {
 	return false
 }
 
-func SyncedTweetToEmbed(st models.SyncedTweet) *discordgo.MessageEmbed {
-	return TweetToEmbed(&st.Tweet, st.Translation, st.Translators)
-}
-
 func WrapTranslation(translation string) string {
 	text := ""
 	linebreak := ""
@@ -264,6 +207,42 @@ func TweetToEmbed(tweet *twitter.Tweet, translation string, translators []string
 	return embed
 }
 
+// PostToEmbed render a post from a non-Twitter feed in the same layout as a mirrored Tweet
+func PostToEmbed(post models.FeedPost, translation string, translators []string) *discordgo.MessageEmbed {
+	authorName := post.Author.Name
+	if post.Author.Handle != "" {
+		authorName = fmt.Sprintf("%s\n@%s", post.Author.Name, post.Author.Handle)
+	}
+
+	footer := post.Via
+	if footer == "" {
+		footer = post.Source
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Color: 3066993,
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: authorName,
+			URL:  post.Author.URL,
+		},
+		Description: fmt.Sprintf("[Post](%s)\n──────────────\n%s\n*TL: %s*\n──────────────\n%s\n\n*Original*", post.URL, WrapTranslation(translation), strings.Join(translators, ", "), post.Text),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: footer,
+		},
+	}
+
+	if post.Author.AvatarURL != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
+			URL: post.Author.AvatarURL,
+		}
+	}
+	if !post.CreatedAt.IsZero() {
+		embed.Timestamp = post.CreatedAt.Format(time.RFC3339)
+	}
+
+	return embed
+}
+
 func TweetToEmbedOld(tweet *twitter.Tweet, translation string, translators []string) *discordgo.MessageEmbed {
 	createdAt, _ := time.Parse(TwitterTimeFormat, tweet.CreatedAt)
 
@@ -0,0 +1,269 @@
+package tweetsync
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/dghubble/go-twitter/twitter"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/utils"
+)
+
+// How far back mirrored posts are checked for deletions and edits
+var verifyWindow = 72 * time.Hour
+
+// FeedVerifier A feed that can check whether posts it already returned are still up
+type FeedVerifier interface {
+	// Lookup returns the current version of each post that is still available, keyed by ID
+	Lookup(posts []models.FeedPost) (map[string]models.FeedPost, error)
+}
+
+// Lookup asks for up to 100 Tweets at a time, deleted or protected ones are left out of the response
+func (src *TwitterSource) Lookup(posts []models.FeedPost) (map[string]models.FeedPost, error) {
+	res := make(map[string]models.FeedPost)
+
+	ids := []int64{}
+	for _, post := range posts {
+		id, err := strconv.ParseInt(post.ID, 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		tweets, _, err := src.client.Statuses.Lookup(ids[start:end], &twitter.StatusLookupParams{
+			TweetMode: "extended",
+		})
+		if err != nil {
+			return res, err
+		}
+		for i := range tweets {
+			post := TweetToPost(&tweets[i])
+			res[post.ID] = post
+		}
+	}
+
+	return res, nil
+}
+
+// Lookup can only tell if a post's page is gone, not whether it was edited
+func (src *RSSSource) Lookup(posts []models.FeedPost) (map[string]models.FeedPost, error) {
+	return lookupByURL(posts, func(post models.FeedPost) string {
+		return post.URL
+	})
+}
+
+// Lookup checks the Nitter page of each post, which 404s once the Tweet is deleted
+func (src *NitterSource) Lookup(posts []models.FeedPost) (map[string]models.FeedPost, error) {
+	return lookupByURL(posts, func(post models.FeedPost) string {
+		return fmt.Sprintf("%s/%s/status/%s", src.instance, src.handle, post.ID)
+	})
+}
+
+func lookupByURL(posts []models.FeedPost, postURL func(models.FeedPost) string) (map[string]models.FeedPost, error) {
+	res := make(map[string]models.FeedPost)
+	for _, post := range posts {
+		url := postURL(post)
+		if url == "" {
+			res[post.ID] = post
+			continue
+		}
+
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return res, err
+		}
+		req.Header.Set("User-Agent", "DeluBot")
+
+		resp, err := feedHTTPClient.Do(req)
+		if err != nil {
+			return res, err
+		}
+		resp.Body.Close()
+
+		// Anything other than a clear "gone" is treated as still up, so flaky feeds don't mark posts deleted
+		if resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone {
+			res[post.ID] = post
+		}
+	}
+
+	return res, nil
+}
+
+// Verifier periodically check recently mirrored posts for deletions and edits
+func Verifier(ds *discordgo.Session) {
+	sleepDuration := 10 * time.Minute
+	for {
+		time.Sleep(sleepDuration)
+
+		session := mongo.MDB.Clone()
+		session.SetMode(mgo.Strong, false)
+		db := session.DB(mongo.DB_NAME)
+
+		for _, ts := range config.GetTweetSyncChannels() {
+			if ts.Paused {
+				continue
+			}
+
+			err := Verify(ds, db, &ts)
+			if err != nil {
+				log.Printf("Failed to verify posts of %s, %s", ts.Handle, err)
+			}
+		}
+
+		session.Close()
+	}
+}
+
+// Verify check the recent posts of one sync against its feed, and mark any that were deleted or edited
+func Verify(ds *discordgo.Session, db *mgo.Database, ts *config.TweetSyncConfig) error {
+	src, err := NewFeedSource(ts)
+	if err != nil {
+		return err
+	}
+	verifier, ok := src.(FeedVerifier)
+	if !ok {
+		return nil
+	}
+
+	// RSS handles are only labels, so those posts are matched by source instead
+	handle := bson.RegEx{Pattern: "^" + regexp.QuoteMeta(ts.Handle) + "$", Options: "i"}
+	ofSync := []bson.M{
+		{"post.author.handle": handle},
+		{"tweet.user.screenname": handle},
+	}
+	if ts.FeedSource() == models.FeedSourceRSS {
+		ofSync = []bson.M{{"post.source": models.FeedSourceRSS}}
+	}
+
+	sts := []models.SyncedTweet{}
+	err = db.C("synced_tweets").Find(bson.M{
+		"channel_id": ts.ChannelID,
+		"created_at": bson.M{"$gt": time.Now().Add(-verifyWindow)},
+		"message_id": bson.M{"$ne": ""},
+		"deleted":    bson.M{"$ne": true},
+		"$or":        ofSync,
+	}).All(&sts)
+	if err != nil {
+		return err
+	}
+	if len(sts) == 0 {
+		return nil
+	}
+
+	posts := []models.FeedPost{}
+	for i := range sts {
+		posts = append(posts, syncedPost(&sts[i]))
+	}
+
+	current, err := verifier.Lookup(posts)
+	if err != nil {
+		return err
+	}
+
+	for i := range sts {
+		st := &sts[i]
+		post, found := current[st.PostID()]
+		if !CheckUpstream(st, post, found, time.Now()) {
+			db.C("synced_tweets").UpdateId(st.OID, bson.M{"$set": bson.M{"verified_at": st.VerifiedAt}})
+			continue
+		}
+
+		// Only what was verified, a translation may have been changed during the lookup,
+		// and the messages are marked with the post as it is now
+		_, err = db.C("synced_tweets").FindId(st.OID).Apply(mgo.Change{
+			Update: bson.M{"$set": bson.M{
+				"verified_at": st.VerifiedAt,
+				"deleted":     st.Deleted,
+				"deleted_at":  st.DeletedAt,
+				"edited_text": st.EditedText,
+				"edited_at":   st.EditedAt,
+			}},
+			ReturnNew: true,
+		}, st)
+		if err != nil {
+			log.Printf("Failed to save verified post %s, %s", st.PostID(), err)
+			continue
+		}
+		notifyUpstreamChange(ds, ts, st)
+	}
+
+	return nil
+}
+
+// CheckUpstream compare a synced post with what the feed returns for it now, returning whether anything changed
+func CheckUpstream(st *models.SyncedTweet, post models.FeedPost, found bool, now time.Time) bool {
+	st.VerifiedAt = now
+
+	if !found {
+		st.Deleted = true
+		st.DeletedAt = now
+		return true
+	}
+
+	// Edits of RSS posts can't be seen, Lookup returns the stored post as-is
+	text := strings.TrimSpace(post.Text)
+	if text == "" || text == strings.TrimSpace(st.Text()) || text == strings.TrimSpace(st.EditedText) {
+		return false
+	}
+
+	st.EditedText = post.Text
+	st.EditedAt = now
+	return true
+}
+
+// syncedPost the feed post of a synced record, rebuilding it for records from before feeds were generic
+func syncedPost(st *models.SyncedTweet) models.FeedPost {
+	if st.Post.ID != "" {
+		return st.Post
+	}
+	return TweetToPost(&st.Tweet)
+}
+
+func notifyUpstreamChange(ds *discordgo.Session, ts *config.TweetSyncConfig, st *models.SyncedTweet) {
+	embeds := SyncedTweetToEmbeds(*st)
+	if st.MessageID != "" {
+		_, err := utils.ChannelMessageEditEmbeds(ds, st.ChannelID, st.MessageID, embeds)
+		if err != nil {
+			log.Printf("Failed to mark post %s, %s", st.PostID(), err)
+		}
+	}
+	if st.ControlMessageID != "" {
+		_, err := utils.ChannelMessageEditEmbeds(ds, st.ControlChannelID, st.ControlMessageID, embeds)
+		if err != nil {
+			log.Printf("Failed to mark control post %s, %s", st.PostID(), err)
+		}
+	}
+
+	if ts.ControlChannelID == "" {
+		return
+	}
+
+	change := "was edited"
+	if st.Deleted {
+		change = "was deleted"
+	}
+	link := fmt.Sprintf("https://discord.com/channels/@me/%s/%s", st.ChannelID, st.MessageID)
+	if ch, err := ds.State.Channel(st.ChannelID); err == nil {
+		link = fmt.Sprintf("https://discord.com/channels/%s/%s/%s", ch.GuildID, st.ChannelID, st.MessageID)
+	}
+	_, err := ds.ChannelMessageSend(ts.ControlChannelID, fmt.Sprintf("🔺A post by @%s %s upstream, the mirrored text is kept: %s", ts.Handle, change, link))
+	if err != nil {
+		log.Printf("Failed to notify about post %s, %s", st.PostID(), err)
+	}
+}
@@ -0,0 +1,51 @@
+package tweetsync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+)
+
+func Test_CheckUpstream(t *testing.T) {
+	config.Loc, _ = time.LoadLocation("Asia/Tokyo")
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	st := models.SyncedTweet{
+		Post: models.FeedPost{ID: "1", Text: "おはよう", URL: "https://example.com/1"},
+	}
+
+	if CheckUpstream(&st, st.Post, true, now) {
+		t.Errorf("unchanged post reported as changed")
+	}
+	if !st.VerifiedAt.Equal(now) {
+		t.Errorf("verification time not recorded")
+	}
+
+	if !CheckUpstream(&st, models.FeedPost{ID: "1", Text: "おはよう！"}, true, now) {
+		t.Fatalf("edit not detected")
+	}
+	if st.EditedText != "おはよう！" || st.Text() != "おはよう" {
+		t.Errorf("edit should keep the original text, got %q / %q", st.EditedText, st.Text())
+	}
+	if CheckUpstream(&st, models.FeedPost{ID: "1", Text: "おはよう！"}, true, now) {
+		t.Errorf("same edit reported twice")
+	}
+
+	if !CheckUpstream(&st, models.FeedPost{}, false, now) || !st.Deleted {
+		t.Fatalf("deletion not detected")
+	}
+
+	embeds := SyncedTweetToEmbeds(st)
+	if embeds[0].Color != deletedColor {
+		t.Errorf("deleted post not recolored")
+	}
+	if !strings.HasPrefix(embeds[0].Description, "🗑 ~~**Deleted upstream**~~ (noticed 2021/03/01 21:00 JST)") {
+		t.Errorf("missing deletion note: %s", embeds[0].Description)
+	}
+	if !strings.Contains(embeds[0].Description, "おはよう") {
+		t.Errorf("archived text was lost")
+	}
+}
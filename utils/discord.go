@@ -1,8 +1,15 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
+	"net/textproto"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -79,3 +86,131 @@ func BulkDeleteMessages(ds *discordgo.Session, channelID string, messageIDs []st
 	}
 	return nil
 }
+
+type messageEmbeds struct {
+	Embeds []*discordgo.MessageEmbed `json:"embeds"`
+}
+
+// ChannelMessageSendEmbeds send a message with several embeds, which discordgo doesn't support yet.
+// Embeds that share a URL are shown by Discord as a single embed with an image gallery.
+func ChannelMessageSendEmbeds(ds *discordgo.Session, channelID string, embeds []*discordgo.MessageEmbed) (*discordgo.Message, error) {
+	endpoint := discordgo.EndpointChannelMessages(channelID)
+	body, err := ds.RequestWithBucketID("POST", endpoint, messageEmbeds{Embeds: embeds}, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &discordgo.Message{}
+	err = json.Unmarshal(body, msg)
+	return msg, err
+}
+
+// ChannelMessageEditEmbeds replace all embeds of a message
+func ChannelMessageEditEmbeds(ds *discordgo.Session, channelID, messageID string, embeds []*discordgo.MessageEmbed) (*discordgo.Message, error) {
+	endpoint := discordgo.EndpointChannelMessage(channelID, messageID)
+	body, err := ds.RequestWithBucketID("PATCH", endpoint, messageEmbeds{Embeds: embeds}, discordgo.EndpointChannelMessage(channelID, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &discordgo.Message{}
+	err = json.Unmarshal(body, msg)
+	return msg, err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// WebhookExecuteFiles post a message through a webhook with files attached, which discordgo doesn't support yet
+func WebhookExecuteFiles(ds *discordgo.Session, webhookID, token string, params *discordgo.WebhookParams, files []*discordgo.File) (*discordgo.Message, error) {
+	endpoint := discordgo.EndpointWebhookToken(webhookID, token) + "?wait=true"
+	bucketID := discordgo.EndpointWebhookToken("", "")
+
+	var body []byte
+	if len(files) == 0 {
+		resp, err := ds.RequestWithBucketID("POST", endpoint, params, bucketID)
+		if err != nil {
+			return nil, err
+		}
+		body = resp
+	} else {
+		buf := &bytes.Buffer{}
+		bodywriter := multipart.NewWriter(buf)
+
+		payload, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="payload_json"`)
+		h.Set("Content-Type", "application/json")
+		p, err := bodywriter.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.Write(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, file := range files {
+			h := make(textproto.MIMEHeader)
+			h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file%d"; filename="%s"`, i, quoteEscaper.Replace(file.Name)))
+			contentType := file.ContentType
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			h.Set("Content-Type", contentType)
+			p, err := bodywriter.CreatePart(h)
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(p, file.Reader)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = bodywriter.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		body, err = ds.RequestWithLockedBucket("POST", endpoint, bodywriter.FormDataContentType(), buf.Bytes(), ds.Ratelimiter.LockBucket(bucketID), 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	msg := &discordgo.Message{}
+	err := json.Unmarshal(body, msg)
+	return msg, err
+}
+
+type webhookMessageEdit struct {
+	Content         string                            `json:"content"`
+	AllowedMentions *discordgo.MessageAllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+// WebhookMessageEdit edit the content of a message posted through a webhook
+func WebhookMessageEdit(ds *discordgo.Session, webhookID, token, messageID, content string, allowedMentions *discordgo.MessageAllowedMentions) error {
+	endpoint := discordgo.EndpointWebhookToken(webhookID, token) + "/messages/" + messageID
+	_, err := ds.RequestWithBucketID("PATCH", endpoint, webhookMessageEdit{Content: content, AllowedMentions: allowedMentions}, discordgo.EndpointWebhookToken("", "")+"/messages/")
+	return err
+}
+
+// WebhookMessageDelete delete a message posted through a webhook
+func WebhookMessageDelete(ds *discordgo.Session, webhookID, token, messageID string) error {
+	endpoint := discordgo.EndpointWebhookToken(webhookID, token) + "/messages/" + messageID
+	_, err := ds.RequestWithBucketID("DELETE", endpoint, nil, discordgo.EndpointWebhookToken("", "")+"/messages/")
+	return err
+}
+
+type memberTimeout struct {
+	CommunicationDisabledUntil *time.Time `json:"communication_disabled_until"`
+}
+
+// GuildMemberTimeout stop a member from talking until a time, or let them talk again with nil, which discordgo doesn't support yet
+func GuildMemberTimeout(ds *discordgo.Session, guildID, userID string, until *time.Time) error {
+	endpoint := discordgo.EndpointGuildMember(guildID, userID)
+	_, err := ds.RequestWithBucketID("PATCH", endpoint, memberTimeout{CommunicationDisabledUntil: until}, discordgo.EndpointGuildMember(guildID, ""))
+	return err
+}
@@ -0,0 +1,233 @@
+package mux
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/automod"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+)
+
+// How long automod mutes for when a rule doesn't say
+var automodMuteDuration = 10 * time.Minute
+
+var automodTracker = automod.NewTracker()
+
+// When each rule last warned someone, by guild:user and rule name, so a rule that keeps
+// catching someone doesn't warn them for every message
+var automodWarned = struct {
+	sync.Mutex
+	at map[string]time.Time
+}{at: make(map[string]time.Time)}
+
+// automodViolation A rule a message broke, and why
+type automodViolation struct {
+	Rule models.AutomodRule
+	Why  string
+}
+
+// automodMessage what automod looks at in a message
+func automodMessage(msg *discordgo.Message, now time.Time) automod.Message {
+	am := automod.Message{
+		Content:     msg.Content,
+		Mentions:    len(msg.Mentions) + len(msg.MentionRoles),
+		Attachments: len(msg.Attachments),
+		Time:        now,
+	}
+	if msg.MentionEveryone {
+		am.Mentions++
+	}
+	if created, err := discordgo.SnowflakeTimestamp(msg.Author.ID); err == nil {
+		am.AccountAge = now.Sub(created)
+	}
+	return am
+}
+
+// automodCheck the rules a message breaks
+func automodCheck(rules []models.AutomodRule, msg automod.Message, recent []automod.Message) []automodViolation {
+	violations := []automodViolation{}
+	for _, rule := range rules {
+		if why := automod.Check(rule, msg, recent); why != "" {
+			violations = append(violations, automodViolation{Rule: rule, Why: why})
+		}
+	}
+	return violations
+}
+
+// automodWarnDue whether any of the broken rules that warn hasn't warned the author within its window yet,
+// rules without a window warn at most once every automod.MaxWindow
+func automodWarnDue(key string, violations []automodViolation, now time.Time) bool {
+	automodWarned.Lock()
+	defer automodWarned.Unlock()
+
+	due := false
+	for _, v := range violations {
+		warns := false
+		for _, action := range v.Rule.Actions {
+			warns = warns || action == models.AutomodWarn
+		}
+		if !warns {
+			continue
+		}
+
+		window := v.Rule.Window
+		if window <= 0 {
+			window = automod.MaxWindow
+		}
+		k := key + ":" + v.Rule.Name
+		if last, ok := automodWarned.at[k]; ok && now.Sub(last) < window {
+			continue
+		}
+		automodWarned.at[k] = now
+		due = true
+	}
+
+	// Forget the old warnings now and then
+	if len(automodWarned.at) > 10000 {
+		for k, last := range automodWarned.at {
+			if now.Sub(last) >= automod.MaxWindow {
+				delete(automodWarned.at, k)
+			}
+		}
+	}
+	return due
+}
+
+// Automod check a message against the guild's automod rules and act on the ones it breaks, true if it was deleted
+func (m *Mux) Automod(db *mgo.Database, ds *discordgo.Session, msg *discordgo.Message) bool {
+	if msg.GuildID == "" || msg.Author == nil || msg.Author.Bot || msg.WebhookID != "" {
+		return false
+	}
+
+	rules, err := automod.Rules(db, msg.GuildID)
+	if err != nil {
+		log.Printf("Failed to get the automod rules of %s, %s", msg.GuildID, err)
+		return false
+	}
+	if len(rules) == 0 {
+		return false
+	}
+
+	now := time.Now()
+	key := msg.GuildID + ":" + msg.Author.ID
+	am := automodMessage(msg, now)
+	recent := automodTracker.Add(key, am)
+	violations := automodCheck(rules, am, recent)
+	if len(violations) == 0 {
+		return false
+	}
+	// Checked last, it costs a request
+	if IsGuildModerator(ds, msg.GuildID, msg.Author.ID) {
+		return false
+	}
+
+	actions := make(map[string]bool)
+	reasons := []string{}
+	muteDuration := time.Duration(0)
+	for _, v := range violations {
+		for _, action := range v.Rule.Actions {
+			actions[action] = true
+		}
+		if v.Rule.MuteDuration > muteDuration {
+			muteDuration = v.Rule.MuteDuration
+		}
+		reasons = append(reasons, fmt.Sprintf("%s (%s)", v.Rule.Name, v.Why))
+	}
+	if muteDuration == 0 {
+		muteDuration = automodMuteDuration
+	}
+	reason := "Automod: " + strings.Join(reasons, ", ")
+
+	deleted := false
+	if actions[models.AutomodDelete] {
+		err := ds.ChannelMessageDelete(msg.ChannelID, msg.ID)
+		if err != nil {
+			log.Printf("Automod failed to delete message %s, %s", msg.ID, err)
+		} else {
+			deleted = true
+		}
+	}
+
+	// A mute is a warning too, they don't both need a case
+	action := ""
+	if actions[models.AutomodMute] {
+		action = models.CaseMute
+	} else if actions[models.AutomodWarn] && automodWarnDue(key, violations, now) {
+		action = models.CaseWarn
+	}
+	// Someone spamming while muted already doesn't need a case for every message
+	if action == models.CaseMute && isMuted(db, msg.GuildID, msg.Author.ID) {
+		action = ""
+	}
+	if action != "" {
+		ma := ModAction{
+			GuildID:   msg.GuildID,
+			Action:    action,
+			Target:    msg.Author,
+			Moderator: ds.State.User,
+			Reason:    reason,
+		}
+		if action == models.CaseMute {
+			ma.Duration = muteDuration
+		}
+		_, err := m.Moderate(db, ds, ma)
+		if err != nil {
+			log.Printf("Automod failed to %s %s, %s", action, msg.Author.ID, err)
+		}
+	}
+
+	if actions[models.AutomodLog] {
+		automodLog(ds, msg, violations, deleted)
+	}
+	return deleted
+}
+
+// automodLog post a message that broke automod rules to the guild's log channel
+func automodLog(ds *discordgo.Session, msg *discordgo.Message, violations []automodViolation, deleted bool) {
+	logChannel, ok := config.LogChannels[msg.GuildID]
+	if !ok {
+		return
+	}
+
+	broken := []string{}
+	for _, v := range violations {
+		broken = append(broken, fmt.Sprintf("**%s**: %s", v.Rule.Name, v.Why))
+	}
+	state := "kept"
+	if deleted {
+		state = "deleted"
+	}
+
+	content := msg.Content
+	if content == "" {
+		content = "*no text*"
+	}
+	embed := &discordgo.MessageEmbed{
+		Color: modLogDeleteColor,
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    msg.Author.String(),
+			IconURL: msg.Author.AvatarURL("64"),
+		},
+		Description: truncateField(fmt.Sprintf("**Automod caught a message by <@%s> in <#%s>, %s**\n%s", msg.Author.ID, msg.ChannelID, state, content), 2048),
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "Rules",
+				Value: truncateField(strings.Join(broken, "\n"), 1024),
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("User ID: %s | Message ID: %s", msg.Author.ID, msg.ID),
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	_, err := ds.ChannelMessageSendEmbed(logChannel, embed)
+	if err != nil {
+		log.Printf("Failed to post automod catch %s to the log channel, %s", msg.ID, err)
+	}
+}
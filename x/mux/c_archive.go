@@ -0,0 +1,145 @@
+package mux
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/attachments"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+)
+
+var archiveUsage = "🔺Usage:\n" +
+	"`-db archive` show whether attachments posted in this server are archived\n" +
+	"`-db archive <on|off>`\n" +
+	"`-db archive <message ID or link>` get the archived attachments of a message, even if it was deleted"
+
+var messageLinkRE = regexp.MustCompile(`^https://(?:\w+\.)?discord(?:app)?\.com/channels/\d+/\d+/(\d+)$`)
+var snowflakeRE = regexp.MustCompile(`^\d+$`)
+
+// Archive manage archiving attachments, and get them back after the message is gone
+func (m *Mux) Archive(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) != 2 {
+		state := "not archived"
+		if config.ArchiveEnabled[dm.GuildID] {
+			state = "archived"
+		}
+		respond(fmt.Sprintf("🔺Attachments posted in this server are %s\n%s", state, archiveUsage))
+		return
+	}
+
+	arg := ctx.Fields[1]
+	if arg == "on" || arg == "off" {
+		err := config.SetArchiveEnabled(dm.GuildID, arg == "on")
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to turn archiving %s: %s", arg, err))
+			return
+		}
+		ds.MessageReactionAdd(dm.ChannelID, dm.ID, ThumbsUp)
+		return
+	}
+
+	messageID := arg
+	if match := messageLinkRE.FindStringSubmatch(arg); match != nil {
+		messageID = match[1]
+	}
+	if !snowflakeRE.MatchString(messageID) {
+		respond(archiveUsage)
+		return
+	}
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	aas, err := attachments.ForMessage(db, messageID)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to look up the attachments: %s", err))
+		return
+	}
+	// Only the server they were posted in gets to see them
+	found := []models.ArchivedAttachment{}
+	for _, aa := range aas {
+		if aa.GuildID == dm.GuildID {
+			found = append(found, aa)
+		}
+	}
+	if len(found) == 0 {
+		respond("🔺No attachments of that message were archived")
+		return
+	}
+
+	files, notes := archivedFiles(found)
+	lines := []string{fmt.Sprintf("🔺Posted by <@%s> (%s) in <#%s>, archived %s", found[0].AuthorID, found[0].AuthorName, found[0].ChannelID, found[0].ArchivedAt.In(config.Loc).Format("2006-01-02 15:04:05"))}
+	for _, aa := range found {
+		lines = append(lines, fmt.Sprintf("`%s` %s, sha256 `%s`", aa.Filename, notes[aa.AttachmentID], aa.Hash[:16]))
+	}
+
+	_, err = ds.ChannelMessageSendComplex(dm.ChannelID, &discordgo.MessageSend{
+		Content:         strings.Join(lines, "\n"),
+		Files:           files,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to send the attachments: %s", err))
+	}
+}
+
+func formatSize(size int) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// archivedFiles read archived attachments from the store to upload them again, as many as fit in one message.
+// Each attachment gets a note, by attachment ID, saying what it is and whether it was attached.
+func archivedFiles(aas []models.ArchivedAttachment) ([]*discordgo.File, map[string]string) {
+	files := []*discordgo.File{}
+	notes := make(map[string]string)
+	total := 0
+	for _, aa := range aas {
+		note := fmt.Sprintf("(%s, %s)", aa.MimeType, formatSize(aa.Size))
+		if len(files) >= 10 || total+aa.Size > mirrorMaxAttachment {
+			notes[aa.AttachmentID] = note + " too big to attach, kept in the archive"
+			continue
+		}
+
+		data, err := readArchived(aa)
+		if err != nil {
+			log.Printf("Failed to read archived attachment %s, %s", aa.Key, err)
+			notes[aa.AttachmentID] = note + " couldn't be read from the archive"
+			continue
+		}
+		files = append(files, &discordgo.File{
+			Name:        aa.Filename,
+			ContentType: aa.MimeType,
+			Reader:      bytes.NewReader(data),
+		})
+		total += aa.Size
+		notes[aa.AttachmentID] = note + " attached"
+	}
+	return files, notes
+}
+
+func readArchived(aa models.ArchivedAttachment) ([]byte, error) {
+	r, err := attachments.Open(aa)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
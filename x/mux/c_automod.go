@@ -0,0 +1,216 @@
+package mux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/automod"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+)
+
+var automodUsage = "🔺Usage:\n" +
+	"`-db automod` list the rules\n" +
+	"`-db automod add <name> <type> [value] [do:<actions>] [mute:<duration>] [within:<duration>]`\n" +
+	"  types: `words <word,word,...>`, `regex <pattern>`, `invites`, `mentions <count>`, `repeat <count>`, `attachments <count>`, `newaccount <days>`\n" +
+	"  actions: `delete`, `warn`, `mute`, `log`, separated by commas, `delete,log` if none are given\n" +
+	"  repeats and attachments are counted `within` a minute unless given, up to 10m\n" +
+	"`-db automod remove <name>`\n" +
+	"`-db automod test <text>` check which rules a message would break, without doing anything"
+
+// AutomodRules manage the rules messages in the server are checked against
+func (m *Mux) AutomodRules(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	if len(ctx.Fields) < 2 {
+		rules, err := automod.Rules(db, dm.GuildID)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to get the rules: %s", err))
+			return
+		}
+		if len(rules) == 0 {
+			respond("🔺This server has no automod rules\n" + automodUsage)
+			return
+		}
+		lines := []string{"🔺Automod rules:"}
+		for _, rule := range rules {
+			lines = append(lines, describeAutomodRule(rule))
+		}
+		respond(strings.Join(lines, "\n"))
+		return
+	}
+
+	switch ctx.Fields[1] {
+	case "add":
+		m.automodAdd(ds, dm, ctx, db)
+	case "remove":
+		if len(ctx.Fields) != 3 {
+			respond(automodUsage)
+			return
+		}
+		err := db.C("automod_rules").Remove(bson.M{"guild_id": dm.GuildID, "name": ctx.Fields[2]})
+		if err == mgo.ErrNotFound {
+			respond(fmt.Sprintf("🔺There's no rule `%s`", ctx.Fields[2]))
+			return
+		}
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to remove the rule: %s", err))
+			return
+		}
+		automod.Forget(dm.GuildID)
+		ds.MessageReactionAdd(dm.ChannelID, dm.ID, ThumbsUp)
+	case "test":
+		m.automodTest(ds, dm, ctx, db)
+	default:
+		respond(automodUsage)
+	}
+}
+
+func describeAutomodRule(rule models.AutomodRule) string {
+	value := ""
+	switch rule.Type {
+	case models.AutomodWords:
+		value = " " + strings.Join(rule.Words, ",")
+	case models.AutomodRegex:
+		value = " " + rule.Pattern
+	case models.AutomodNewAccount:
+		value = fmt.Sprintf(" under %d days", rule.Threshold)
+	case models.AutomodMentions:
+		value = fmt.Sprintf(" %d", rule.Threshold)
+	case models.AutomodRepeat, models.AutomodAttachments:
+		window := rule.Window
+		if window <= 0 {
+			window = time.Minute
+		}
+		value = fmt.Sprintf(" %d within %s", rule.Threshold, formatDuration(window))
+	}
+
+	actions := strings.Join(rule.Actions, ", ")
+	for _, action := range rule.Actions {
+		if action == models.AutomodMute && rule.MuteDuration > 0 {
+			actions += fmt.Sprintf(" (mute for %s)", formatDuration(rule.MuteDuration))
+		}
+	}
+	return fmt.Sprintf("`%s` %s`%s` → %s", rule.Name, rule.Type, value, actions)
+}
+
+// parseAutomodRule build a rule from the arguments of `automod add`, after the name
+func parseAutomodRule(args []string) (models.AutomodRule, error) {
+	rule := models.AutomodRule{
+		Type:    args[0],
+		Actions: []string{models.AutomodDelete, models.AutomodLog},
+	}
+
+	value := []string{}
+	for _, arg := range args[1:] {
+		key, opt := "", arg
+		if i := strings.Index(arg, ":"); i > 0 {
+			key, opt = strings.ToLower(arg[:i]), arg[i+1:]
+		}
+
+		switch key {
+		case "do":
+			rule.Actions = strings.Split(strings.ToLower(opt), ",")
+		case "mute", "within":
+			d, ok := parseDuration(opt)
+			if !ok {
+				return rule, fmt.Errorf("`%s` isn't a duration like `30m` or `12h`", opt)
+			}
+			if key == "mute" {
+				rule.MuteDuration = d
+			} else {
+				rule.Window = d
+			}
+		default:
+			value = append(value, arg)
+		}
+	}
+
+	switch rule.Type {
+	case models.AutomodWords:
+		for _, word := range strings.Split(strings.Join(value, " "), ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				rule.Words = append(rule.Words, word)
+			}
+		}
+	case models.AutomodRegex:
+		rule.Pattern = strings.Join(value, " ")
+	case models.AutomodMentions, models.AutomodRepeat, models.AutomodAttachments, models.AutomodNewAccount:
+		if len(value) == 1 {
+			rule.Threshold, _ = strconv.Atoi(value[0])
+		}
+	}
+	return rule, automod.Validate(rule)
+}
+
+func (m *Mux) automodAdd(ds *discordgo.Session, dm *discordgo.Message, ctx *Context, db *mgo.Database) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) < 4 {
+		respond(automodUsage)
+		return
+	}
+	rule, err := parseAutomodRule(ctx.Fields[3:])
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s\n%s", err, automodUsage))
+		return
+	}
+	rule.GuildID = dm.GuildID
+	rule.Name = ctx.Fields[2]
+	rule.CreatedBy = dm.Author.ID
+	rule.CreatedAt = time.Now()
+
+	_, err = db.C("automod_rules").Upsert(bson.M{"guild_id": rule.GuildID, "name": rule.Name}, rule)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to save the rule: %s", err))
+		return
+	}
+	automod.Forget(dm.GuildID)
+	respond("🔺Added " + describeAutomodRule(rule))
+}
+
+func (m *Mux) automodTest(ds *discordgo.Session, dm *discordgo.Message, ctx *Context, db *mgo.Database) {
+	respond := GetResponder(ds, dm)
+
+	text := strings.TrimSpace(strings.TrimPrefix(ctx.Content, "automod"))
+	text = strings.TrimSpace(strings.TrimPrefix(text, "test"))
+	if text == "" && len(dm.Attachments) == 0 {
+		respond(automodUsage)
+		return
+	}
+
+	rules, err := automod.Rules(db, dm.GuildID)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to get the rules: %s", err))
+		return
+	}
+
+	// Judged as a message of its own, what was said before doesn't count
+	msg := *dm
+	msg.Content = text
+	am := automodMessage(&msg, time.Now())
+	violations := automodCheck(rules, am, []automod.Message{am})
+	if len(violations) == 0 {
+		respond("🔺That breaks none of the rules")
+		return
+	}
+
+	lines := []string{"🔺That breaks:"}
+	for _, v := range violations {
+		lines = append(lines, fmt.Sprintf("`%s` %s → %s", v.Rule.Name, v.Why, strings.Join(v.Rule.Actions, ", ")))
+	}
+	ds.ChannelMessageSendComplex(dm.ChannelID, &discordgo.MessageSend{
+		Content:         strings.Join(lines, "\n"),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
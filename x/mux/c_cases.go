@@ -0,0 +1,119 @@
+package mux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+)
+
+// How many cases a history shows
+var historyLimit = 25
+
+// Case show a moderation case
+func (m *Mux) Case(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) != 2 {
+		respond("🔺Usage: `-db case <number>`")
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(ctx.Fields[1], "#"))
+	if err != nil {
+		respond("🔺Usage: `-db case <number>`")
+		return
+	}
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	mc := models.ModCase{}
+	err = db.C("cases").Find(bson.M{"guild_id": dm.GuildID, "case_number": n}).One(&mc)
+	if err == mgo.ErrNotFound {
+		respond(fmt.Sprintf("🔺There's no case #%d", n))
+		return
+	}
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to get case #%d: %s", n, err))
+		return
+	}
+
+	ds.ChannelMessageSendEmbed(dm.ChannelID, caseEmbed(mc))
+}
+
+// History list the moderation cases of someone
+func (m *Mux) History(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) != 2 {
+		respond("🔺Usage: `-db history <@user>`")
+		return
+	}
+	target, err := targetUser(ds, ctx.Fields[1])
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s\nUsage: `-db history <@user>`", err))
+		return
+	}
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	query := bson.M{"guild_id": dm.GuildID, "user_id": target.ID}
+	cases := []models.ModCase{}
+	err = db.C("cases").Find(query).Sort("-created_at").Limit(historyLimit).All(&cases)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to get the history: %s", err))
+		return
+	}
+	if len(cases) == 0 {
+		respond(fmt.Sprintf("🔺%s has a clean record", target.String()))
+		return
+	}
+
+	counts := []string{}
+	for _, action := range []string{models.CaseWarn, models.CaseMute, models.CaseTimeout, models.CaseKick, models.CaseBan} {
+		n, err := db.C("cases").Find(bson.M{"guild_id": dm.GuildID, "user_id": target.ID, "action": action}).Count()
+		if err == nil && n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, action))
+		}
+	}
+
+	lines := []string{}
+	for _, mc := range cases {
+		line := fmt.Sprintf("`#%d` %s **%s**", mc.CaseNumber, mc.CreatedAt.In(config.Loc).Format("2006-01-02"), mc.Action)
+		if mc.Duration > 0 {
+			line += " " + formatDuration(mc.Duration)
+		}
+		if mc.Active {
+			line += " (active)"
+		}
+		line += " by " + mc.ModeratorName
+		if mc.Reason != "" {
+			line += ": " + truncateField(mc.Reason, 100)
+		}
+		lines = append(lines, line)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Color:       3066993,
+		Title:       fmt.Sprintf("History of %s", target.String()),
+		Description: truncateField(strings.Join(lines, "\n"), 2048),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("User ID: %s | %s", target.ID, strings.Join(counts, ", ")),
+		},
+	}
+	if len(cases) == historyLimit {
+		embed.Footer.Text += fmt.Sprintf(" | latest %d cases", historyLimit)
+	}
+	ds.ChannelMessageSendEmbed(dm.ChannelID, embed)
+}
@@ -1,7 +1,10 @@
 package mux
 
 import (
+	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/w8kerr/delubot/config"
@@ -9,31 +12,70 @@ import (
 
 var ThumbsUp = "\U0001F44D"
 
+var PublicTLChannel = "796526853442895915"
+var MembersTLChannel = "776620304888889374"
+
+// DoubleTL shortcut for mirroring the public live TL channel into the members one
 func (m *Mux) DoubleTL(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
 	prerespond := GetResponder(ds, dm)
 
-	// emoji, err := ds.State.Emoji(dm.GuildID, "788243303816364062")
-	// if err != nil {
-	// 	prerespond(fmt.Sprintf("🔺No more eight ball I dropped it on the floor (" + err.Error() + ")"))
-	// 	return
-	// }
-
 	ctx.Content = strings.TrimPrefix(ctx.Content, "doubletl")
 	ctx.Content = strings.TrimSpace(ctx.Content)
 
 	if ctx.Content == "disable" {
+		err := config.RemoveMirror(PublicTLChannel, MembersTLChannel)
+		if err != nil {
+			prerespond("🔺" + err.Error())
+			return
+		}
 		ds.MessageReactionAdd(dm.ChannelID, dm.ID, ThumbsUp)
-		config.SetDoubleTLEnabled(false)
 		return
 	}
 	if ctx.Content == "enable" {
+		mc := doubleTLMirror(ds, dm.Author.ID)
+		err := config.SetMirror(mc)
+		if err != nil {
+			prerespond("🔺" + err.Error())
+			return
+		}
+		if !mc.Webhook {
+			prerespond(fmt.Sprintf("🔺Couldn't set up a webhook in <#%s>, do I have Manage Webhooks there? Messages will be copied by me instead", MembersTLChannel))
+		}
 		ds.MessageReactionAdd(dm.ChannelID, dm.ID, ThumbsUp)
-		config.SetDoubleTLEnabled(true)
 		return
 	}
 
-	if ctx.Content == "" {
-		prerespond("🔺Usage: -db doubletl <enable/disable>")
+	prerespond("🔺Usage: -db doubletl <enable/disable>, see `-db mirror` for other channels")
+}
+
+// doubleTLMirror the mirror of the public TL channel, copied by the bot if it can't use a webhook there
+func doubleTLMirror(ds *discordgo.Session, createdBy string) config.MirrorConfig {
+	mc := config.MirrorConfig{
+		SourceChannelID: PublicTLChannel,
+		DestChannelID:   MembersTLChannel,
+		Webhook:         true,
+		CreatedBy:       createdBy,
+		CreatedAt:       time.Now(),
+	}
+	_, err := ChannelWebhook(ds, MembersTLChannel)
+	if err != nil {
+		log.Printf("Couldn't set up a webhook for DoubleTL, mirroring without one, %s", err)
+		mc.Webhook = false
+	}
+	return mc
+}
+
+// MigrateDoubleTL turn the old DoubleTL switch into a mirror
+func (m *Mux) MigrateDoubleTL(ds *discordgo.Session) {
+	if !config.DoubleTL {
 		return
 	}
+
+	err := config.SetMirror(doubleTLMirror(ds, ""))
+	if err == nil {
+		err = config.SetDoubleTLEnabled(false)
+	}
+	if err != nil {
+		log.Printf("Failed to turn DoubleTL into a mirror, %s", err)
+	}
 }
@@ -0,0 +1,178 @@
+package mux
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/mongo"
+)
+
+// How long a stream is assumed to last when Youtube doesn't know yet
+var defaultStreamDuration = 2 * time.Hour
+
+const icsTimeFormat = "20060102T150405Z"
+
+// ScheduleICS render the schedule as an iCalendar feed.
+// Times are written in UTC so every calendar app shows them in its own time zone.
+func ScheduleICS(sched Schedule, now time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//DeluBot//Stream schedule//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Stream schedule",
+	}
+
+	event := func(uid string, start, end time.Time, summary, description, url string) {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+uid,
+			"DTSTAMP:"+now.UTC().Format(icsTimeFormat),
+			"DTSTART:"+start.UTC().Format(icsTimeFormat),
+			"DTEND:"+end.UTC().Format(icsTimeFormat),
+			"SUMMARY:"+icsEscape(summary),
+		)
+		if description != "" {
+			lines = append(lines, "DESCRIPTION:"+icsEscape(description))
+		}
+		if url != "" {
+			lines = append(lines, "URL:"+url)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+
+	for _, rec := range sched.Streams {
+		start := rec.ScheduledTime
+		if !rec.StartedAt.IsZero() {
+			start = rec.StartedAt
+		}
+		end := start.Add(defaultStreamDuration)
+		if !rec.EndedAt.IsZero() {
+			end = rec.EndedAt
+		} else if rec.IsLive() && now.After(end) {
+			end = now.Add(time.Hour)
+		}
+
+		description := sched.ChannelName(rec) + "\n" + rec.PostLink
+		if rec.PostPlan > 0 {
+			description = fmt.Sprintf("%s\nRestricted to ¥%d plan members, see Fanbox for the link\n%s", sched.ChannelName(rec), rec.PostPlan, rec.PostLink)
+		}
+		event(rec.YoutubeID+"@delubot", start, end, rec.StreamTitle, description, rec.PostLink)
+	}
+
+	for _, man := range sched.Manual {
+		uid := fmt.Sprintf("manual-%s@delubot", man.ID)
+		if man.GuerrillaTime == "" {
+			event(uid, man.Time, man.Time.Add(defaultStreamDuration), man.Title, "", "")
+			continue
+		}
+
+		start, end := GuerrillaWindow(man)
+		event(uid, start, end, "❓"+man.Title, fmt.Sprintf("Guerrilla stream, estimated %s", man.GuerrillaTime), "")
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	folded := []string{}
+	for _, line := range lines {
+		folded = append(folded, icsFold(line))
+	}
+	return strings.Join(folded, "\r\n") + "\r\n"
+}
+
+// GuerrillaWindow the time span a guerrilla stream could happen in.
+// An estimate like "2h" is used as the stream's length, otherwise it's the same eight hour range the schedule shows.
+func GuerrillaWindow(man ManualStream) (time.Time, time.Time) {
+	d, ok := parseDuration(man.GuerrillaTime)
+	if ok {
+		return man.Time, man.Time.Add(d)
+	}
+	return man.Time.Add(-4 * time.Hour), man.Time.Add(4 * time.Hour)
+}
+
+func icsEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// icsFold split a content line into lines of at most 75 octets, without splitting a character
+func icsFold(line string) string {
+	res := ""
+	size := 0
+	for _, r := range line {
+		l := len(string(r))
+		if size+l > 75 {
+			res += "\r\n "
+			size = 1
+		}
+		res += string(r)
+		size += l
+	}
+	return res
+}
+
+// StreamsICS attach the schedule as an .ics file
+func (m *Mux) StreamsICS(ds *discordgo.Session, dm *discordgo.Message) {
+	respond := GetResponder(ds, dm)
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	sched, err := CollectSchedule(db, dm.GuildID, false)
+	if err != nil {
+		respond("🔺" + err.Error())
+		return
+	}
+
+	_, err = ds.ChannelMessageSendComplex(dm.ChannelID, &discordgo.MessageSend{
+		Content: "🔺Stream schedule, open it with your calendar app",
+		Files: []*discordgo.File{
+			{
+				Name:        "streams.ics",
+				ContentType: "text/calendar",
+				Reader:      strings.NewReader(ScheduleICS(sched, time.Now())),
+			},
+		},
+	})
+	if err != nil {
+		respond("🔺Failed to send the calendar: " + err.Error())
+	}
+}
+
+// ServeScheduleICS serve each guild's schedule at /streams/<guild id>.ics, so calendar apps can subscribe to it
+func (m *Mux) ServeScheduleICS(addr string) {
+	http.HandleFunc("/streams/", func(w http.ResponseWriter, r *http.Request) {
+		guildID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/streams/"), ".ics")
+		if guildID == "" || strings.Contains(guildID, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		session := mongo.MDB.Clone()
+		defer session.Close()
+		session.SetMode(mgo.Strong, false)
+		db := session.DB(mongo.DB_NAME)
+
+		sched, err := CollectSchedule(db, guildID, false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Write([]byte(ScheduleICS(sched, time.Now())))
+	})
+
+	log.Printf("Serving stream calendars on %s", addr)
+	err := http.ListenAndServe(addr, nil)
+	if err != nil {
+		log.Printf("Stream calendar server stopped, %s", err)
+	}
+}
@@ -0,0 +1,255 @@
+package mux
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/timeparse"
+)
+
+var logsUsage = "🔺Usage:\n" +
+	"`-db logs [user:<@user>] [channel:<#channel>] [action:create|edit|delete] [after:<time>] [before:<time>] [text...]`\n" +
+	"  times are like `3d` or `12h` ago, or `2021/05/01`, the matching logs are attached as a file\n" +
+	"`-db logs retention` show how long logs are kept\n" +
+	"`-db logs retention <days|forever> [guild]` keep this channel's logs, or the whole server's, for that long\n" +
+	"`-db logs retention reset` use the server's setting for this channel"
+
+// How many logs an export can hold
+var logsExportLimit = 10000
+
+var userMentionRE = regexp.MustCompile(`^<@!?(\d+)>$`)
+var agoRE = regexp.MustCompile(`^(\d+)([mhdw])$`)
+
+// Logs search the message logs of the server
+func (m *Mux) Logs(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) > 1 && ctx.Fields[1] == "retention" {
+		m.logsRetention(ds, dm, ctx)
+		return
+	}
+	if len(ctx.Fields) < 2 {
+		respond(logsUsage)
+		return
+	}
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	loc := timeparse.UserZone(db, dm.Author.ID)
+	query, err := logsQuery(dm.GuildID, ctx.Fields[1:], time.Now(), loc)
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s\n%s", err, logsUsage))
+		return
+	}
+
+	logs := []MessageLog{}
+	err = db.C("message_logs").Find(query).Sort("-time").Limit(logsExportLimit).All(&logs)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to search the logs: %s", err))
+		return
+	}
+	if len(logs) == 0 {
+		respond("🔺No logs match")
+		return
+	}
+
+	summary := fmt.Sprintf("🔺%d logs match", len(logs))
+	if len(logs) == logsExportLimit {
+		summary = fmt.Sprintf("🔺More than %d logs match, only the latest %d are attached", logsExportLimit, logsExportLimit)
+	}
+	preview := ""
+	for i := 0; i < len(logs) && i < 10; i++ {
+		line := formatMessageLog(logs[i], loc)
+		if len([]rune(line)) > 150 {
+			line = string([]rune(line)[:150]) + "…"
+		}
+		preview += "\n" + line
+	}
+
+	// Oldest first in the file, it reads like the channel did
+	export := strings.Builder{}
+	for i := len(logs) - 1; i >= 0; i-- {
+		export.WriteString(formatMessageLog(logs[i], loc) + "\n")
+	}
+
+	_, err = ds.ChannelMessageSendComplex(dm.ChannelID, &discordgo.MessageSend{
+		Content: summary + "\n```" + strings.ReplaceAll(preview, "`", "'") + "\n```",
+		Files: []*discordgo.File{{
+			Name:        fmt.Sprintf("logs-%s.txt", time.Now().In(loc).Format("20060102-150405")),
+			ContentType: "text/plain",
+			Reader:      strings.NewReader(export.String()),
+		}},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to send the logs: %s", err))
+	}
+}
+
+// logsQuery build the search for a guild's message logs from the command's arguments
+func logsQuery(guildID string, args []string, now time.Time, loc *time.Location) (bson.M, error) {
+	query := bson.M{"guildid": guildID}
+	timeRange := bson.M{}
+	text := []string{}
+
+	for _, arg := range args {
+		key, value := "", arg
+		if i := strings.Index(arg, ":"); i > 0 {
+			key, value = strings.ToLower(arg[:i]), arg[i+1:]
+		}
+
+		switch key {
+		case "user":
+			if match := userMentionRE.FindStringSubmatch(value); match != nil {
+				value = match[1]
+			}
+			query["userid"] = value
+		case "channel":
+			if match := channelMentionRE.FindStringSubmatch(value); match != nil {
+				value = match[1]
+			}
+			query["$or"] = []bson.M{{"channelid": value}, {"threadid": value}}
+		case "action":
+			if value != "create" && value != "edit" && value != "delete" {
+				return nil, fmt.Errorf("There's no action `%s`", value)
+			}
+			query["action"] = value
+		case "after", "before":
+			t, err := logsTime(value, now, loc)
+			if err != nil {
+				return nil, err
+			}
+			if key == "after" {
+				timeRange["$gte"] = t
+			} else {
+				timeRange["$lt"] = t
+			}
+		default:
+			text = append(text, arg)
+		}
+	}
+
+	if len(timeRange) > 0 {
+		query["time"] = timeRange
+	}
+	if len(text) > 0 {
+		query["content"] = bson.RegEx{Pattern: regexp.QuoteMeta(strings.Join(text, " ")), Options: "i"}
+	}
+	if len(query) == 1 {
+		return nil, errors.New("Search for something")
+	}
+	return query, nil
+}
+
+// logsTime a time in a search, either how long ago or a date
+func logsTime(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	if ago, ok := parseDuration(value); ok {
+		return now.Add(-ago), nil
+	}
+
+	t, err := timeparse.Parse(strings.ReplaceAll(value, "_", " "), now, loc)
+	if err != nil {
+		return t, fmt.Errorf("I don't understand the time `%s`", value)
+	}
+	return t, nil
+}
+
+func formatMessageLog(ml MessageLog, loc *time.Location) string {
+	channel := ml.ChannelName
+	if channel == "" {
+		channel = ml.ChannelID
+	}
+	line := fmt.Sprintf("%s [%s] #%s %s (%s): %s", ml.Time.In(loc).Format("2006-01-02 15:04:05"), ml.Action, channel, ml.UserName, ml.UserID, ml.Content)
+	if ml.Action == "edit" && ml.OriginalContent != "" {
+		line += fmt.Sprintf(" (was: %s)", ml.OriginalContent)
+	}
+	for _, att := range ml.Attachments {
+		line += " " + att.URL
+	}
+	return strings.ReplaceAll(line, "\n", " ⏎ ")
+}
+
+func (m *Mux) logsRetention(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	describe := func(days int) string {
+		if days <= 0 {
+			return "forever"
+		}
+		return fmt.Sprintf("for %d days", days)
+	}
+
+	if len(ctx.Fields) == 2 {
+		guildDays, _ := config.GetLogRetention(dm.GuildID)
+		resp := fmt.Sprintf("🔺Logs in this server are kept %s", describe(guildDays))
+		if days, ok := config.GetLogRetention(dm.ChannelID); ok {
+			resp += fmt.Sprintf(", in this channel %s", describe(days))
+		}
+		respond(resp)
+		return
+	}
+
+	if ctx.Fields[2] == "reset" && len(ctx.Fields) == 3 {
+		err := config.RemoveLogRetention(dm.ChannelID)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to reset the retention: %s", err))
+			return
+		}
+		guildDays, _ := config.GetLogRetention(dm.GuildID)
+		respond(fmt.Sprintf("🔺Logs in this channel are kept like the rest of the server, %s", describe(guildDays)))
+		return
+	}
+
+	if len(ctx.Fields) > 4 || (len(ctx.Fields) == 4 && ctx.Fields[3] != "guild") {
+		respond(logsUsage)
+		return
+	}
+	days := 0
+	if ctx.Fields[2] != "forever" {
+		var err error
+		days, err = strconv.Atoi(ctx.Fields[2])
+		if err != nil || days <= 0 {
+			respond(logsUsage)
+			return
+		}
+	}
+
+	id, channelID, where := dm.ChannelID, dm.ChannelID, "this channel"
+	if len(ctx.Fields) == 4 {
+		id, channelID, where = dm.GuildID, "", "this server"
+	}
+	err := config.SetLogRetention(id, days)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to set the retention: %s", err))
+		return
+	}
+	if days <= 0 {
+		respond(fmt.Sprintf("🔺Messages in %s will be logged %s, starting with new messages", where, describe(days)))
+		return
+	}
+
+	// Logs from before the retention was set would otherwise be kept forever
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	count, err := backfillLogExpiry(ds, db, dm.GuildID, channelID, days)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Messages in %s will be logged %s, but I failed to update the older logs: %s", where, describe(days), err))
+		return
+	}
+	respond(fmt.Sprintf("🔺Messages in %s will be logged %s, %d older logs now expire %d days after they were sent", where, describe(days), count, days))
+}
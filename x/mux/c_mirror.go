@@ -0,0 +1,309 @@
+package mux
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+)
+
+var mirrorUsage = "🔺Usage:\n" +
+	"`-db mirror` list the mirrors from and into this channel\n" +
+	"`-db mirror <#channel> [plain]` copy this channel's messages into another channel, under the author's name and avatar, or posted by me with `plain`\n" +
+	"`-db mirror stop <#channel>`"
+
+// Attachments over Discord's upload limit are linked instead of copied
+var mirrorMaxAttachment = 8 * 1024 * 1024
+
+var mirrorHTTPClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// Mirror copy the channel's messages into another channel, following their edits and deletes
+func (m *Mux) Mirror(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) < 2 {
+		lines := []string{}
+		for _, mc := range config.Mirrors {
+			plain := ""
+			if !mc.Webhook {
+				plain = " (plain)"
+			}
+			if mc.SourceChannelID == dm.ChannelID || mc.DestChannelID == dm.ChannelID {
+				lines = append(lines, fmt.Sprintf("<#%s> → <#%s>%s", mc.SourceChannelID, mc.DestChannelID, plain))
+			}
+		}
+		if len(lines) == 0 {
+			respond("🔺This channel isn't mirrored\n" + mirrorUsage)
+			return
+		}
+		respond("🔺Mirrors:\n" + strings.Join(lines, "\n"))
+		return
+	}
+
+	if ctx.Fields[1] == "stop" {
+		if len(ctx.Fields) != 3 || !channelMentionRE.MatchString(ctx.Fields[2]) {
+			respond(mirrorUsage)
+			return
+		}
+		destID := channelMentionRE.FindStringSubmatch(ctx.Fields[2])[1]
+		err := config.RemoveMirror(dm.ChannelID, destID)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to stop mirroring: %s", err))
+			return
+		}
+		respond(fmt.Sprintf("🔺No longer mirroring this channel into <#%s>", destID))
+		return
+	}
+
+	if !channelMentionRE.MatchString(ctx.Fields[1]) || len(ctx.Fields) > 3 || (len(ctx.Fields) == 3 && ctx.Fields[2] != "plain") {
+		respond(mirrorUsage)
+		return
+	}
+	destID := channelMentionRE.FindStringSubmatch(ctx.Fields[1])[1]
+	if destID == dm.ChannelID {
+		respond("🔺A channel can't be mirrored into itself")
+		return
+	}
+	dest, err := ds.State.Channel(destID)
+	if err != nil {
+		dest, err = ds.Channel(destID)
+	}
+	if err != nil || dest.GuildID != dm.GuildID {
+		respond(fmt.Sprintf("🔺I couldn't find <#%s> in this server", destID))
+		return
+	}
+
+	mc := config.MirrorConfig{
+		SourceChannelID: dm.ChannelID,
+		DestChannelID:   destID,
+		Webhook:         len(ctx.Fields) == 2,
+		CreatedBy:       dm.Author.ID,
+		CreatedAt:       time.Now(),
+	}
+	if mc.Webhook {
+		_, err := ChannelWebhook(ds, destID)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Couldn't set up a webhook in <#%s>, do I have Manage Webhooks there? %s", destID, err))
+			return
+		}
+	}
+
+	err = config.SetMirror(mc)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to start mirroring: %s", err))
+		return
+	}
+	respond(fmt.Sprintf("🔺Mirroring this channel into <#%s>", destID))
+}
+
+// mirrorContent the text of a mirrored message, attachments too big to copy are linked
+func mirrorContent(mc config.MirrorConfig, msg *discordgo.Message) string {
+	content := msg.Content
+	for _, att := range msg.Attachments {
+		if att.Size > mirrorMaxAttachment {
+			content += "\n" + att.URL
+		}
+	}
+	if !mc.Webhook && msg.Author != nil {
+		content = fmt.Sprintf("**%s:** %s", msg.Author.Username, content)
+	}
+
+	runes := []rune(strings.TrimSpace(SanitizeMentions(content)))
+	if len(runes) > 2000 {
+		runes = runes[:2000]
+	}
+	return string(runes)
+}
+
+type mirrorFile struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+// downloadAttachments fetch the attachments that can be uploaded again
+func downloadAttachments(atts []*discordgo.MessageAttachment) []mirrorFile {
+	files := []mirrorFile{}
+	for _, att := range atts {
+		if att.Size > mirrorMaxAttachment {
+			continue
+		}
+		resp, err := mirrorHTTPClient.Get(att.URL)
+		if err != nil {
+			log.Printf("Failed to download attachment %s, %s", att.URL, err)
+			continue
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			log.Printf("Failed to download attachment %s, %s %v", att.URL, resp.Status, err)
+			continue
+		}
+		files = append(files, mirrorFile{
+			name:        att.Filename,
+			contentType: resp.Header.Get("Content-Type"),
+			data:        data,
+		})
+	}
+	return files
+}
+
+func discordFiles(files []mirrorFile) []*discordgo.File {
+	res := []*discordgo.File{}
+	for _, f := range files {
+		res = append(res, &discordgo.File{
+			Name:        f.name,
+			ContentType: f.contentType,
+			Reader:      bytes.NewReader(f.data),
+		})
+	}
+	return res
+}
+
+// MirrorMessage copy a new message into every channel its channel is mirrored to
+func (m *Mux) MirrorMessage(ds *discordgo.Session, db *mgo.Database, msg *discordgo.Message) {
+	mcs := config.GetMirrors(msg.ChannelID)
+	if len(mcs) == 0 {
+		return
+	}
+	// Don't copy copies, two channels can be mirrored into each other
+	if IsRelayWebhook(msg.WebhookID) {
+		return
+	}
+
+	files := downloadAttachments(msg.Attachments)
+	for _, mc := range mcs {
+		content := mirrorContent(mc, msg)
+		if content == "" && len(files) == 0 {
+			continue
+		}
+
+		var copied *discordgo.Message
+		webhookID := ""
+		var err error
+		if mc.Webhook {
+			var wh *discordgo.Webhook
+			copied, wh, err = RelayMessage(ds, mc.DestChannelID, msg.Author, msg.Member, Relay{
+				Content: content,
+				Files:   discordFiles(files),
+			})
+			if err == nil {
+				webhookID = wh.ID
+			} else {
+				// Still copied, by the bot itself
+				log.Printf("Failed to relay message %s into %s, copying it without a webhook, %s", msg.ID, mc.DestChannelID, err)
+				mc.Webhook = false
+				content = mirrorContent(mc, msg)
+			}
+		}
+		if !mc.Webhook {
+			copied, err = ds.ChannelMessageSendComplex(mc.DestChannelID, &discordgo.MessageSend{
+				Content: content,
+				Files:   discordFiles(files),
+				// The copy shouldn't ping anyone a second time
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			})
+		}
+		if err != nil {
+			log.Printf("Failed to mirror message %s into %s, %s", msg.ID, mc.DestChannelID, err)
+			continue
+		}
+
+		err = db.C("mirrored_messages").Insert(models.MirroredMessage{
+			SourceChannelID: msg.ChannelID,
+			SourceMessageID: msg.ID,
+			DestChannelID:   mc.DestChannelID,
+			DestMessageID:   copied.ID,
+			WebhookID:       webhookID,
+			CreatedAt:       time.Now(),
+		})
+		if err != nil {
+			log.Printf("Failed to record mirrored message %s, %s", msg.ID, err)
+		}
+	}
+}
+
+// MirrorMessageEdit apply an edit to the copies of a message
+func (m *Mux) MirrorMessageEdit(ds *discordgo.Session, db *mgo.Database, msg *discordgo.Message) {
+	// Updates without an author only add link previews
+	if msg.Author == nil {
+		return
+	}
+
+	copies := []models.MirroredMessage{}
+	err := db.C("mirrored_messages").Find(bson.M{"source_message_id": msg.ID}).All(&copies)
+	if err != nil {
+		log.Printf("Failed to get the copies of message %s, %s", msg.ID, err)
+		return
+	}
+
+	for _, mm := range copies {
+		mc := config.MirrorConfig{
+			SourceChannelID: mm.SourceChannelID,
+			DestChannelID:   mm.DestChannelID,
+			Webhook:         mm.WebhookID != "",
+		}
+		content := mirrorContent(mc, msg)
+
+		if mm.WebhookID != "" {
+			err := EditRelay(ds, mm.DestChannelID, mm.WebhookID, mm.DestMessageID, content)
+			if err != nil {
+				log.Printf("Failed to edit the copy of message %s, %s", msg.ID, err)
+			}
+			continue
+		}
+
+		_, err := ds.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:              mm.DestMessageID,
+			Channel:         mm.DestChannelID,
+			Content:         &content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			log.Printf("Failed to edit the copy of message %s, %s", msg.ID, err)
+		}
+	}
+}
+
+// MirrorMessageDelete delete the copies of a message
+func (m *Mux) MirrorMessageDelete(ds *discordgo.Session, db *mgo.Database, messageID string) {
+	col := db.C("mirrored_messages")
+
+	copies := []models.MirroredMessage{}
+	err := col.Find(bson.M{"source_message_id": messageID}).All(&copies)
+	if err != nil {
+		log.Printf("Failed to get the copies of message %s, %s", messageID, err)
+		return
+	}
+	if len(copies) == 0 {
+		return
+	}
+
+	for _, mm := range copies {
+		var err error
+		if mm.WebhookID != "" {
+			err = DeleteRelay(ds, mm.DestChannelID, mm.WebhookID, mm.DestMessageID)
+		} else {
+			err = ds.ChannelMessageDelete(mm.DestChannelID, mm.DestMessageID)
+		}
+		if err != nil {
+			log.Printf("Failed to delete the copy of message %s, %s", messageID, err)
+		}
+	}
+
+	_, err = col.RemoveAll(bson.M{"source_message_id": messageID})
+	if err != nil {
+		log.Printf("Failed to forget the copies of message %s, %s", messageID, err)
+	}
+}
@@ -0,0 +1,136 @@
+package mux
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+)
+
+var moderationUsage = map[string]string{
+	models.CaseWarn:    "`-db warn <@user> [reason]`",
+	models.CaseMute:    "`-db mute <@user> [duration] [reason]` mute with the mute role, until unmuted if no duration like `30m`, `12h`, `3d` or `1w` is given",
+	models.CaseUnmute:  "`-db unmute <@user> [reason]` lift a mute or timeout",
+	models.CaseTimeout: "`-db timeout <@user> <duration> [reason]` time out with Discord's timeout, for up to 28 days",
+	models.CaseKick:    "`-db kick <@user> [reason]`",
+	models.CaseBan:     "`-db ban <@user> [reason]`",
+}
+
+// Warn warn someone, recording it as a case
+func (m *Mux) Warn(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	m.moderate(ds, dm, ctx, models.CaseWarn)
+}
+
+// Mute mute someone with the mute role, for a while or until they're unmuted
+func (m *Mux) Mute(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	m.moderate(ds, dm, ctx, models.CaseMute)
+}
+
+// Unmute lift someone's mute or timeout
+func (m *Mux) Unmute(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	m.moderate(ds, dm, ctx, models.CaseUnmute)
+}
+
+// Timeout time someone out with Discord's timeout
+func (m *Mux) Timeout(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	m.moderate(ds, dm, ctx, models.CaseTimeout)
+}
+
+// Kick kick someone from the server
+func (m *Mux) Kick(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	m.moderate(ds, dm, ctx, models.CaseKick)
+}
+
+// Ban ban someone from the server
+func (m *Mux) Ban(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	m.moderate(ds, dm, ctx, models.CaseBan)
+}
+
+// targetUser the user a command is about, by mention or ID
+func targetUser(ds *discordgo.Session, arg string) (*discordgo.User, error) {
+	userID := arg
+	if match := userMentionRE.FindStringSubmatch(arg); match != nil {
+		userID = match[1]
+	}
+	if !snowflakeRE.MatchString(userID) {
+		return nil, fmt.Errorf("`%s` isn't a user", arg)
+	}
+	return ds.User(userID)
+}
+
+func (m *Mux) moderate(ds *discordgo.Session, dm *discordgo.Message, ctx *Context, action string) {
+	respond := GetResponder(ds, dm)
+	usage := "🔺Usage:\n" + moderationUsage[action]
+
+	if len(ctx.Fields) < 2 {
+		respond(usage)
+		return
+	}
+	target, err := targetUser(ds, ctx.Fields[1])
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s\n%s", err, usage))
+		return
+	}
+
+	args := ctx.Fields[2:]
+	ma := ModAction{
+		GuildID:   dm.GuildID,
+		Action:    action,
+		Target:    target,
+		Moderator: dm.Author,
+	}
+	if action == models.CaseMute || action == models.CaseTimeout {
+		// Something that looks like a duration but isn't valid mustn't end up as a mute without end
+		if len(args) > 0 && agoRE.MatchString(args[0]) {
+			d, ok := parseDuration(args[0])
+			if !ok {
+				respond(usage)
+				return
+			}
+			ma.Duration = d
+			args = args[1:]
+		}
+		if action == models.CaseTimeout && (ma.Duration <= 0 || ma.Duration > maxTimeout) {
+			respond(usage)
+			return
+		}
+	}
+	ma.Reason = strings.Join(args, " ")
+
+	if action != models.CaseUnmute {
+		if target.ID == dm.Author.ID || target.ID == ds.State.User.ID {
+			respond("🔺That's not going to happen")
+			return
+		}
+		if IsGuildModerator(ds, dm.GuildID, target.ID) {
+			respond("🔺Moderators can't be moderated with the bot")
+			return
+		}
+	}
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	mc, err := m.Moderate(db, ds, ma)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to %s %s: %s", action, target.String(), err))
+		return
+	}
+
+	resp := fmt.Sprintf("🔺Case #%d: %s <@%s>", mc.CaseNumber, casePastTense[action], target.ID)
+	if mc.Duration > 0 {
+		resp += " for " + formatDuration(mc.Duration)
+	}
+	if !mc.Notified {
+		resp += ", I couldn't DM them"
+	}
+	ds.ChannelMessageSendComplex(dm.ChannelID, &discordgo.MessageSend{
+		Content:         resp,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
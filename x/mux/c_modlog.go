@@ -0,0 +1,67 @@
+package mux
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/w8kerr/delubot/config"
+)
+
+var modLogUsage = "🔺Usage:\n" +
+	"`-db modlog` show whether deletes and edits are posted to the log channel\n" +
+	"`-db modlog <on|off>`\n" +
+	"`-db modlog ignore <#channel>` don't post deletes and edits in a channel\n" +
+	"`-db modlog unignore <#channel>`"
+
+// ModLog manage posting deleted and edited messages to the server's log channel
+func (m *Mux) ModLog(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	logChannel, ok := config.LogChannels[dm.GuildID]
+	if !ok {
+		respond("🔺This server doesn't have a log channel")
+		return
+	}
+
+	if len(ctx.Fields) < 2 {
+		state := "not posted"
+		if config.ModLogEnabled[dm.GuildID] {
+			state = "posted"
+		}
+		resp := fmt.Sprintf("🔺Deleted and edited messages are %s to <#%s>", state, logChannel)
+		ignored := []string{}
+		for _, channelID := range config.ModLogIgnored[dm.GuildID] {
+			ignored = append(ignored, fmt.Sprintf("<#%s>", channelID))
+		}
+		if len(ignored) > 0 {
+			resp += "\nIgnoring " + strings.Join(ignored, ", ")
+		}
+		respond(resp + "\n" + modLogUsage)
+		return
+	}
+
+	switch ctx.Fields[1] {
+	case "on", "off":
+		err := config.SetModLogEnabled(dm.GuildID, ctx.Fields[1] == "on")
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to turn the mod log %s: %s", ctx.Fields[1], err))
+			return
+		}
+		ds.MessageReactionAdd(dm.ChannelID, dm.ID, ThumbsUp)
+	case "ignore", "unignore":
+		if len(ctx.Fields) != 3 || !channelMentionRE.MatchString(ctx.Fields[2]) {
+			respond(modLogUsage)
+			return
+		}
+		channelID := channelMentionRE.FindStringSubmatch(ctx.Fields[2])[1]
+		err := config.SetModLogIgnored(dm.GuildID, channelID, ctx.Fields[1] == "ignore")
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to update the ignored channels: %s", err))
+			return
+		}
+		ds.MessageReactionAdd(dm.ChannelID, dm.ID, ThumbsUp)
+	default:
+		respond(modLogUsage)
+	}
+}
@@ -502,7 +502,7 @@ func (m *Mux) MuteRole(ds *discordgo.Session, dm *discordgo.Message, ctx *Contex
 	ctx.Content = strings.TrimPrefix(ctx.Content, "muterole")
 	ctx.Content = strings.TrimSpace(ctx.Content)
 	if ctx.Content == "clear" {
-		err = config.SetFormerRole(dm.GuildID, "")
+		err = config.SetMuteRole(dm.GuildID, "")
 		if err != nil {
 			respond(fmt.Sprintf("Failed to clear mute role, %s", err))
 			return
@@ -0,0 +1,427 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/timeparse"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+// How often the channel feeds are checked for new streams.
+// Known streams are refreshed every time, youtubesvc caches them for as long as they won't change.
+var scheduleDiscoverInterval = 5 * time.Minute
+
+// Schedule Everything that goes on a guild's schedule board
+type Schedule struct {
+	Streams  []models.YoutubeStreamRecord
+	Manual   []ManualStream
+	Channels []config.YoutubeChannelConfig
+}
+
+// ChannelName the label of the Youtube channel a stream is on, as configured for the guild
+func (sched *Schedule) ChannelName(rec models.YoutubeStreamRecord) string {
+	for _, yc := range sched.Channels {
+		if yc.ChannelID == rec.ChannelID && yc.Name != "" {
+			return yc.Name
+		}
+	}
+	return rec.ChannelTitle
+}
+
+var streamCache = struct {
+	sync.Mutex
+	recs         []models.YoutubeStreamRecord
+	discoveredAt time.Time
+}{}
+
+// CollectSchedule gather the streams of the guild's Youtube channels and the manually added ones
+func CollectSchedule(db *mgo.Database, guildID string, forceDiscover bool) (Schedule, error) {
+	sched := Schedule{
+		Channels: config.GuildYoutubeChannels(guildID),
+	}
+
+	ytCol := db.C("youtube_stream_records")
+	recs := []models.YoutubeStreamRecord{}
+	err := ytCol.Find(bson.M{"completed": false}).All(&recs)
+	if err != nil {
+		return sched, fmt.Errorf("could not get stream information, %s", err)
+	}
+
+	// Manual streams linked to their video are replaced by it, linked ones that are running late are still followed
+	manual := []ManualStream{}
+	schedCol := db.C("scheduled_streams")
+	err = schedCol.Find(bson.M{"time": bson.M{"$gt": time.Now().Add(-24 * time.Hour)}}).Sort("time").All(&manual)
+	if err != nil && err != mgo.ErrNotFound {
+		return sched, fmt.Errorf("failed to get manually scheduled streams, %s", err)
+	}
+	assignManualStreamIDs(db, manual)
+
+	linked := make(map[string]bool)
+	for _, man := range manual {
+		if man.Announced() {
+			linked[man.VideoID] = true
+			recs = append(recs, models.YoutubeStreamRecord{YoutubeID: man.VideoID})
+		} else if man.Time.After(time.Now()) {
+			sched.Manual = append(sched.Manual, man)
+		}
+	}
+
+	liveRecs, err := cachedStreams(db, recs, forceDiscover)
+	if err != nil {
+		return sched, err
+	}
+
+	tracked := make(map[string]bool)
+	for _, yc := range sched.Channels {
+		tracked[yc.ChannelID] = true
+	}
+	for _, rec := range liveRecs {
+		// Fanbox streams are always shown, they were posted for this server, and so are the ones staff linked
+		if tracked[rec.ChannelID] || rec.PostPlan > 0 || linked[rec.YoutubeID] {
+			sched.Streams = append(sched.Streams, rec)
+		}
+	}
+
+	// Streams that weren't linked are still left out when a Youtube stream is scheduled around the same time
+	unlinked := []ManualStream{}
+	for _, man := range sched.Manual {
+		if !man.ReplacedBy(sched.Streams) {
+			unlinked = append(unlinked, man)
+		}
+	}
+	sched.Manual = unlinked
+
+	return sched, nil
+}
+
+// cachedStreams the upcoming and live Youtube streams of all tracked channels
+func cachedStreams(db *mgo.Database, recs []models.YoutubeStreamRecord, forceDiscover bool) ([]models.YoutubeStreamRecord, error) {
+	streamCache.Lock()
+	defer streamCache.Unlock()
+
+	now := time.Now()
+
+	c := context.WithValue(context.Background(), "mgo", db.Session)
+	ytSvc, err := youtubesvc.NewYoutubeService(c)
+	if err != nil {
+		return streamCache.recs, fmt.Errorf("could not connect to Youtube, %s", err)
+	}
+
+	// Keep following known streams after they go live, until they end
+	known := make(map[string]models.YoutubeStreamRecord)
+	for _, rec := range streamCache.recs {
+		known[rec.YoutubeID] = rec
+	}
+	for _, rec := range recs {
+		known[rec.YoutubeID] = rec
+	}
+
+	if forceDiscover || now.Sub(streamCache.discoveredAt) > scheduleDiscoverInterval {
+		for _, yc := range config.AllYoutubeChannels() {
+			upcoming, err := ytSvc.ListChannelStreams(yc.ChannelID)
+			if err != nil {
+				return streamCache.recs, fmt.Errorf("could not check upcoming Youtube streams of %s, %s", yc.Name, err)
+			}
+			for _, rec := range upcoming {
+				known[rec.YoutubeID] = rec
+			}
+		}
+		streamCache.discoveredAt = now
+	}
+
+	ids := []string{}
+	for id := range known {
+		ids = append(ids, id)
+	}
+	fresh, err := ytSvc.GetStreams(ids)
+	if err != nil {
+		return streamCache.recs, fmt.Errorf("could not refresh stream information, %s", err)
+	}
+	youtubesvc.ArchiveStreams(db, fresh)
+
+	streamCache.recs = []models.YoutubeStreamRecord{}
+	for _, rec := range fresh {
+		if rec.Completed {
+			continue
+		}
+		// Fanbox records carry the plan and post link, which the video itself doesn't know
+		if old, ok := known[rec.YoutubeID]; ok && old.PostPlan > 0 {
+			rec.PostTitle = old.PostTitle
+			rec.PostLink = old.PostLink
+			rec.PostPlan = old.PostPlan
+		}
+		streamCache.recs = append(streamCache.recs, rec)
+	}
+
+	return append([]models.YoutubeStreamRecord{}, streamCache.recs...), nil
+}
+
+// ScheduleEmbed render the schedule split into live now, today, this week and later
+func ScheduleEmbed(sched Schedule, now time.Time) *discordgo.MessageEmbed {
+	now = now.In(config.Loc)
+	endOfToday := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, config.Loc)
+	endOfWeek := now.Add(7 * 24 * time.Hour)
+
+	recs := append([]models.YoutubeStreamRecord{}, sched.Streams...)
+	sort.Slice(recs, func(a int, b int) bool {
+		return recs[a].ScheduledTime.Before(recs[b].ScheduledTime)
+	})
+
+	type entry struct {
+		t    time.Time
+		line string
+	}
+	live := []string{}
+	today := []entry{}
+	week := []entry{}
+	later := []entry{}
+	place := func(t time.Time, line string) {
+		switch {
+		case t.Before(endOfToday):
+			today = append(today, entry{t, line})
+		case t.Before(endOfWeek):
+			week = append(week, entry{t, line})
+		default:
+			later = append(later, entry{t, line})
+		}
+	}
+
+	thumbnail := ""
+	for _, rec := range recs {
+		if rec.IsLive() {
+			live = append(live, fmt.Sprintf("🔴 **[%s](%s)**\n📺 %s, live for %s", rec.StreamTitle, rec.PostLink, sched.ChannelName(rec), now.Sub(rec.StartedAt).Round(time.Minute)))
+			if thumbnail == "" {
+				thumbnail = rec.StreamThumbnail
+			}
+		}
+	}
+	for _, rec := range recs {
+		if rec.IsLive() {
+			continue
+		}
+		if thumbnail == "" {
+			thumbnail = rec.StreamThumbnail
+		}
+
+		t := rec.ScheduledTime.In(config.Loc)
+		line := fmt.Sprintf("🔺**[%s](%s)**\n📺 %s", rec.StreamTitle, rec.PostLink, sched.ChannelName(rec))
+		if rec.PostPlan > 0 {
+			line = fmt.Sprintf("🔺**%s**\n📺 %s, [see Fanbox for link](%s), restricted to ¥%d plan members", rec.StreamTitle, sched.ChannelName(rec), rec.PostLink, rec.PostPlan)
+		}
+		place(t, fmt.Sprintf("%s\n%s\n%s", line, TimeBefore(t), timeparse.Discord(t, "F")))
+	}
+
+	for _, man := range sched.Manual {
+		t := man.Time.In(config.Loc)
+		if man.GuerrillaTime == "" {
+			place(t, fmt.Sprintf("🔺**%s**\n%s\n%s", man.Title, TimeBefore(t), timeparse.Discord(t, "F")))
+		} else {
+			place(t, fmt.Sprintf("🔺❓**%s**\n%s\n%s (%s)", man.Title, EightHourRange(t), timeparse.Discord(t, "D"), man.GuerrillaTime))
+		}
+	}
+
+	lines := func(entries []entry) []string {
+		sort.SliceStable(entries, func(a, b int) bool {
+			return entries[a].t.Before(entries[b].t)
+		})
+		res := []string{}
+		for _, e := range entries {
+			res = append(res, e.line)
+		}
+		return res
+	}
+
+	fields := []*discordgo.MessageEmbedField{}
+	if len(live) > 0 {
+		fields = append(fields, scheduleSection("🔴 Live now", live))
+	}
+	fields = append(fields, scheduleSection("📅 Today", lines(today)))
+	fields = append(fields, scheduleSection("🗓 This week", lines(week)))
+	if len(later) > 0 {
+		fields = append(fields, scheduleSection("🔭 Later", lines(later)))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Color:  3066993,
+		Title:  "Stream schedule",
+		Fields: fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Updated",
+		},
+		Timestamp: now.Format(time.RFC3339),
+	}
+	if thumbnail != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
+			URL: thumbnail,
+		}
+	}
+
+	return embed
+}
+
+// scheduleSection a field listing as many of the lines as fit in an embed field
+func scheduleSection(title string, lines []string) *discordgo.MessageEmbedField {
+	if len(lines) == 0 {
+		return &discordgo.MessageEmbedField{
+			Name:  title,
+			Value: "Nothing scheduled",
+		}
+	}
+
+	value := ""
+	for i, line := range lines {
+		more := fmt.Sprintf("\n…and %d more", len(lines)-i)
+		if len(value)+len(line)+len(more)+2 > 1024 {
+			value += more
+			break
+		}
+		if value != "" {
+			value += "\n\n"
+		}
+		value += line
+	}
+
+	return &discordgo.MessageEmbedField{
+		Name:  title,
+		Value: value,
+	}
+}
+
+func (m *Mux) ScheduleBoard(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+	bCol := db.C("schedule_boards")
+
+	if len(ctx.Fields) > 1 && ctx.Fields[1] == "remove" {
+		board := models.ScheduleBoard{}
+		err := bCol.Find(bson.M{"channel_id": dm.ChannelID}).One(&board)
+		if err != nil {
+			respond(fmt.Sprintf("🔺This channel has no schedule board: %s", err))
+			return
+		}
+		bCol.RemoveId(board.OID)
+		ds.ChannelMessageDelete(board.ChannelID, board.MessageID)
+		respond("🔺Schedule board removed")
+		return
+	}
+
+	board := models.ScheduleBoard{}
+	err := bCol.Find(bson.M{"channel_id": dm.ChannelID}).One(&board)
+	if err != nil && err != mgo.ErrNotFound {
+		respond(fmt.Sprintf("🔺Failed to check schedule boards: %s", err))
+		return
+	}
+	if err == mgo.ErrNotFound {
+		board = models.ScheduleBoard{
+			OID:       bson.NewObjectId(),
+			GuildID:   dm.GuildID,
+			ChannelID: dm.ChannelID,
+			CreatedAt: time.Now(),
+		}
+	} else {
+		// Move the board to the bottom of the channel
+		ds.ChannelMessageDelete(board.ChannelID, board.MessageID)
+	}
+
+	sched, err := CollectSchedule(db, board.GuildID, false)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to get the schedule: %s", err))
+		return
+	}
+
+	msg, err := ds.ChannelMessageSendEmbed(board.ChannelID, ScheduleEmbed(sched, time.Now()))
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to post the schedule board: %s", err))
+		return
+	}
+	board.MessageID = msg.ID
+	board.UpdatedAt = time.Now()
+
+	_, err = bCol.UpsertId(board.OID, board)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to save the schedule board: %s", err))
+		return
+	}
+
+	ds.ChannelMessageDelete(dm.ChannelID, dm.ID)
+}
+
+func (m *Mux) InitScheduleBoards(ds *discordgo.Session) {
+	sleepDuration := 60 * time.Second
+	for {
+		time.Sleep(sleepDuration)
+		m.UpdateScheduleBoards(ds)
+	}
+}
+
+// UpdateScheduleBoards edit every schedule board in place, reposting any that were deleted
+func (m *Mux) UpdateScheduleBoards(ds *discordgo.Session) {
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+	bCol := db.C("schedule_boards")
+
+	boards := []models.ScheduleBoard{}
+	err := bCol.Find(nil).All(&boards)
+	if err != nil {
+		log.Printf("Failed to get schedule boards: %s", err)
+		return
+	}
+	if len(boards) == 0 {
+		return
+	}
+
+	embeds := make(map[string]*discordgo.MessageEmbed)
+	for _, board := range boards {
+		embed, ok := embeds[board.GuildID]
+		if !ok {
+			sched, err := CollectSchedule(db, board.GuildID, false)
+			if err != nil {
+				log.Printf("Failed to get the schedule of %s: %s", board.GuildID, err)
+				continue
+			}
+			embed = ScheduleEmbed(sched, time.Now())
+			embeds[board.GuildID] = embed
+		}
+
+		_, err = ds.ChannelMessageEditEmbed(board.ChannelID, board.MessageID, embed)
+		if err == nil {
+			continue
+		}
+
+		restErr, ok := err.(*discordgo.RESTError)
+		if !ok || restErr.Response == nil || restErr.Response.StatusCode != http.StatusNotFound {
+			log.Printf("Failed to update schedule board in %s: %s", board.ChannelID, err)
+			continue
+		}
+
+		msg, err := ds.ChannelMessageSendEmbed(board.ChannelID, embed)
+		if err != nil {
+			log.Printf("Failed to repost schedule board in %s: %s", board.ChannelID, err)
+			continue
+		}
+		board.MessageID = msg.ID
+		board.UpdatedAt = time.Now()
+		err = bCol.UpdateId(board.OID, board)
+		if err != nil {
+			log.Printf("Failed to save schedule board in %s: %s", board.ChannelID, err)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/timeparse"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+var streamNotifyUsage = "🔺Usage:\n" +
+	"`-db streamnotify` show this channel's notification settings\n" +
+	"`-db streamnotify on [scheduled] [soon] [live] [ended]` (all events if none are given)\n" +
+	"`-db streamnotify off`\n" +
+	"`-db streamnotify role <@role|none>`\n" +
+	"`-db streamnotify before <minutes>`"
+
+var allStreamEvents = []string{models.StreamEventScheduled, models.StreamEventSoon, models.StreamEventLive, models.StreamEventEnded}
+
+var roleMentionRE = regexp.MustCompile(`^<@&(\d+)>$`)
+
+func (m *Mux) StreamNotify(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	snc := config.GetStreamNotifyConfig(dm.ChannelID)
+	if len(ctx.Fields) < 2 {
+		if snc == nil {
+			respond("🔺This channel doesn't get stream notifications\n" + streamNotifyUsage)
+			return
+		}
+
+		role := "nobody"
+		if snc.RoleID != "" {
+			role = fmt.Sprintf("<@&%s>", snc.RoleID)
+		}
+		respond(fmt.Sprintf("🔺This channel is notified of: %s\nPinging %s, %d minutes before streams start", strings.Join(snc.Events, ", "), role, snc.MinutesBefore))
+		return
+	}
+
+	args := ctx.Fields[2:]
+	switch ctx.Fields[1] {
+	case "on":
+		if snc == nil {
+			snc = &config.StreamNotifyConfig{
+				GuildID:       dm.GuildID,
+				ChannelID:     dm.ChannelID,
+				MinutesBefore: 15,
+			}
+		}
+		snc.Events = allStreamEvents
+		if len(args) > 0 {
+			snc.Events = []string{}
+			for _, arg := range args {
+				if !isStreamEvent(arg) {
+					respond(fmt.Sprintf("🔺`%s` isn't an event I know\n%s", arg, streamNotifyUsage))
+					return
+				}
+				snc.Events = append(snc.Events, arg)
+			}
+		}
+
+		err := config.SetStreamNotifyConfig(*snc)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to turn on notifications: %s", err))
+			return
+		}
+		respond(fmt.Sprintf("🔺This channel will be notified of: %s", strings.Join(snc.Events, ", ")))
+	case "off":
+		err := config.RemoveStreamNotifyConfig(dm.ChannelID)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to turn off notifications: %s", err))
+			return
+		}
+		respond("🔺Stream notifications turned off")
+	case "role":
+		if snc == nil || len(args) != 1 {
+			respond(streamNotifyUsage)
+			return
+		}
+		snc.RoleID = ""
+		if match := roleMentionRE.FindStringSubmatch(args[0]); match != nil {
+			snc.RoleID = match[1]
+		} else if args[0] != "none" {
+			respond(fmt.Sprintf("🔺`%s` isn't a role\n%s", args[0], streamNotifyUsage))
+			return
+		}
+
+		err := config.SetStreamNotifyConfig(*snc)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to set the role: %s", err))
+			return
+		}
+		respond("🔺Notification role updated")
+	case "before":
+		if snc == nil || len(args) != 1 {
+			respond(streamNotifyUsage)
+			return
+		}
+		minutes, err := strconv.Atoi(args[0])
+		if err != nil || minutes <= 0 {
+			respond(fmt.Sprintf("🔺`%s` isn't a number of minutes", args[0]))
+			return
+		}
+		snc.MinutesBefore = minutes
+
+		err = config.SetStreamNotifyConfig(*snc)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to set the reminder time: %s", err))
+			return
+		}
+		respond(fmt.Sprintf("🔺Reminders will be sent %d minutes before streams start", minutes))
+	default:
+		respond(streamNotifyUsage)
+	}
+}
+
+func isStreamEvent(event string) bool {
+	for _, e := range allStreamEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Mux) InitStreamNotifier(ds *discordgo.Session) {
+	sleepDuration := 60 * time.Second
+	for {
+		time.Sleep(sleepDuration)
+		m.NotifyStreams(ds)
+	}
+}
+
+// NotifyStreams send any stream notifications that are due and weren't sent yet
+func (m *Mux) NotifyStreams(ds *discordgo.Session) {
+	if len(config.StreamNotifications) == 0 {
+		return
+	}
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	scheds := make(map[string]Schedule)
+	now := time.Now()
+	for _, snc := range config.StreamNotifications {
+		sched, ok := scheds[snc.GuildID]
+		if !ok {
+			var err error
+			sched, err = CollectSchedule(db, snc.GuildID, false)
+			if err != nil {
+				log.Printf("Failed to get the schedule of %s: %s", snc.GuildID, err)
+				continue
+			}
+			scheds[snc.GuildID] = sched
+		}
+
+		for _, rec := range sched.Streams {
+			// Only the latest event is sent, a stream first seen starting soon isn't also announced as scheduled
+			latest := ""
+			for _, event := range DueStreamEvents(rec, snc.MinutesBefore, now) {
+				if snc.Wants(event) {
+					latest = event
+				}
+			}
+			if latest != "" {
+				sendStreamNotification(ds, db, snc, sched, rec, latest)
+			}
+		}
+	}
+
+	trackLiveStreams(db, scheds, now)
+	notifyEndedStreams(ds, db, scheds)
+}
+
+// DueStreamEvents the events that have happened to a stream as of now, from the earliest to the latest
+func DueStreamEvents(rec models.YoutubeStreamRecord, minutesBefore int, now time.Time) []string {
+	if rec.Completed {
+		return []string{}
+	}
+	if rec.IsLive() {
+		return []string{models.StreamEventLive}
+	}
+
+	events := []string{}
+	untilStart := rec.ScheduledTime.Sub(now)
+	if untilStart > 0 {
+		events = append(events, models.StreamEventScheduled)
+	}
+	if untilStart <= time.Duration(minutesBefore)*time.Minute && untilStart > 0 {
+		events = append(events, models.StreamEventSoon)
+	}
+	return events
+}
+
+// trackLiveStreams remember the streams that are live, so their end can be announced even where going live wasn't
+func trackLiveStreams(db *mgo.Database, scheds map[string]Schedule, now time.Time) {
+	lsCol := db.C("live_streams")
+	for guildID, sched := range scheds {
+		for _, rec := range sched.Streams {
+			if !rec.IsLive() {
+				continue
+			}
+			_, err := lsCol.Upsert(bson.M{"guild_id": guildID, "video_id": rec.YoutubeID}, bson.M{
+				"$setOnInsert": bson.M{"seen_at": now},
+				"$set":         bson.M{"expires_at": now.Add(24 * time.Hour)},
+			})
+			if err != nil {
+				log.Printf("Failed to track live stream %s: %s", rec.YoutubeID, err)
+			}
+		}
+	}
+}
+
+// notifyEndedStreams check the streams that were seen live, and announce the ones that ended
+func notifyEndedStreams(ds *discordgo.Session, db *mgo.Database, scheds map[string]Schedule) {
+	lsCol := db.C("live_streams")
+
+	lives := []models.LiveStream{}
+	err := lsCol.Find(nil).All(&lives)
+	if err != nil || len(lives) == 0 {
+		return
+	}
+
+	ids := []string{}
+	for _, live := range lives {
+		ids = append(ids, live.VideoID)
+	}
+
+	c := context.WithValue(context.Background(), "mgo", db.Session)
+	ytSvc, err := youtubesvc.NewYoutubeService(c)
+	if err != nil {
+		log.Printf("Could not connect to Youtube, %s", err)
+		return
+	}
+	recs, err := ytSvc.GetStreams(ids)
+	if err != nil {
+		log.Printf("Could not check live streams, %s", err)
+		return
+	}
+
+	for _, rec := range recs {
+		if !rec.Completed {
+			continue
+		}
+		for _, live := range lives {
+			if live.VideoID != rec.YoutubeID {
+				continue
+			}
+			sent := true
+			for _, snc := range config.StreamNotifications {
+				if snc.GuildID != live.GuildID || !snc.Wants(models.StreamEventEnded) {
+					continue
+				}
+				if !sendStreamNotification(ds, db, snc, scheds[snc.GuildID], rec, models.StreamEventEnded) {
+					sent = false
+				}
+			}
+			// Kept until every channel got it
+			if sent {
+				lsCol.RemoveId(live.OID)
+			}
+		}
+	}
+}
+
+// sendStreamNotification post a notification, unless the log shows it was already sent.
+// Returns whether it has been sent, now or before.
+func sendStreamNotification(ds *discordgo.Session, db *mgo.Database, snc config.StreamNotifyConfig, sched Schedule, rec models.YoutubeStreamRecord, event string) bool {
+	snCol := db.C("stream_notifications")
+
+	// Claim the notification first, so a crash after sending can't cause a second ping
+	sn := models.StreamNotification{
+		OID:       bson.NewObjectId(),
+		ChannelID: snc.ChannelID,
+		VideoID:   rec.YoutubeID,
+		Event:     event,
+		SentAt:    time.Now(),
+	}
+	err := snCol.Insert(sn)
+	if mgo.IsDup(err) {
+		return true
+	}
+	if err != nil {
+		log.Printf("Failed to log stream notification: %s", err)
+		return false
+	}
+
+	send := &discordgo.MessageSend{
+		Embed: StreamNotificationEmbed(sched, rec, event, snc.MinutesBefore),
+	}
+	if snc.RoleID != "" && event != models.StreamEventEnded {
+		send.Content = fmt.Sprintf("<@&%s>", snc.RoleID)
+		send.AllowedMentions = &discordgo.MessageAllowedMentions{
+			Roles: []string{snc.RoleID},
+		}
+	}
+
+	msg, err := ds.ChannelMessageSendComplex(snc.ChannelID, send)
+	if err != nil {
+		log.Printf("Failed to send stream notification to %s: %s", snc.ChannelID, err)
+		// Give up the claim, so it's tried again next time
+		snCol.RemoveId(sn.OID)
+		return false
+	}
+	snCol.UpdateId(sn.OID, bson.M{"$set": bson.M{"message_id": msg.ID}})
+	return true
+}
+
+func StreamNotificationEmbed(sched Schedule, rec models.YoutubeStreamRecord, event string, minutesBefore int) *discordgo.MessageEmbed {
+	t := rec.ScheduledTime.In(config.Loc)
+
+	embed := &discordgo.MessageEmbed{
+		Color:       3066993,
+		URL:         rec.PostLink,
+		Description: fmt.Sprintf("**[%s](%s)**\n📺 %s", rec.StreamTitle, rec.PostLink, sched.ChannelName(rec)),
+		Thumbnail: &discordgo.MessageEmbedThumbnail{
+			URL: rec.StreamThumbnail,
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	switch event {
+	case models.StreamEventScheduled:
+		embed.Title = "📅 Stream scheduled"
+		embed.Description += fmt.Sprintf("\n%s\n%s", timeparse.Discord(t, "F"), TimeBefore(t))
+	case models.StreamEventSoon:
+		embed.Title = fmt.Sprintf("⏰ Starting in %d minutes", minutesBefore)
+		embed.Description += "\n" + timeparse.Discord(t, "F")
+	case models.StreamEventLive:
+		embed.Title = "🔴 Live now!"
+		embed.Color = 15158332
+	case models.StreamEventEnded:
+		embed.Title = "Stream ended"
+		if !rec.StartedAt.IsZero() && !rec.EndedAt.IsZero() {
+			embed.Description += fmt.Sprintf("\nStreamed for %s", rec.EndedAt.Sub(rec.StartedAt).Round(time.Minute))
+		}
+	}
+
+	return embed
+}
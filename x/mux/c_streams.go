@@ -2,9 +2,10 @@ package mux
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
-	"sort"
+	"log"
+	"math/rand"
 	"strings"
 	"time"
 
@@ -14,23 +15,26 @@ import (
 	"github.com/w8kerr/delubot/config"
 	"github.com/w8kerr/delubot/models"
 	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/timeparse"
 	"github.com/w8kerr/delubot/youtubesvc"
 )
 
-type EmbedToUpdate struct {
-	ChannelID string
-	MessageID string
-	Time      time.Time
+// Seeded once, seeding for every stream ID would repeat IDs made in the same instant
+func init() {
+	rand.Seed(time.Now().UnixNano())
 }
 
-var EmbedsToUpdate = []EmbedToUpdate{}
-
 func (m *Mux) Stream(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
 	respond := GetResponder(ds, dm)
 	respond("🔺No fuck you it's supposed to be 'streams' >:l")
 }
 
 func (m *Mux) Streams(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	if len(ctx.Fields) > 1 && ctx.Fields[1] == "ics" {
+		m.StreamsICS(ds, dm)
+		return
+	}
+
 	prerespond := GetResponder(ds, dm)
 	msg := prerespond("🔺Looking up stream information...")
 	respond := GetEditor(ds, msg)
@@ -38,156 +42,27 @@ func (m *Mux) Streams(ds *discordgo.Session, dm *discordgo.Message, ctx *Context
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	session.SetMode(mgo.Strong, false)
-	c := context.Background()
-	c = context.WithValue(c, "mgo", session)
 	db := session.DB(mongo.DB_NAME)
 
-	ytCol := db.C("youtube_stream_records")
-	recs := []models.YoutubeStreamRecord{}
-	err := ytCol.Find(bson.M{"completed": false}).All(&recs)
+	sched, err := CollectSchedule(db, dm.GuildID, true)
 	if err != nil {
-		respond("🔺Could not get stream information, " + err.Error())
+		respond("🔺" + err.Error())
 		return
 	}
 
-	ytSvc, err := youtubesvc.NewYoutubeService(c)
-	if err != nil {
-		respond("🔺Could not connect to Youtube, " + err.Error())
-		return
-	}
-
-	if len(recs) > 0 {
-		for i, rec := range recs {
-			scheduledTime, _, snippet, err := ytSvc.GetStreamInfo(rec.YoutubeID)
-			if err != nil {
-				respond("🔺Error getting video info: " + err.Error())
-			}
-			recs[i].ScheduledTime = scheduledTime
-			recs[i].StreamTitle = snippet.Title
-			recs[i].StreamThumbnail = snippet.Thumbnails.High.Url
-		}
-	}
-
-	liveRecs, err := ytSvc.ListUpcomingStreams("UC7YXqPO3eUnxbJ6rN0z2z1Q")
-	if err != nil {
-		respond("🔺Could not check upcoming Youtube streams, " + err.Error())
-		return
-	}
-
-	recs = append(liveRecs, recs...)
-
-	schedCol := db.C("scheduled_streams")
-	schedStreams := []ManualStream{}
-	err = schedCol.Find(bson.M{"time": bson.M{"$gt": time.Now()}}).Sort("time").All(&schedStreams)
-	if err != nil && err != mgo.ErrNotFound {
-		respond("🔺Failed to get manually scheduled streams: " + err.Error())
-	}
-
-	if len(schedStreams) > 0 {
-		Loc, _ := time.LoadLocation("Asia/Tokyo")
-		for _, schedStream := range schedStreams {
-			schedStream.Time = schedStream.Time.In(Loc)
-		}
-	}
-
-	if len(recs) == 0 && len(schedStreams) == 0 {
+	if len(sched.Streams) == 0 && len(sched.Manual) == 0 {
 		respond("🔺No upcoming streams found :(")
 		return
 	}
 
-	embed := StreamsEmbed(schedStreams, recs)
-
 	ds.ChannelMessageDelete(dm.ChannelID, msg.ID)
-	msg, err = ds.ChannelMessageSendEmbed(dm.ChannelID, embed)
-	if err == nil {
-		EmbedsToUpdate = append(EmbedsToUpdate, EmbedToUpdate{
-			ChannelID: dm.ChannelID,
-			MessageID: msg.ID,
-			Time:      time.Now(),
-		})
-	}
-}
-
-func StreamsEmbed(mans []ManualStream, recs []models.YoutubeStreamRecord) *discordgo.MessageEmbed {
-	embed := &discordgo.MessageEmbed{
-		Color: 3066993,
-		Footer: &discordgo.MessageEmbedFooter{
-			Text: "Updated",
-		},
-		Timestamp: time.Now().Format(time.RFC3339),
-	}
-
-	sort.Slice(recs, func(a int, b int) bool {
-		return recs[a].ScheduledTime.Before(recs[b].ScheduledTime)
-	})
-
-	Loc, _ := time.LoadLocation("Asia/Tokyo")
-
-	fields := []*discordgo.MessageEmbedField{}
-
-	for _, rec := range recs {
-		rec.ScheduledTime = rec.ScheduledTime.In(Loc)
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:  "🔺" + rec.StreamTitle,
-			Value: fmt.Sprintf("[See Fanbox for link](%s)\nRestricted to ¥%d plan members\n%s\n%s", rec.PostLink, rec.PostPlan, TimeBefore(rec.ScheduledTime), config.PrintTime(rec.ScheduledTime)),
-		})
-	}
-
-	for _, man := range mans {
-		if man.ReplacedBy(recs) {
-			continue
-		}
-
-		if man.GuerrillaTime == "" {
-			man.Time = man.Time.In(Loc)
-			fields = append(fields, &discordgo.MessageEmbedField{
-				Name:  "🔺" + man.Title,
-				Value: fmt.Sprintf("%s\n%s", TimeBefore(man.Time), config.PrintTime(man.Time)),
-			})
-		} else {
-			man.Time = man.Time.In(Loc)
-			fields = append(fields, &discordgo.MessageEmbedField{
-				Name:  "🔺❓" + man.Title,
-				Value: fmt.Sprintf("%s\n%s (%s)", EightHourRange(man.Time), config.PrintDate(man.Time), man.GuerrillaTime),
-			})
-		}
-
-	}
-
-	if len(recs) > 0 {
-		embed.Title = "Upcoming streams:"
-		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
-			URL: recs[0].StreamThumbnail,
-		}
-	} else {
-		embed.Title = "Upcoming scheduled streams:"
-	}
-
-	embed.Fields = fields
-
-	return embed
+	ds.ChannelMessageSendEmbed(dm.ChannelID, ScheduleEmbed(sched, time.Now()))
 }
 
+// TimeBefore how long until a time, as a Discord timestamp that counts down by itself
 func TimeBefore(t time.Time) string {
-	currentTime := time.Now()
-	difference := t.Sub(currentTime)
-
-	total := int(difference.Seconds())
-	days := int(total / (60 * 60 * 24))
-	hours := int(total / (60 * 60) % 24)
-	minutes := int(total/60) % 60
-	deltas := float32(total) / (60 * 60 * 4)
-
-	parts := []string{}
-	if days > 0 {
-		parts = append(parts, fmt.Sprintf("%d days", days))
-	}
-	if hours > 0 {
-		parts = append(parts, fmt.Sprintf("%d hours", hours))
-	}
-	parts = append(parts, fmt.Sprintf("%d minutes", minutes))
-
-	return fmt.Sprintf("%s (%.1f Δs) from now", strings.Join(parts, ", "), deltas)
+	deltas := t.Sub(time.Now()).Hours() / 4
+	return fmt.Sprintf("%s (%.1f Δs)", timeparse.Discord(t, "R"), deltas)
 }
 
 func EightHourRange(t time.Time) string {
@@ -235,11 +110,22 @@ func EightHourRange(t time.Time) string {
 }
 
 type ManualStream struct {
+	ID            string    `json:"id" bson:"id"`
 	Time          time.Time `json:"time" bson:"time"`
 	Title         string    `json:"title" bson:"title"`
 	GuerrillaTime string    `json:"guerrilla_time" bson:"guerrilla_time"`
+	VideoID       string    `json:"video_id" bson:"video_id"`
+	CreatedBy     string    `json:"created_by" bson:"created_by"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Announced whether the stream was linked to its Youtube video, which takes its place on the schedule
+func (ms *ManualStream) Announced() bool {
+	return ms.VideoID != ""
 }
 
+// ReplacedBy whether a stream that wasn't linked to its video seems to be on the schedule already, going by its time
 func (ms *ManualStream) ReplacedBy(recs []models.YoutubeStreamRecord) bool {
 	for _, rec := range recs {
 		diff := ms.Time.Sub(rec.ScheduledTime).Hours()
@@ -251,210 +137,314 @@ func (ms *ManualStream) ReplacedBy(recs []models.YoutubeStreamRecord) bool {
 	return false
 }
 
-var addGuerrillaRE = regexp.MustCompile(`(\d\d\d\d\/\d\d\/\d\d \d\d:\d\d) ([\S]+) (.+)`)
+// Leaves out characters that are easily confused
+const manualStreamIDChars = "abcdefghjkmnpqrstuvwxyz23456789"
 
-func (m *Mux) AddGuerrilla(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
-	respond := GetResponder(ds, dm)
-
-	cmd := strings.TrimSpace(strings.TrimPrefix(ctx.Content, "addstream"))
+// newManualStreamID a short ID that no other manual stream has
+func newManualStreamID(db *mgo.Database) (string, error) {
+	schedCol := db.C("scheduled_streams")
+	for i := 0; i < 10; i++ {
+		id := make([]byte, 4)
+		for j := range id {
+			id[j] = manualStreamIDChars[rand.Intn(len(manualStreamIDChars))]
+		}
 
-	match := addGuerrillaRE.FindAllSubmatch([]byte(cmd), -1)
-	if match == nil {
-		respond("🔺Usage: `-addguerrilla yyyy/mm/dd hh:mm <est. time> <title>`")
-		return
+		n, err := schedCol.Find(bson.M{"id": string(id)}).Count()
+		if err != nil {
+			return "", err
+		}
+		if n == 0 {
+			return string(id), nil
+		}
 	}
+	return "", errors.New("could not find a free stream ID")
+}
 
-	timeStr := string(match[0][1])
-	guerStr := string(match[0][2])
-	titleStr := string(match[0][3])
+// assignManualStreamIDs give an ID to streams that were added before streams had them
+func assignManualStreamIDs(db *mgo.Database, streams []ManualStream) {
+	schedCol := db.C("scheduled_streams")
+	for i := range streams {
+		if streams[i].ID != "" {
+			continue
+		}
+		id, err := newManualStreamID(db)
+		if err != nil {
+			log.Printf("Failed to assign a stream ID, %s", err)
+			return
+		}
+		err = schedCol.Update(bson.M{"time": streams[i].Time, "title": streams[i].Title, "id": bson.M{"$in": []interface{}{nil, ""}}}, bson.M{"$set": bson.M{"id": id}})
+		if err != nil {
+			log.Printf("Failed to assign a stream ID, %s", err)
+			continue
+		}
+		streams[i].ID = id
+	}
+}
 
-	Loc, _ := time.LoadLocation("Asia/Tokyo")
-	t, err := time.ParseInLocation("2006/01/02 15:04", timeStr, Loc)
+func insertManualStream(db *mgo.Database, stream *ManualStream) error {
+	id, err := newManualStreamID(db)
 	if err != nil {
-		respond("🔺I don't understand that stream time :(\nUsage: `-addguerrilla yyyy/mm/dd hh:mm <est. time> <title>` (" + err.Error() + ")")
-		return
+		return err
 	}
+	stream.ID = id
+	stream.CreatedAt = time.Now()
+	stream.UpdatedAt = stream.CreatedAt
+	return db.C("scheduled_streams").Insert(stream)
+}
+
+var addGuerrillaUsage = "🔺Usage: `-addguerrilla <time> <est. time> <title>`\nTimes can be like `2021/03/12 21:00`, `tomorrow 9pm`, `in 2h`, `saturday 20:00 PST` or a Discord timestamp\nThe estimate is a length like `30m`, `2h` or `1d`"
+
+func (m *Mux) AddGuerrilla(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	cmd := strings.TrimSpace(strings.TrimPrefix(ctx.Content, "addguerrilla"))
 
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	session.SetMode(mgo.Strong, false)
-	c := context.Background()
-	c = context.WithValue(c, "mgo", session)
 	db := session.DB(mongo.DB_NAME)
 
+	t, rest, err := timeparse.ParsePrefix(cmd, time.Now(), timeparse.UserZone(db, dm.Author.ID))
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s :(\n%s", err, addGuerrillaUsage))
+		return
+	}
+
+	parts := strings.SplitN(rest, " ", 2)
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		respond(addGuerrillaUsage)
+		return
+	}
+	guerStr := parts[0]
+	titleStr := strings.TrimSpace(parts[1])
+	if _, ok := parseDuration(guerStr); !ok {
+		respond("🔺I don't understand that estimated time :(\n" + addGuerrillaUsage)
+		return
+	}
+
 	stream := ManualStream{
 		Time:          t,
 		Title:         titleStr,
 		GuerrillaTime: guerStr,
+		CreatedBy:     dm.Author.ID,
 	}
-	schedCol := db.C("scheduled_streams")
-	schedCol.Upsert(bson.M{"time": stream.Time}, stream)
-	respond("🔺Guerilla stream added at " + config.PrintDate(t) + " (" + guerStr + ")")
+	err = insertManualStream(db, &stream)
+	if err != nil {
+		respond("🔺Failed to add the stream: " + err.Error())
+		return
+	}
+	respond(fmt.Sprintf("🔺Guerilla stream `%s` added around %s (%s)", stream.ID, timeparse.Discord(t, "f"), guerStr))
 }
 
-var addStreamRE = regexp.MustCompile(`(\d\d\d\d\/\d\d\/\d\d \d\d:\d\d) (.+)`)
+var addStreamUsage = "🔺Usage: `-addstream <time> <title>`\nTimes can be like `2021/03/12 21:00`, `tomorrow 9pm`, `in 2h`, `saturday 20:00 PST` or a Discord timestamp"
 
 func (m *Mux) AddStream(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
 	respond := GetResponder(ds, dm)
 
 	cmd := strings.TrimSpace(strings.TrimPrefix(ctx.Content, "addstream"))
 
-	match := addStreamRE.FindAllSubmatch([]byte(cmd), -1)
-	if match == nil {
-		respond("🔺Usage: `-addstream yyyy/mm/dd hh:mm <title>`")
-		return
-	}
-
-	timeStr := string(match[0][1])
-	titleStr := string(match[0][2])
-
-	Loc, _ := time.LoadLocation("Asia/Tokyo")
-	t, err := time.ParseInLocation("2006/01/02 15:04", timeStr, Loc)
-	if err != nil {
-		respond("🔺I don't understand that stream time :(\nUsage: `-addstream yyyy/mm/dd hh:mm <title>` (" + err.Error() + ")")
-		return
-	}
-
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	session.SetMode(mgo.Strong, false)
-	c := context.Background()
-	c = context.WithValue(c, "mgo", session)
 	db := session.DB(mongo.DB_NAME)
 
+	t, titleStr, err := timeparse.ParsePrefix(cmd, time.Now(), timeparse.UserZone(db, dm.Author.ID))
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s :(\n%s", err, addStreamUsage))
+		return
+	}
+	if titleStr == "" {
+		respond(addStreamUsage)
+		return
+	}
+
 	stream := ManualStream{
-		Time:  t,
-		Title: titleStr,
+		Time:      t,
+		Title:     titleStr,
+		CreatedBy: dm.Author.ID,
 	}
-	schedCol := db.C("scheduled_streams")
-	schedCol.Upsert(bson.M{"time": stream.Time}, stream)
-	respond("🔺Stream added at " + config.PrintTime(t))
+	err = insertManualStream(db, &stream)
+	if err != nil {
+		respond("🔺Failed to add the stream: " + err.Error())
+		return
+	}
+	respond(fmt.Sprintf("🔺Stream `%s` added at %s (%s JST)", stream.ID, timeparse.Discord(t, "F"), config.PrintTime(t.In(config.Loc))))
 }
 
-var removeStreamRE = regexp.MustCompile(`(\d\d\d\d\/\d\d\/\d\d \d\d:\d\d)`)
+var removeStreamUsage = "🔺Usage: `-removestream <id>`, see `-db manualstreams` for the IDs"
 
 func (m *Mux) RemoveStream(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
 	respond := GetResponder(ds, dm)
 
-	cmd := strings.TrimSpace(strings.TrimPrefix(ctx.Content, "removestream"))
-
-	match := removeStreamRE.FindAllSubmatch([]byte(cmd), -1)
-	if match == nil {
-		respond("🔺Usage: `-removestream yyyy/mm/dd hh:mm`")
-		return
-	}
-
-	timeStr := string(match[0][1])
-
-	Loc, _ := time.LoadLocation("Asia/Tokyo")
-	t, err := time.ParseInLocation("2006/01/02 15:04", timeStr, Loc)
-	if err != nil {
-		respond("🔺I don't understand that stream time :(\nUsage: `-removestream yyyy/mm/dd hh:mm` (" + err.Error() + ")")
+	if len(ctx.Fields) != 2 {
+		respond(removeStreamUsage)
 		return
 	}
 
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	session.SetMode(mgo.Strong, false)
-	c := context.Background()
-	c = context.WithValue(c, "mgo", session)
 	db := session.DB(mongo.DB_NAME)
 
 	schedCol := db.C("scheduled_streams")
 	stream := ManualStream{}
-	err = schedCol.Find(bson.M{"time": t}).One(&stream)
+	err := schedCol.Find(bson.M{"id": strings.ToLower(ctx.Fields[1])}).One(&stream)
 	if err != nil {
-		respond("🔺I couldn't find a stream at " + config.PrintTime(t) + " :(")
-	} else {
-		schedCol.Remove(bson.M{"time": t})
-		respond("🔺Stream removed")
+		respond(fmt.Sprintf("🔺I couldn't find a stream `%s` :(\n%s", ctx.Fields[1], removeStreamUsage))
+		return
 	}
-}
 
-func (m *Mux) InitScanForUpdates(ds *discordgo.Session) {
-	sleepDuration := 60 * time.Second
-	for {
-		time.Sleep(sleepDuration)
-		m.ScanForUpdates(ds)
+	err = schedCol.Remove(bson.M{"id": stream.ID})
+	if err != nil {
+		respond("🔺Failed to remove the stream: " + err.Error())
+		return
 	}
+	respond(fmt.Sprintf("🔺Stream `%s` (%s) removed", stream.ID, stream.Title))
 }
 
-func (m *Mux) ScanForUpdates(ds *discordgo.Session) {
-	// fmt.Println("SCAN FOR UPDATES")
-	pruned := []EmbedToUpdate{}
-	for _, etu := range EmbedsToUpdate {
-		if etu.Time.Add(24 * time.Hour).After(time.Now()) {
-			pruned = append(pruned, etu)
-		}
-	}
-	EmbedsToUpdate = pruned
-	if len(EmbedsToUpdate) == 0 {
+var editStreamUsage = "🔺Usage:\n" +
+	"`-db editstream <id> title <title>`\n" +
+	"`-db editstream <id> time <time>`\n" +
+	"`-db editstream <id> guerrilla <est. time|none>`\n" +
+	"`-db editstream <id> video <Youtube link|none>` link the stream to its Youtube video once it's announced"
+
+// EditStream change a manually added stream
+func (m *Mux) EditStream(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) < 4 {
+		respond(editStreamUsage)
 		return
 	}
+	id := strings.ToLower(ctx.Fields[1])
+	field := ctx.Fields[2]
+	value := strings.Join(ctx.Fields[3:], " ")
 
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	session.SetMode(mgo.Strong, false)
-	c := context.Background()
-	c = context.WithValue(c, "mgo", session)
 	db := session.DB(mongo.DB_NAME)
 
-	ytCol := db.C("youtube_stream_records")
-	recs := []models.YoutubeStreamRecord{}
-	err := ytCol.Find(bson.M{"completed": false}).All(&recs)
+	schedCol := db.C("scheduled_streams")
+	stream := ManualStream{}
+	err := schedCol.Find(bson.M{"id": id}).One(&stream)
 	if err != nil {
-		fmt.Println("🔺Could not get stream information, " + err.Error())
+		respond(fmt.Sprintf("🔺I couldn't find a stream `%s` :(", id))
 		return
 	}
 
-	if len(recs) > 0 {
-		ytSvc, err := youtubesvc.NewYoutubeService(c)
+	set := bson.M{"updated_at": time.Now()}
+	var resp string
+	switch field {
+	case "title":
+		set["title"] = value
+		resp = fmt.Sprintf("🔺Stream `%s` is now called %s", id, value)
+	case "time":
+		t, err := timeparse.Parse(value, time.Now(), timeparse.UserZone(db, dm.Author.ID))
 		if err != nil {
-			fmt.Println("🔺Could not connect to Youtube")
+			respond("🔺I don't understand that stream time :( (" + err.Error() + ")")
 			return
 		}
-		for i, rec := range recs {
-			scheduledTime, _, snippet, err := ytSvc.GetStreamInfo(rec.YoutubeID)
+		set["time"] = t
+		resp = fmt.Sprintf("🔺Stream `%s` moved to %s", id, timeparse.Discord(t, "F"))
+	case "guerrilla":
+		if value == "none" {
+			value = ""
+		} else if _, ok := parseDuration(value); !ok {
+			respond("🔺I don't understand that estimated time :( It should be a length like `30m`, `2h` or `1d`")
+			return
+		}
+		set["guerrilla_time"] = value
+		resp = fmt.Sprintf("🔺Stream `%s` is no longer a guerrilla stream", id)
+		if value != "" {
+			resp = fmt.Sprintf("🔺Stream `%s` is a guerrilla stream of about %s", id, value)
+		}
+	case "video":
+		videoID := ""
+		resp = fmt.Sprintf("🔺Stream `%s` is no longer linked to a video", id)
+		if value != "none" {
+			c := context.WithValue(context.Background(), "mgo", session)
+			ytSvc, err := youtubesvc.NewYoutubeService(c)
+			if err != nil {
+				respond("🔺Could not connect to Youtube: " + err.Error())
+				return
+			}
+			videoID, err = ytSvc.ParseVideoID(value)
 			if err != nil {
-				fmt.Println("🔺Error getting video info: " + err.Error())
+				respond("🔺That doesn't look like a Youtube link or video ID to me!")
+				return
 			}
-			recs[i].ScheduledTime = scheduledTime
-			recs[i].StreamTitle = snippet.Title
-			recs[i].StreamThumbnail = snippet.Thumbnails.High.Url
+			recs, err := ytSvc.GetStreams([]string{videoID})
+			if err != nil || len(recs) == 0 {
+				respond("🔺I couldn't find that video on Youtube, or it isn't a stream")
+				return
+			}
+			resp = fmt.Sprintf("🔺Stream `%s` is linked to [%s](%s), which takes its place on the schedule", id, recs[0].StreamTitle, recs[0].PostLink)
 		}
+		set["video_id"] = videoID
+	default:
+		respond(editStreamUsage)
+		return
 	}
 
-	schedCol := db.C("scheduled_streams")
-	schedStreams := []ManualStream{}
-	err = schedCol.Find(bson.M{"time": bson.M{"$gt": time.Now()}}).Sort("time").All(&schedStreams)
-	if err != nil && err != mgo.ErrNotFound {
-		fmt.Println("Failed to get manually scheduled streams: " + err.Error())
+	err = schedCol.Update(bson.M{"id": id}, bson.M{"$set": set})
+	if err != nil {
+		respond("🔺Failed to update the stream: " + err.Error())
+		return
 	}
+	respond(resp)
+}
 
-	if len(schedStreams) > 0 {
-		Loc, _ := time.LoadLocation("Asia/Tokyo")
-		for _, schedStream := range schedStreams {
-			schedStream.Time = schedStream.Time.In(Loc)
-		}
+// ManualStreams list the manually added streams with their IDs, upcoming ones or the past ones
+func (m *Mux) ManualStreams(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	past := len(ctx.Fields) > 1 && ctx.Fields[1] == "past"
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	query := bson.M{"time": bson.M{"$gt": time.Now()}}
+	sort := "time"
+	if past {
+		query = bson.M{"time": bson.M{"$lte": time.Now()}}
+		sort = "-time"
 	}
 
-	if len(recs) == 0 && len(schedStreams) == 0 {
-		for _, etu := range EmbedsToUpdate {
-			ds.ChannelMessageEdit(etu.ChannelID, etu.MessageID, "🔺No upcoming streams found :(")
-		}
-		EmbedsToUpdate = []EmbedToUpdate{}
+	streams := []ManualStream{}
+	err := db.C("scheduled_streams").Find(query).Sort(sort).Limit(20).All(&streams)
+	if err != nil {
+		respond("🔺Failed to get the manually added streams: " + err.Error())
 		return
 	}
+	assignManualStreamIDs(db, streams)
 
-	embed := StreamsEmbed(schedStreams, recs)
-	succeeded := []EmbedToUpdate{}
-	for _, etu := range EmbedsToUpdate {
-		_, err = ds.ChannelMessageEditEmbed(etu.ChannelID, etu.MessageID, embed)
-		if err != nil {
-			fmt.Println("Failed to update previous streams embed: " + err.Error())
+	if len(streams) == 0 {
+		if past {
+			respond("🔺No streams were added manually yet")
 		} else {
-			succeeded = append(succeeded, etu)
+			respond("🔺No upcoming streams were added manually, `-db manualstreams past` lists the past ones")
+		}
+		return
+	}
+
+	lines := []string{}
+	for _, stream := range streams {
+		line := fmt.Sprintf("`%s` %s **%s**", stream.ID, timeparse.Discord(stream.Time, "f"), stream.Title)
+		if stream.GuerrillaTime != "" {
+			line += fmt.Sprintf(" ❓%s", stream.GuerrillaTime)
+		}
+		if stream.Announced() {
+			line += fmt.Sprintf(" → <https://www.youtube.com/watch?v=%s>", stream.VideoID)
 		}
+		lines = append(lines, line)
+	}
+
+	title := "🔺Upcoming manually added streams:\n"
+	if past {
+		title = "🔺Past manually added streams, latest first:\n"
 	}
-	EmbedsToUpdate = succeeded
+	respond(title + strings.Join(lines, "\n"))
 }
@@ -0,0 +1,67 @@
+package mux
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+// How many months the stats go back
+var streamStatsMonths = 12
+
+// StreamStats show monthly totals and streaks of the guild's Youtube channels, from the stream archive
+func (m *Mux) StreamStats(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	channelIDs := []string{}
+	for _, yc := range config.GuildYoutubeChannels(dm.GuildID) {
+		channelIDs = append(channelIDs, yc.ChannelID)
+	}
+
+	archs := []models.StreamArchive{}
+	err := db.C("stream_archive").Find(bson.M{
+		"channel_id": bson.M{"$in": channelIDs},
+		"started_at": bson.M{"$gt": config.Now().AddDate(0, -streamStatsMonths, 0)},
+	}).All(&archs)
+	if err != nil {
+		respond("🔺Failed to get the stream archive: " + err.Error())
+		return
+	}
+	if len(archs) == 0 {
+		respond("🔺No streams were archived yet")
+		return
+	}
+
+	stats := youtubesvc.ComputeStreamStats(archs, config.Now(), config.Loc)
+
+	resp := "```Month     Streams    Hours    Peak viewers    Views"
+	for _, ms := range stats.Months {
+		resp += fmt.Sprintf("\n%-9s %7d %8.1f %15d %8d", ms.Month, ms.Streams, ms.Duration.Hours(), ms.PeakViewers, ms.Views)
+	}
+	resp += fmt.Sprintf("\n\nLongest streak:  %d days in a row, until %s", stats.LongestStreak, config.PrintDate(stats.LongestEnd))
+	resp += fmt.Sprintf("\nCurrent streak:  %d days", stats.CurrentStreak)
+
+	best := archs[0]
+	for _, arch := range archs {
+		if arch.PeakViewers > best.PeakViewers {
+			best = arch
+		}
+	}
+	if best.PeakViewers > 0 {
+		resp += fmt.Sprintf("\nMost watched:    %s (%d viewers)", best.Title, best.PeakViewers)
+	}
+	resp += "```"
+
+	respond(resp)
+}
@@ -0,0 +1,59 @@
+package mux
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/timeparse"
+)
+
+var timezoneUsage = "🔺Usage: `-db timezone [<zone>|reset]`, e.g. `America/New_York`, `PST` or `UTC+2`"
+
+// Timezone show or set the time zone the user's times are read in
+func (m *Mux) Timezone(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	if len(ctx.Fields) < 2 {
+		loc := timeparse.UserZone(db, dm.Author.ID)
+		respond(fmt.Sprintf("🔺Your times are read in %s, it's %s there now\n%s", loc, time.Now().In(loc).Format("15:04"), timezoneUsage))
+		return
+	}
+	if len(ctx.Fields) > 2 {
+		respond(timezoneUsage)
+		return
+	}
+
+	zone := ctx.Fields[1]
+	if strings.ToLower(zone) == "reset" {
+		err := timeparse.SetUserZone(db, dm.Author.ID, "")
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to reset your time zone: %s", err))
+			return
+		}
+		respond(fmt.Sprintf("🔺Your times will be read in %s", config.Loc))
+		return
+	}
+
+	loc, err := timeparse.LoadZone(zone)
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s\n%s", err, timezoneUsage))
+		return
+	}
+
+	err = timeparse.SetUserZone(db, dm.Author.ID, zone)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to set your time zone: %s", err))
+		return
+	}
+	respond(fmt.Sprintf("🔺Your times will be read in %s, it's %s there now", loc, time.Now().In(loc).Format("15:04")))
+}
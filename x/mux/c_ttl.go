@@ -20,7 +20,7 @@ func (m *Mux) TweetTranslate(ds *discordgo.Session, dm *discordgo.Message, ctx *
 	respond := GetResponder(ds, dm)
 
 	foundChannel := false
-	for _, tsc := range config.TweetSyncChannels {
+	for _, tsc := range config.GetTweetSyncChannels() {
 		if tsc.ChannelID == dm.ChannelID {
 			foundChannel = true
 		}
@@ -60,7 +60,7 @@ func (m *Mux) TweetTranslate(ds *discordgo.Session, dm *discordgo.Message, ctx *
 	}
 
 	if ctx.Content == "" {
-		msg := respond(fmt.Sprintf("🔺Usage: -db ttl <translation for oldest untranslated Tweet within 24 hours>\nCurrently pointing to:\n❝ %s ❞", st.Tweet.FullText))
+		msg := respond(fmt.Sprintf("🔺Usage: -db ttl <translation for oldest untranslated Tweet within 24 hours>\nCurrently pointing to:\n❝ %s ❞", st.Text()))
 		err = ds.MessageReactionAdd(msg.ChannelID, msg.ID, "\u274C")
 		if err != nil {
 			fmt.Printf("Failed to add \u274C reaction, %s\n", err)
@@ -86,7 +86,7 @@ func (m *Mux) TweetTranslate(ds *discordgo.Session, dm *discordgo.Message, ctx *
 		return
 	}
 
-	msg := respond(fmt.Sprintf("🔺Translate:\n❝ %s ❞\nto\n❝ %s ❞", st.Tweet.FullText, ctx.Content))
+	msg := respond(fmt.Sprintf("🔺Translate:\n❝ %s ❞\nto\n❝ %s ❞", st.Text(), ctx.Content))
 	err = ds.MessageReactionAdd(msg.ChannelID, msg.ID, "\u2705")
 	if err != nil {
 		fmt.Printf("Failed to add \u2705 reaction, %s\n", err)
@@ -109,7 +109,7 @@ func (m *Mux) TweetEdit(ds *discordgo.Session, dm *discordgo.Message, ctx *Conte
 	respond := GetResponder(ds, dm)
 
 	foundChannel := false
-	for _, tsc := range config.TweetSyncChannels {
+	for _, tsc := range config.GetTweetSyncChannels() {
 		if tsc.ChannelID == dm.ChannelID {
 			foundChannel = true
 		}
@@ -177,7 +177,7 @@ func (m *Mux) TweetEdit(ds *discordgo.Session, dm *discordgo.Message, ctx *Conte
 	}
 
 	if len(parts) == 1 {
-		msg := respond(fmt.Sprintf("🔺Usage: -db tedit <number of tweet counting upwards> <translation>\nCurrently pointing to:\n❝ %s ❞", st.Tweet.FullText))
+		msg := respond(fmt.Sprintf("🔺Usage: -db tedit <number of tweet counting upwards> <translation>\nCurrently pointing to:\n❝ %s ❞", st.Text()))
 		err = ds.MessageReactionAdd(msg.ChannelID, msg.ID, "\u274C")
 		if err != nil {
 			fmt.Printf("Failed to add \u274C reaction, %s\n", err)
@@ -192,7 +192,7 @@ func (m *Mux) TweetEdit(ds *discordgo.Session, dm *discordgo.Message, ctx *Conte
 
 	ctx.Content = strings.Join(parts[1:], " ")
 
-	msg := respond(fmt.Sprintf("🔺Translate:\n❝ %s ❞\nto\n❝ %s ❞", st.Tweet.FullText, ctx.Content))
+	msg := respond(fmt.Sprintf("🔺Translate:\n❝ %s ❞\nto\n❝ %s ❞", st.Text(), ctx.Content))
 	err = ds.MessageReactionAdd(msg.ChannelID, msg.ID, "\u2705")
 	if err != nil {
 		fmt.Printf("Failed to add \u2705 reaction, %s\n", err)
@@ -233,9 +233,9 @@ func (m *Mux) ConfirmTweet(ds *discordgo.Session, st models.SyncedTweet) {
 		return
 	}
 
-	embed := tweetsync.SyncedTweetToEmbed(st)
+	embeds := tweetsync.SyncedTweetToEmbeds(st)
 
-	_, err = ds.ChannelMessageEditEmbed(st.ChannelID, st.MessageID, embed)
+	_, err = utils.ChannelMessageEditEmbeds(ds, st.ChannelID, st.MessageID, embeds)
 	if err != nil {
 		ds.ChannelMessageSend(st.ChannelID, fmt.Sprintf("Error updating tweet: %s", err))
 		return
@@ -285,15 +285,15 @@ func (m *Mux) DoTweetUpdateByReply(ds *discordgo.Session, dm *discordgo.Message,
 		return
 	}
 
-	embed := tweetsync.SyncedTweetToEmbed(st)
+	embeds := tweetsync.SyncedTweetToEmbeds(st)
 
-	_, err = ds.ChannelMessageEditEmbed(st.ChannelID, st.MessageID, embed)
+	_, err = utils.ChannelMessageEditEmbeds(ds, st.ChannelID, st.MessageID, embeds)
 	if err != nil {
 		ds.ChannelMessageSend(st.ControlChannelID, fmt.Sprintf("Error updating tweet: %s", err))
 		return
 	}
 
-	_, err = ds.ChannelMessageEditEmbed(st.ControlChannelID, st.ControlMessageID, embed)
+	_, err = utils.ChannelMessageEditEmbeds(ds, st.ControlChannelID, st.ControlMessageID, embeds)
 	if err != nil {
 		ds.ChannelMessageSend(st.ControlChannelID, fmt.Sprintf("Error updating tweet: %s", err))
 		return
@@ -342,16 +342,16 @@ func (m *Mux) DoTweetUpdate(ds *discordgo.Session, tu config.TweetUpdate) {
 		return
 	}
 
-	embed := tweetsync.SyncedTweetToEmbed(st)
+	embeds := tweetsync.SyncedTweetToEmbeds(st)
 
-	_, err = ds.ChannelMessageEditEmbed(tu.ChannelID, st.MessageID, embed)
+	_, err = utils.ChannelMessageEditEmbeds(ds, tu.ChannelID, st.MessageID, embeds)
 	if err != nil {
 		ds.ChannelMessageSend(tu.ChannelID, fmt.Sprintf("Error updating tweet: %s", err))
 		return
 	}
 
 	if st.ControlMessageID != "" {
-		_, err = ds.ChannelMessageEditEmbed(tu.ChannelID, st.ControlMessageID, embed)
+		_, err = utils.ChannelMessageEditEmbeds(ds, tu.ChannelID, st.ControlMessageID, embeds)
 		if err != nil {
 			ds.ChannelMessageSend(tu.ChannelID, fmt.Sprintf("Error updating tweet: %s", err))
 			return
@@ -0,0 +1,296 @@
+package mux
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/tweetsync"
+)
+
+var tweetSyncUsage = "🔺Usage:\n" +
+	"`-db tweetsync list`\n" +
+	"`-db tweetsync add <handle> <#channel> [#control channel] [twitter|rss|nitter] [feed url]`\n" +
+	"`-db tweetsync remove <handle> [#channel]`\n" +
+	"`-db tweetsync pause <handle> [#channel]`\n" +
+	"`-db tweetsync resume <handle> [#channel]`\n" +
+	"`-db tweetsync backfill <handle> <since> <until>` (Tweet IDs or yyyy/mm/dd)\n" +
+	"`-db tweetsync repost <tweet id>`"
+
+var channelMentionRE = regexp.MustCompile(`^<#(\d+)>$`)
+
+func (m *Mux) TweetSync(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) < 2 {
+		respond(tweetSyncUsage)
+		return
+	}
+
+	args := ctx.Fields[2:]
+	switch ctx.Fields[1] {
+	case "list":
+		m.TweetSyncList(ds, dm)
+	case "add":
+		m.TweetSyncAdd(ds, dm, args)
+	case "remove":
+		m.TweetSyncRemove(ds, dm, args)
+	case "pause":
+		m.TweetSyncPause(ds, dm, args, true)
+	case "resume":
+		m.TweetSyncPause(ds, dm, args, false)
+	case "backfill":
+		m.TweetSyncBackfill(ds, dm, args)
+	case "repost":
+		m.TweetSyncRepost(ds, dm, args)
+	default:
+		respond(tweetSyncUsage)
+	}
+}
+
+func (m *Mux) TweetSyncBackfill(ds *discordgo.Session, dm *discordgo.Message, args []string) {
+	prerespond := GetResponder(ds, dm)
+
+	if len(args) != 3 {
+		prerespond(tweetSyncUsage)
+		return
+	}
+
+	since, err := tweetsync.ParseBackfillBound(args[1], false)
+	if err != nil {
+		prerespond(fmt.Sprintf("🔺Bad start of range: %s", err))
+		return
+	}
+	until, err := tweetsync.ParseBackfillBound(args[2], true)
+	if err != nil {
+		prerespond(fmt.Sprintf("🔺Bad end of range: %s", err))
+		return
+	}
+	if until.Before(since) {
+		prerespond("🔺The end of the range is before the start!")
+		return
+	}
+
+	msg := prerespond(fmt.Sprintf("🔺Backfilling @%s...", args[0]))
+	respond := GetEditor(ds, msg)
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	res, err := tweetsync.Backfill(ds, db, args[0], since, until)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to backfill: %s", err))
+		return
+	}
+
+	respond(fmt.Sprintf("🔺Backfilled @%s: %d posted, %d updated, %d failed", args[0], res.Posted, res.Updated, res.Failed))
+}
+
+func (m *Mux) TweetSyncRepost(ds *discordgo.Session, dm *discordgo.Message, args []string) {
+	prerespond := GetResponder(ds, dm)
+
+	if len(args) != 1 {
+		prerespond(tweetSyncUsage)
+		return
+	}
+
+	msg := prerespond("🔺Reposting...")
+	respond := GetEditor(ds, msg)
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	res, err := tweetsync.Repost(ds, db, args[0])
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to repost: %s", err))
+		return
+	}
+
+	respond(fmt.Sprintf("🔺Reposted %s: %d posted, %d updated, %d failed", args[0], res.Posted, res.Updated, res.Failed))
+}
+
+func (m *Mux) TweetSyncList(ds *discordgo.Session, dm *discordgo.Message) {
+	respond := GetResponder(ds, dm)
+
+	statuses := tweetsync.ScannerStatuses()
+	if len(statuses) == 0 {
+		respond("🔺No Tweet syncs are set up")
+		return
+	}
+
+	resp := "🔺Tweet syncs:"
+	for _, st := range statuses {
+		state := "▶ Running"
+		if st.Retrying {
+			state = "⚠ Failed to start, retrying"
+		} else if !st.Running {
+			state = "⏸ Paused"
+		}
+		resp += fmt.Sprintf("\n**@%s** (%s) → <#%s> — %s", st.Handle, st.Source, st.ChannelID, state)
+
+		if st.LastPoll.IsZero() {
+			resp += "\n　Not polled yet"
+		} else {
+			resp += fmt.Sprintf("\n　Last poll: %s ago", time.Since(st.LastPoll).Round(time.Second))
+		}
+		if !st.LastPost.IsZero() {
+			resp += fmt.Sprintf(", last post %s, lag %s", config.PrintTime(st.LastPost.In(config.Loc)), st.Lag.Round(time.Second))
+		}
+		if st.LastError != "" {
+			resp += fmt.Sprintf("\n　Last error (%s ago): `%s`", time.Since(st.LastErrorAt).Round(time.Second), st.LastError)
+		}
+	}
+
+	respond(resp)
+}
+
+func (m *Mux) TweetSyncAdd(ds *discordgo.Session, dm *discordgo.Message, args []string) {
+	prerespond := GetResponder(ds, dm)
+
+	if len(args) < 2 {
+		prerespond(tweetSyncUsage)
+		return
+	}
+
+	ts := config.TweetSyncConfig{
+		Handle: strings.TrimPrefix(args[0], "@"),
+	}
+	for _, arg := range args[1:] {
+		if match := channelMentionRE.FindStringSubmatch(arg); match != nil {
+			if ts.ChannelID == "" {
+				ts.ChannelID = match[1]
+			} else {
+				ts.ControlChannelID = match[1]
+			}
+			continue
+		}
+
+		switch arg {
+		case models.FeedSourceTwitter, models.FeedSourceRSS, models.FeedSourceNitter:
+			ts.Source = arg
+		default:
+			if strings.HasPrefix(arg, "http") {
+				ts.FeedURL = arg
+			} else {
+				prerespond(fmt.Sprintf("🔺I don't understand `%s`\n%s", arg, tweetSyncUsage))
+				return
+			}
+		}
+	}
+
+	if ts.ChannelID == "" {
+		prerespond("🔺Which channel should the posts go to?\n" + tweetSyncUsage)
+		return
+	}
+
+	msg := prerespond(fmt.Sprintf("🔺Checking @%s...", ts.Handle))
+	respond := GetEditor(ds, msg)
+
+	err := config.AddTweetSyncChannel(ts)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to add Tweet sync: %s", err))
+		return
+	}
+
+	err = tweetsync.StartScanner(ds, ts)
+	if err != nil {
+		// A sync that never worked isn't kept around to retry
+		tweetsync.RemoveScanner(ts.Handle, ts.ChannelID)
+		config.RemoveTweetSyncChannel(ts.Handle, ts.ChannelID)
+		respond(fmt.Sprintf("🔺Couldn't follow @%s: %s", ts.Handle, err))
+		return
+	}
+
+	respond(fmt.Sprintf("🔺Now syncing @%s (%s) to <#%s>", ts.Handle, ts.FeedSource(), ts.ChannelID))
+}
+
+func (m *Mux) TweetSyncRemove(ds *discordgo.Session, dm *discordgo.Message, args []string) {
+	respond := GetResponder(ds, dm)
+
+	ts, err := findTweetSync(args)
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s", err))
+		return
+	}
+
+	tweetsync.RemoveScanner(ts.Handle, ts.ChannelID)
+	err = config.RemoveTweetSyncChannel(ts.Handle, ts.ChannelID)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to remove Tweet sync: %s", err))
+		return
+	}
+
+	respond(fmt.Sprintf("🔺No longer syncing @%s to <#%s>", ts.Handle, ts.ChannelID))
+}
+
+func (m *Mux) TweetSyncPause(ds *discordgo.Session, dm *discordgo.Message, args []string, paused bool) {
+	respond := GetResponder(ds, dm)
+
+	ts, err := findTweetSync(args)
+	if err != nil {
+		respond(fmt.Sprintf("🔺%s", err))
+		return
+	}
+
+	err = config.SetTweetSyncPaused(ts.Handle, ts.ChannelID, paused)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to update Tweet sync: %s", err))
+		return
+	}
+
+	if paused {
+		tweetsync.StopScanner(ts.Handle, ts.ChannelID)
+		respond(fmt.Sprintf("🔺Paused syncing @%s to <#%s>", ts.Handle, ts.ChannelID))
+		return
+	}
+
+	ts.Paused = false
+	err = tweetsync.StartScanner(ds, ts)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to resume @%s, will keep trying: %s", ts.Handle, err))
+		return
+	}
+	respond(fmt.Sprintf("🔺Resumed syncing @%s to <#%s>", ts.Handle, ts.ChannelID))
+}
+
+// findTweetSync find the single sync described by a handle and optional channel mention
+func findTweetSync(args []string) (config.TweetSyncConfig, error) {
+	if len(args) == 0 {
+		return config.TweetSyncConfig{}, fmt.Errorf("Which handle?\n%s", tweetSyncUsage)
+	}
+
+	channelID := ""
+	if len(args) > 1 {
+		match := channelMentionRE.FindStringSubmatch(args[1])
+		if match == nil {
+			return config.TweetSyncConfig{}, fmt.Errorf("`%s` isn't a channel", args[1])
+		}
+		channelID = match[1]
+	}
+
+	found := []config.TweetSyncConfig{}
+	for _, ts := range tweetsync.SyncConfigsForHandle(args[0]) {
+		if channelID == "" || ts.ChannelID == channelID {
+			found = append(found, *ts)
+		}
+	}
+
+	if len(found) == 0 {
+		return config.TweetSyncConfig{}, fmt.Errorf("No Tweet sync found for %s", args[0])
+	}
+	if len(found) > 1 {
+		return config.TweetSyncConfig{}, fmt.Errorf("%s is synced to several channels, which one?", args[0])
+	}
+
+	return found[0], nil
+}
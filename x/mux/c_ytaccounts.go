@@ -0,0 +1,44 @@
+package mux
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+// YoutubeAccounts list the Youtube accounts chat messages are copied with, and how they're doing
+func (m *Mux) YoutubeAccounts(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	statuses := youtubesvc.PosterStatuses()
+	if len(statuses) == 0 {
+		respond("🔺No Youtube accounts are linked")
+		return
+	}
+
+	now := time.Now()
+	resp := "```Youtube accounts"
+	for _, ps := range statuses {
+		health := "OK"
+		if now.Before(ps.RestUntil) {
+			health = fmt.Sprintf("resting for %s", ps.RestUntil.Sub(now).Round(time.Second))
+		} else if ps.Failures > 0 {
+			health = fmt.Sprintf("%d failures in a row", ps.Failures)
+		}
+
+		resp += fmt.Sprintf("\n\n%s: %s", ps.Email, health)
+		resp += fmt.Sprintf("\n  Sent today:    %d messages, %d units", ps.Sent, ps.Units)
+		if ps.BannedChats > 0 {
+			resp += fmt.Sprintf("\n  Banned from:   %d chats", ps.BannedChats)
+		}
+		if ps.LastError != "" {
+			resp += fmt.Sprintf("\n  Last error:    %s, %s", config.PrintTime(ps.LastErrorAt.In(config.Loc)), ps.LastError)
+		}
+	}
+	resp += "```"
+
+	respond(resp)
+}
@@ -0,0 +1,86 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+var ytChannelsUsage = "🔺Usage:\n" +
+	"`-db ytchannels` list the Youtube channels on this server's schedule\n" +
+	"`-db ytchannels add <channel id> [label]`\n" +
+	"`-db ytchannels remove <channel id>`"
+
+func (m *Mux) YoutubeChannels(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	prerespond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) < 2 {
+		ycs := config.GuildYoutubeChannels(dm.GuildID)
+		if len(ycs) == 0 {
+			prerespond("🔺No Youtube channels are tracked on this server\n" + ytChannelsUsage)
+			return
+		}
+
+		resp := "🔺Tracked Youtube channels:"
+		for _, yc := range ycs {
+			resp += fmt.Sprintf("\n**%s** <https://www.youtube.com/channel/%s>", yc.Name, yc.ChannelID)
+		}
+		prerespond(resp)
+		return
+	}
+
+	switch ctx.Fields[1] {
+	case "add":
+		if len(ctx.Fields) < 3 {
+			prerespond(ytChannelsUsage)
+			return
+		}
+		msg := prerespond("🔺Looking up channel...")
+		respond := GetEditor(ds, msg)
+
+		ytSvc, err := youtubesvc.NewYoutubeService(context.Background())
+		if err != nil {
+			respond("🔺Could not connect to Youtube, " + err.Error())
+			return
+		}
+		channelID := ctx.Fields[2]
+		title, err := ytSvc.GetChannelTitle(channelID)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Couldn't find the channel `%s`: %s", channelID, err))
+			return
+		}
+
+		yc := config.YoutubeChannelConfig{
+			ChannelID: channelID,
+			Name:      title,
+		}
+		if len(ctx.Fields) > 3 {
+			yc.Name = strings.Join(ctx.Fields[3:], " ")
+		}
+
+		err = config.AddYoutubeChannel(dm.GuildID, yc)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to add channel: %s", err))
+			return
+		}
+		respond(fmt.Sprintf("🔺Now tracking streams of **%s**", yc.Name))
+	case "remove":
+		if len(ctx.Fields) < 3 {
+			prerespond(ytChannelsUsage)
+			return
+		}
+
+		err := config.RemoveYoutubeChannel(dm.GuildID, ctx.Fields[2])
+		if err != nil {
+			prerespond(fmt.Sprintf("🔺Failed to remove channel: %s", err))
+			return
+		}
+		prerespond("🔺Channel removed from the schedule")
+	default:
+		prerespond(ytChannelsUsage)
+	}
+}
@@ -3,18 +3,21 @@ package mux
 import (
 	"context"
 	"fmt"
+	"log"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/w8kerr/delubot/config"
 	"github.com/w8kerr/delubot/mongo"
 	"github.com/w8kerr/delubot/youtubesvc"
 )
 
-var YTSvc *youtubesvc.UserYoutubeService
-
 func (m *Mux) YoutubeCopy(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
 	respond := GetResponder(ds, dm)
 
@@ -22,12 +25,21 @@ func (m *Mux) YoutubeCopy(ds *discordgo.Session, dm *discordgo.Message, ctx *Con
 	ctx.Content = strings.TrimSpace(ctx.Content)
 
 	if ctx.Content == "" {
-		respond("🔺Usage: -db ytcopy <youtube video ID or link>")
+		respond("🔺Usage: -db ytcopy <youtube video ID or link> [as <account email>] [prefix]")
 		return
 	}
 
 	parts := strings.Split(ctx.Content, " ")
 	link := parts[0]
+	poster := ""
+	if len(parts) > 2 && parts[1] == "as" {
+		poster = parts[2]
+		parts = parts[2:]
+		if !hasPosterAccount(poster) {
+			respond(fmt.Sprintf("🔺There's no Youtube account %s, see `-db ytaccounts`", poster))
+			return
+		}
+	}
 	prefix := strings.Join(parts[1:], " ")
 
 	if prefix == "" {
@@ -58,12 +70,14 @@ func (m *Mux) YoutubeCopy(ds *discordgo.Session, dm *discordgo.Message, ctx *Con
 
 	// Save the copy pipeline
 	cp := config.CopyPipeline{
+		OID:               bson.NewObjectId(),
 		CreatedAt:         time.Now(),
 		CreatedBy:         dm.Author.ID,
 		CreatedByName:     dm.Author.Username,
-		Type:              "youtube",
+		Type:              config.CopyToYoutube,
 		ChannelID:         dm.ChannelID,
 		Prefix:            prefix,
+		PosterEmail:       poster,
 		YoutubeVideoID:    videoID,
 		YoutubeVideoTitle: videoTitle,
 		YoutubeLivechatID: livechatID,
@@ -74,12 +88,10 @@ func (m *Mux) YoutubeCopy(ds *discordgo.Session, dm *discordgo.Message, ctx *Con
 		return
 	}
 
-	if YTSvc == nil {
-		YTSvc, err = youtubesvc.NewUserYoutubeService(config.YoutubeOauthToken, &config.YoutubeRefreshToken)
-		if err != nil {
-			fmt.Println("Error", err)
-		}
+	if len(config.YoutubeCredentials) == 0 {
+		respond("🔺No Youtube accounts are linked, messages can't be copied yet")
 	}
+	m.copyQueue(ds, cp)
 
 	ds.ChannelMessageSendEmbed(dm.ChannelID, StartCopyEmbed(cp))
 }
@@ -91,7 +103,7 @@ func (m *Mux) YoutubeCopy(ds *discordgo.Session, dm *discordgo.Message, ctx *Con
 func (m *Mux) EndYoutubeCopy(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
 	respond := GetResponder(ds, dm)
 
-	_, err := config.RemoveCopyPipeline(dm.ChannelID)
+	_, err := config.RemoveCopyPipeline(dm.ChannelID, config.CopyToYoutube)
 	if err != nil {
 		respond(fmt.Sprintf("🔺Failed to initialize copy pipeline: %s", err))
 		return
@@ -121,16 +133,89 @@ func StartCopyEmbed(cp config.CopyPipeline) *discordgo.MessageEmbed {
 	return embed
 }
 
-func (m *Mux) CopyMessageToYoutube(ds *discordgo.Session, dm *discordgo.Message, cp config.CopyPipeline) {
-	respond := GetResponder(ds, dm)
+// The longest message Youtube live chat accepts, in characters
+var youtubeChatLimit = 200
+
+// How long to wait between chat messages, Youtube rejects bursts
+var copySendInterval = 1500 * time.Millisecond
+
+// How often a pipeline checks whether its stream is still live
+var copyLiveCheckInterval = 2 * time.Minute
+
+// How many times a chat message is tried before it's given up on
+var copyMaxAttempts = 5
+
+// How many chat messages can wait to be sent before new ones are dropped
+var copyQueueSize = 100
+
+// How long a chat message waits for the ones sent before it, they're handled concurrently and can be queued out of order
+var copyReorderDelay = 2 * time.Second
+
+type copyMessage struct {
+	ChannelID string
+	MessageID string
+	Part      int
+	Text      string
+	QueuedAt  time.Time
+}
+
+// before whether the message was sent on Discord before another one
+func (cm copyMessage) before(other copyMessage) bool {
+	if cm.MessageID != other.MessageID {
+		// Snowflakes grow with time, a shorter one is older
+		if len(cm.MessageID) != len(other.MessageID) {
+			return len(cm.MessageID) < len(other.MessageID)
+		}
+		return cm.MessageID < other.MessageID
+	}
+	return cm.Part < other.Part
+}
+
+var copyQueues = struct {
+	sync.Mutex
+	queues map[bson.ObjectId]chan copyMessage
+	// How many messages each pipeline has queued or held back for reordering
+	waiting map[bson.ObjectId]int
+}{queues: make(map[bson.ObjectId]chan copyMessage), waiting: make(map[bson.ObjectId]int)}
+
+func hasPosterAccount(email string) bool {
+	for _, cred := range config.YoutubeCredentials {
+		if cred.Email == email {
+			return true
+		}
+	}
+	return false
+}
 
-	var err error
-	if YTSvc == nil {
-		YTSvc, err = youtubesvc.NewUserYoutubeService(config.YoutubeOauthToken, &config.YoutubeRefreshToken)
-		if err != nil {
-			fmt.Println("Error", err)
+// InitCopyPipelines restart the copy pipelines and live chat relays that were running before a restart
+func (m *Mux) InitCopyPipelines(ds *discordgo.Session) {
+	for _, cp := range config.CopyPipelines {
+		switch cp.Type {
+		case config.CopyToYoutube:
+			m.copyQueue(ds, cp)
+		case config.CopyFromYoutube:
+			go m.RunYoutubeRelay(ds, cp)
 		}
 	}
+}
+
+// copyQueue the queue of a pipeline's chat messages, starting the pipeline's sender if it isn't running
+func (m *Mux) copyQueue(ds *discordgo.Session, cp config.CopyPipeline) chan copyMessage {
+	copyQueues.Lock()
+	defer copyQueues.Unlock()
+
+	queue, ok := copyQueues.queues[cp.OID]
+	if !ok {
+		queue = make(chan copyMessage, copyQueueSize)
+		copyQueues.queues[cp.OID] = queue
+		go m.runCopyQueue(ds, cp, queue)
+	}
+	return queue
+}
+
+// CopyMessageToYoutube queue a Discord message to be sent to the pipeline's live chat
+func (m *Mux) CopyMessageToYoutube(ds *discordgo.Session, dm *discordgo.Message, cp config.CopyPipeline) {
+	respond := GetResponder(ds, dm)
 
 	text, err := dm.ContentWithMoreMentionsReplaced(ds)
 	if err != nil {
@@ -143,35 +228,187 @@ func (m *Mux) CopyMessageToYoutube(ds *discordgo.Session, dm *discordgo.Message,
 		return
 	}
 
-	characterLimit := 200 - len(cp.Prefix)
+	parts := youtubesvc.SplitChatText(text, youtubeChatLimit-utf8.RuneCountInString(cp.Prefix))
+	queue := m.copyQueue(ds, cp)
+	if !reserveCopySlots(cp.OID, len(parts)) {
+		respond("🔺Too many messages are waiting to be copied to Youtube, this one was dropped")
+		return
+	}
+	for i, part := range parts {
+		msg := copyMessage{
+			ChannelID: dm.ChannelID,
+			MessageID: dm.ID,
+			Part:      i,
+			Text:      cp.Prefix + part,
+			QueuedAt:  time.Now(),
+		}
+		select {
+		case queue <- msg:
+		default:
+			// The pipeline's sender stopped while this was being queued
+			return
+		}
+	}
+}
+
+// reserveCopySlots count messages against a pipeline's limit, returning false if they don't fit
+func reserveCopySlots(oid bson.ObjectId, n int) bool {
+	copyQueues.Lock()
+	defer copyQueues.Unlock()
+
+	if copyQueues.waiting[oid]+n > copyQueueSize {
+		return false
+	}
+	copyQueues.waiting[oid] += n
+	return true
+}
+
+// releaseCopySlot free up the slot of a message that left the queue
+func releaseCopySlot(oid bson.ObjectId) {
+	copyQueues.Lock()
+	defer copyQueues.Unlock()
+
+	if copyQueues.waiting[oid] > 0 {
+		copyQueues.waiting[oid]--
+	}
+}
+
+// runCopyQueue send a pipeline's chat messages one at a time in the order they were sent on Discord,
+// until the pipeline is removed or its stream ends
+func (m *Mux) runCopyQueue(ds *discordgo.Session, cp config.CopyPipeline, queue chan copyMessage) {
+	defer func() {
+		copyQueues.Lock()
+		delete(copyQueues.queues, cp.OID)
+		delete(copyQueues.waiting, cp.OID)
+		copyQueues.Unlock()
+	}()
 
-	words := strings.Split(text, " ")
+	liveCheck := time.NewTicker(copyLiveCheckInterval)
+	defer liveCheck.Stop()
 
-	output := ""
-	for i := 0; i < len(words); i++ {
-		nextWord := words[i]
-		if output != "" {
-			nextWord = " " + nextWord
+	// Waiting messages sorted by when they were sent, each is held for copyReorderDelay
+	pending := []copyMessage{}
+	for {
+		var due <-chan time.Time
+		if len(pending) > 0 {
+			due = time.After(time.Until(pending[0].QueuedAt.Add(copyReorderDelay)))
 		}
-		if len(output)+len(nextWord) > characterLimit {
-			_, err = YTSvc.SendChatMessage(cp.YoutubeLivechatID, cp.Prefix+output)
-			fmt.Println("Sent youtube message,", cp.Prefix+output)
-			if err != nil {
-				respond(fmt.Sprintf("🔺Failed to copy message to Youtube: %s", err))
+
+		select {
+		case msg := <-queue:
+			pending = insertCopyMessage(pending, msg)
+			// Sorted, an earlier message may still be waiting in the queue
+			for len(queue) > 0 {
+				pending = insertCopyMessage(pending, <-queue)
+			}
+		case <-due:
+			msg := pending[0]
+			pending = pending[1:]
+			releaseCopySlot(cp.OID)
+			if !config.HasCopyPipeline(cp.OID) {
+				return
+			}
+			if sendCopyMessage(ds, cp, msg) {
+				stopYoutubeCopy(ds, cp)
 				return
 			}
-			time.Sleep(1 * time.Second)
-			output = nextWord
-		} else {
-			output += nextWord
+			time.Sleep(copySendInterval)
+		case <-liveCheck.C:
+			if !config.HasCopyPipeline(cp.OID) {
+				return
+			}
+			if !copyChatLive(cp) {
+				stopYoutubeCopy(ds, cp)
+				return
+			}
+		}
+	}
+}
+
+// insertCopyMessage add a message to the waiting ones, keeping them in the order they were sent
+func insertCopyMessage(pending []copyMessage, msg copyMessage) []copyMessage {
+	i := sort.Search(len(pending), func(i int) bool {
+		return msg.before(pending[i])
+	})
+	pending = append(pending, copyMessage{})
+	copy(pending[i+1:], pending[i:])
+	pending[i] = msg
+	return pending
+}
+
+// sendCopyMessage send one chat message, retrying what can be retried and failing over to other accounts.
+// Returns whether the chat has ended.
+func sendCopyMessage(ds *discordgo.Session, cp config.CopyPipeline, msg copyMessage) bool {
+	backoff := 2 * time.Second
+	var pa *youtubesvc.PosterAccount
+	for attempt := 1; ; attempt++ {
+		if pa == nil {
+			var err error
+			pa, err = youtubesvc.PickPoster(cp.YoutubeLivechatID, cp.PosterEmail)
+			if err != nil {
+				if err != youtubesvc.ErrNoPoster || attempt >= copyMaxAttempts {
+					reportCopyFailure(ds, msg, err)
+					return false
+				}
+				time.Sleep(backoff)
+				backoff *= 2
+				continue
+			}
+		}
+
+		err := pa.SendChatMessage(cp.YoutubeLivechatID, msg.Text)
+		if err == nil {
+			return false
+		}
+
+		kind := youtubesvc.ClassifySendError(err)
+		switch kind {
+		case youtubesvc.SendEnded:
+			return true
+		case youtubesvc.SendDrop:
+			reportCopyFailure(ds, msg, err)
+			return false
+		}
+
+		pa.Failed(cp.YoutubeLivechatID, kind, err)
+		if attempt >= copyMaxAttempts {
+			reportCopyFailure(ds, msg, err)
+			return false
+		}
+
+		if kind == youtubesvc.SendRetry {
+			// Most likely a hiccup, the same account tries again after a while
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
 		}
+		// The account rests, signs in again or is left out of this chat, the next attempt goes to another one
+		pa = nil
 	}
-	_, err = YTSvc.SendChatMessage(cp.YoutubeLivechatID, cp.Prefix+output)
-	fmt.Println("Sent youtube message,", cp.Prefix+output)
+}
+
+func reportCopyFailure(ds *discordgo.Session, msg copyMessage, err error) {
+	log.Printf("Failed to copy message %s to Youtube, %s", msg.MessageID, err)
+	ds.MessageReactionAdd(msg.ChannelID, msg.MessageID, "⚠")
+	ds.ChannelMessageSend(msg.ChannelID, fmt.Sprintf("🔺Failed to copy message to Youtube: %s", err))
+}
+
+// copyChatLive whether the pipeline's stream still has a live chat. Only a definite answer from Youtube counts as ended.
+func copyChatLive(cp config.CopyPipeline) bool {
+	svc, err := youtubesvc.NewYoutubeService(context.Background())
 	if err != nil {
-		respond(fmt.Sprintf("🔺Failed to copy message to Youtube: %s", err))
-		return
+		return true
+	}
+	_, _, err = svc.GetLivechatID(cp.YoutubeVideoID)
+	return err != youtubesvc.ErrNotLive && err != youtubesvc.ErrNoVideo
+}
+
+func stopYoutubeCopy(ds *discordgo.Session, cp config.CopyPipeline) {
+	err := config.RemoveCopyPipelineByID(cp.OID)
+	if err != nil {
+		log.Printf("Failed to remove the copy pipeline of %s, %s", cp.YoutubeVideoID, err)
 	}
+	ds.ChannelMessageSend(cp.ChannelID, fmt.Sprintf("🔺\"%s\" is no longer live, stopped copying messages to its chat", cp.YoutubeVideoTitle))
 }
 
 func RemoveUnwantedElements(text string) (string, bool) {
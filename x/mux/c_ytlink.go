@@ -0,0 +1,86 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+var youtubeLinkUsage = "🔺Usage:\n" +
+	"`-db ytlink` link a Youtube account for copying messages, the instructions are sent in DMs\n" +
+	"`-db ytlink remove <account email>`"
+
+// YoutubeLink link a Youtube account with Google's device flow, so nobody has to put tokens in the database by hand
+func (m *Mux) YoutubeLink(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) > 1 {
+		if ctx.Fields[1] != "remove" || len(ctx.Fields) != 3 {
+			respond(youtubeLinkUsage)
+			return
+		}
+
+		removed, err := config.RemoveYoutubeCredential(ctx.Fields[2])
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to remove the account: %s", err))
+			return
+		}
+		if !removed {
+			respond(fmt.Sprintf("🔺There's no Youtube account %s, see `-db ytaccounts`", ctx.Fields[2]))
+			return
+		}
+		youtubesvc.ReloadPosters()
+		respond(fmt.Sprintf("🔺Removed %s, revoke the bot's access at <https://myaccount.google.com/permissions> too", ctx.Fields[2]))
+		return
+	}
+
+	if !youtubesvc.CanEncryptTokens() {
+		respond(fmt.Sprintf("🔺Accounts can't be linked until `%s` is set, tokens are only stored encrypted", youtubesvc.TokenKeyEnv))
+		return
+	}
+
+	dc, err := youtubesvc.RequestDeviceCode()
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to start linking: %s", err))
+		return
+	}
+
+	dmChannel, err := ds.UserChannelCreate(dm.Author.ID)
+	if err != nil {
+		respond(fmt.Sprintf("🔺I can't DM you: %s", err))
+		return
+	}
+	_, err = ds.ChannelMessageSend(dmChannel.ID, fmt.Sprintf("🔺To link a Youtube account, go to <%s> and enter the code **%s**\nSign in with the account messages should be posted as. The code expires in %d minutes.", dc.VerificationURL, dc.UserCode, dc.ExpiresIn/60))
+	if err != nil {
+		respond(fmt.Sprintf("🔺I can't DM you: %s", err))
+		return
+	}
+	if !ctx.IsPrivate {
+		respond("🔺Check your DMs")
+	}
+
+	go func() {
+		tok, email, err := youtubesvc.PollDeviceToken(context.Background(), dc)
+		if err != nil {
+			ds.ChannelMessageSend(dmChannel.ID, fmt.Sprintf("🔺The account wasn't linked, %s", err))
+			return
+		}
+
+		cred, err := youtubesvc.LinkedCredential(email, tok, dm.Author.ID)
+		if err == nil {
+			err = config.SetYoutubeCredential(cred)
+		}
+		if err != nil {
+			log.Printf("Failed to store the linked Youtube account %s, %s", email, err)
+			ds.ChannelMessageSend(dmChannel.ID, fmt.Sprintf("🔺Failed to store the account: %s", err))
+			return
+		}
+		youtubesvc.ReloadPosters()
+
+		ds.ChannelMessageSend(dmChannel.ID, fmt.Sprintf("🔺Linked %s, it will take turns copying messages to Youtube", email))
+	}()
+}
@@ -0,0 +1,33 @@
+package mux
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+func (m *Mux) YoutubeQuota(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	usage := youtubesvc.QuotaToday()
+
+	methods := []string{}
+	for method := range usage.ByMethod {
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return usage.ByMethod[methods[i]] > usage.ByMethod[methods[j]]
+	})
+
+	resp := fmt.Sprintf("```Youtube quota for %s (Pacific time)", usage.Day)
+	resp += fmt.Sprintf("\nSpent:          %d / %d units (%.1f%%)", usage.Units, youtubesvc.DailyQuota, float64(usage.Units)*100/youtubesvc.DailyQuota)
+	for _, method := range methods {
+		resp += fmt.Sprintf("\n  %-22s %d", method, usage.ByMethod[method])
+	}
+	resp += fmt.Sprintf("\nCached videos:  %d", youtubesvc.CachedVideos())
+	resp += "```"
+
+	respond(resp)
+}
@@ -0,0 +1,262 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+var youtubeRelayUsage = "🔺Usage:\n" +
+	"`-db ytrelay` list the relays into this channel\n" +
+	"`-db ytrelay <youtube video ID or link> [owner] [moderators] [members] [verified] [superchats] [keywords...]`\n" +
+	"  relays messages from authors with any of the roles or containing any of the keywords, owner, moderators and superchats if none are given\n" +
+	"`-db ytrelay stop`"
+
+var defaultRelayRoles = []string{youtubesvc.ChatRoleOwner, youtubesvc.ChatRoleModerator, youtubesvc.ChatRoleSuperChat}
+
+// Each poll costs quota, Youtube asks for polls every few seconds which would use up the day's quota within hours
+var relayMinInterval = 10 * time.Second
+
+// How many polls in a row can fail before a relay gives up
+var relayMaxFailures = 5
+
+var runningRelays = struct {
+	sync.Mutex
+	oids map[bson.ObjectId]bool
+}{oids: make(map[bson.ObjectId]bool)}
+
+// YoutubeRelay relay a Youtube live chat into the channel
+func (m *Mux) YoutubeRelay(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) < 2 {
+		relays := []string{}
+		for _, cp := range config.GetCopyPipelines(dm.ChannelID) {
+			if cp.Type == config.CopyFromYoutube {
+				relays = append(relays, fmt.Sprintf("\"%s\", relaying %s", cp.YoutubeVideoTitle, strings.Join(append(cp.RelayRoles, cp.RelayKeywords...), ", ")))
+			}
+		}
+		if len(relays) == 0 {
+			respond("🔺No live chats are relayed into this channel\n" + youtubeRelayUsage)
+			return
+		}
+		respond("🔺Relaying into this channel:\n" + strings.Join(relays, "\n"))
+		return
+	}
+
+	if ctx.Fields[1] == "stop" {
+		removed, err := config.RemoveCopyPipeline(dm.ChannelID, config.CopyFromYoutube)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to stop the relay: %s", err))
+			return
+		}
+		if len(removed) == 0 {
+			respond("🔺No live chats are relayed into this channel")
+			return
+		}
+		respond("🔺Live chat relay stopped")
+		return
+	}
+
+	roles := []string{}
+	keywords := []string{}
+	for _, arg := range ctx.Fields[2:] {
+		if isChatRole(arg) {
+			roles = append(roles, arg)
+		} else {
+			keywords = append(keywords, arg)
+		}
+	}
+	if len(roles) == 0 && len(keywords) == 0 {
+		roles = defaultRelayRoles
+	}
+
+	svc, err := youtubesvc.NewYoutubeService(context.Background())
+	if err != nil {
+		respond(fmt.Sprintf("🔺Could not connect to Youtube: %s", err))
+		return
+	}
+
+	videoID, err := svc.ParseVideoID(ctx.Fields[1])
+	if err != nil {
+		respond("🔺That doesn't look like a Youtube link or video ID to me!\n" + youtubeRelayUsage)
+		return
+	}
+
+	livechatID, videoTitle, err := svc.GetLivechatID(videoID)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to connect to live chat: %s", err))
+		return
+	}
+
+	cp := config.CopyPipeline{
+		OID:               bson.NewObjectId(),
+		CreatedAt:         time.Now(),
+		CreatedBy:         dm.Author.ID,
+		CreatedByName:     dm.Author.Username,
+		Type:              config.CopyFromYoutube,
+		ChannelID:         dm.ChannelID,
+		YoutubeVideoID:    videoID,
+		YoutubeVideoTitle: videoTitle,
+		YoutubeLivechatID: livechatID,
+		RelayRoles:        roles,
+		RelayKeywords:     keywords,
+	}
+	err = config.SetCopyPipeline(cp)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to start the relay: %s", err))
+		return
+	}
+
+	go m.RunYoutubeRelay(ds, cp)
+
+	ds.ChannelMessageSendEmbed(dm.ChannelID, StartRelayEmbed(cp))
+}
+
+func isChatRole(arg string) bool {
+	for _, role := range youtubesvc.ChatRoles {
+		if role == arg {
+			return true
+		}
+	}
+	return false
+}
+
+func StartRelayEmbed(cp config.CopyPipeline) *discordgo.MessageEmbed {
+	relayed := strings.Join(cp.RelayRoles, ", ")
+	if len(cp.RelayKeywords) > 0 {
+		if relayed != "" {
+			relayed += ", "
+		}
+		relayed += "messages containing " + strings.Join(cp.RelayKeywords, ", ")
+	}
+
+	return &discordgo.MessageEmbed{
+		Color:       3066993,
+		Description: fmt.Sprintf("Now relaying the live chat of \"%s\" into this channel\nRelaying: %s\nType `-db ytrelay stop` to end", cp.YoutubeVideoTitle, relayed),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: cp.CreatedByName,
+		},
+		Timestamp: cp.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// RunYoutubeRelay poll a live chat and post the messages that pass the filter, until the chat ends or the relay is stopped
+func (m *Mux) RunYoutubeRelay(ds *discordgo.Session, cp config.CopyPipeline) {
+	runningRelays.Lock()
+	if runningRelays.oids[cp.OID] {
+		runningRelays.Unlock()
+		return
+	}
+	runningRelays.oids[cp.OID] = true
+	runningRelays.Unlock()
+	defer func() {
+		runningRelays.Lock()
+		delete(runningRelays.oids, cp.OID)
+		runningRelays.Unlock()
+	}()
+
+	svc, err := youtubesvc.NewYoutubeService(context.Background())
+	if err != nil {
+		log.Printf("Could not connect to Youtube for the relay of %s, %s", cp.YoutubeVideoID, err)
+		return
+	}
+
+	filter := youtubesvc.ChatFilter{
+		Roles:    cp.RelayRoles,
+		Keywords: cp.RelayKeywords,
+	}
+	pageToken := ""
+	failures := 0
+	for config.HasCopyPipeline(cp.OID) {
+		page, err := svc.ListChatMessages(cp.YoutubeLivechatID, pageToken)
+
+		// The first page is the chat's backlog, which was already seen on Youtube
+		if pageToken != "" {
+			relayChatMessages(ds, cp.ChannelID, page.Messages, filter)
+		}
+
+		if err == youtubesvc.ErrChatEnded {
+			stopYoutubeRelay(ds, cp, fmt.Sprintf("🔺The live chat of \"%s\" has ended, stopped relaying", cp.YoutubeVideoTitle))
+			return
+		}
+		if err != nil {
+			failures++
+			log.Printf("Failed to poll the live chat of %s, %s", cp.YoutubeVideoID, err)
+			if failures >= relayMaxFailures {
+				stopYoutubeRelay(ds, cp, fmt.Sprintf("🔺Stopped relaying \"%s\", Youtube keeps failing: %s", cp.YoutubeVideoTitle, err))
+				return
+			}
+			time.Sleep(time.Duration(failures) * relayMinInterval)
+			continue
+		}
+		failures = 0
+
+		pageToken = page.NextPageToken
+		interval := page.PollingInterval
+		if interval < relayMinInterval {
+			interval = relayMinInterval
+		}
+		time.Sleep(interval)
+	}
+}
+
+func stopYoutubeRelay(ds *discordgo.Session, cp config.CopyPipeline, reason string) {
+	err := config.RemoveCopyPipelineByID(cp.OID)
+	if err != nil {
+		log.Printf("Failed to remove the relay of %s, %s", cp.YoutubeVideoID, err)
+	}
+	ds.ChannelMessageSend(cp.ChannelID, reason)
+}
+
+var chatMarkdownReplacer = strings.NewReplacer("\\", "\\\\", "*", "\\*", "_", "\\_", "~", "\\~", "`", "\\`", "|", "\\|", ">", "\\>")
+
+// relayChatMessages post the messages that pass the filter, as few Discord messages as possible
+func relayChatMessages(ds *discordgo.Session, channelID string, msgs []youtubesvc.ChatMessage, filter youtubesvc.ChatFilter) {
+	lines := []string{}
+	for _, msg := range msgs {
+		if !filter.Matches(msg) {
+			continue
+		}
+
+		line := fmt.Sprintf("**%s**%s: %s", chatMarkdownReplacer.Replace(msg.AuthorName), msg.Badges(), chatMarkdownReplacer.Replace(msg.Text))
+		if msg.SuperChat != "" {
+			line = fmt.Sprintf("💴 **%s**%s (%s): %s", chatMarkdownReplacer.Replace(msg.AuthorName), msg.Badges(), msg.SuperChat, chatMarkdownReplacer.Replace(msg.Text))
+		}
+		lines = append(lines, line)
+	}
+
+	content := ""
+	send := func() {
+		if content == "" {
+			return
+		}
+		_, err := ds.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content: content,
+			// Chat messages can contain anything, they should never ping
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			log.Printf("Failed to relay live chat messages to %s, %s", channelID, err)
+		}
+		content = ""
+	}
+	for _, line := range lines {
+		if len(content)+len(line)+1 > 2000 {
+			send()
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += line
+	}
+	send()
+}
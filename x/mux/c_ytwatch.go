@@ -0,0 +1,147 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/youtubesvc"
+)
+
+var youtubeWatchUsage = "🔺Usage:\n" +
+	"`-db ytwatch` list the videos whose comments are posted in this channel\n" +
+	"`-db ytwatch <youtube video ID or link> [talent] [threads] [keywords...]`\n" +
+	"  posts new comments, only the channel owner's with `talent`, no replies with `threads`, only ones containing a keyword if any are given\n" +
+	"`-db ytunwatch <youtube video ID or link>`"
+
+// YoutubeWatch post new comments on a Youtube video in the channel
+func (m *Mux) YoutubeWatch(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	if len(ctx.Fields) < 2 {
+		wvs, err := youtubesvc.ChannelWatches(db, dm.ChannelID)
+		if err != nil {
+			respond(fmt.Sprintf("🔺Failed to get the watched videos: %s", err))
+			return
+		}
+		if len(wvs) == 0 {
+			respond("🔺No videos are watched in this channel\n" + youtubeWatchUsage)
+			return
+		}
+
+		resp := "🔺Watching the comments of:"
+		for _, wv := range wvs {
+			resp += fmt.Sprintf("\n\"%s\" <https://www.youtube.com/watch?v=%s>%s", wv.VideoTitle, wv.VideoID, watchFilterText(wv))
+		}
+		respond(resp)
+		return
+	}
+
+	svc, err := youtubesvc.NewYoutubeService(context.Background())
+	if err != nil {
+		respond(fmt.Sprintf("🔺Could not connect to Youtube: %s", err))
+		return
+	}
+	videoID, err := svc.ParseVideoID(ctx.Fields[1])
+	if err != nil {
+		respond("🔺That doesn't look like a Youtube link or video ID to me!\n" + youtubeWatchUsage)
+		return
+	}
+	snippet, err := svc.GetVideoSnippet(videoID)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Couldn't find the video: %s", err))
+		return
+	}
+
+	wv := models.WatchedVideo{
+		VideoID:          videoID,
+		VideoTitle:       snippet.Title,
+		YoutubeChannelID: snippet.ChannelId,
+		ChannelID:        dm.ChannelID,
+		Keywords:         []string{},
+		CreatedBy:        dm.Author.ID,
+		CreatedAt:        time.Now(),
+	}
+	for _, arg := range ctx.Fields[2:] {
+		switch arg {
+		case "talent":
+			wv.TalentOnly = true
+		case "threads":
+			wv.NewThreadsOnly = true
+		default:
+			wv.Keywords = append(wv.Keywords, arg)
+		}
+	}
+
+	wv, err = youtubesvc.WatchVideo(db, wv)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to watch the video: %s", err))
+		return
+	}
+
+	if wv.LastScan.IsZero() {
+		respond(fmt.Sprintf("🔺Watching the comments of \"%s\"%s\nComments from now on will be posted here", wv.VideoTitle, watchFilterText(wv)))
+		return
+	}
+	respond(fmt.Sprintf("🔺Updated the watch on \"%s\"%s", wv.VideoTitle, watchFilterText(wv)))
+}
+
+// YoutubeUnwatch stop posting comments on a Youtube video in the channel
+func (m *Mux) YoutubeUnwatch(ds *discordgo.Session, dm *discordgo.Message, ctx *Context) {
+	respond := GetResponder(ds, dm)
+
+	if len(ctx.Fields) != 2 {
+		respond(youtubeWatchUsage)
+		return
+	}
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	// No need to ask Youtube to find the ID in a link
+	videoID, err := (&youtubesvc.YoutubeService{}).ParseVideoID(ctx.Fields[1])
+	if err != nil {
+		respond("🔺That doesn't look like a Youtube link or video ID to me!\n" + youtubeWatchUsage)
+		return
+	}
+
+	removed, err := youtubesvc.UnwatchVideo(db, dm.ChannelID, videoID)
+	if err != nil {
+		respond(fmt.Sprintf("🔺Failed to stop watching the video: %s", err))
+		return
+	}
+	if !removed {
+		respond("🔺That video isn't watched in this channel")
+		return
+	}
+	respond("🔺Stopped watching the video")
+}
+
+func watchFilterText(wv models.WatchedVideo) string {
+	filters := []string{}
+	if wv.TalentOnly {
+		filters = append(filters, "channel owner only")
+	}
+	if wv.NewThreadsOnly {
+		filters = append(filters, "no replies")
+	}
+	if len(wv.Keywords) > 0 {
+		filters = append(filters, "containing "+strings.Join(wv.Keywords, ", "))
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(filters, ", ") + ")"
+}
@@ -13,15 +13,20 @@ import (
 
 // IsModerator check if a user is a moderator
 func IsModerator(ds *discordgo.Session, dm *discordgo.MessageCreate) bool {
-	member, err := ds.GuildMember(dm.GuildID, dm.Author.ID)
+	return IsGuildModerator(ds, dm.GuildID, dm.Author.ID)
+}
+
+// IsGuildModerator check if a user is a moderator of a guild
+func IsGuildModerator(ds *discordgo.Session, guildID, userID string) bool {
+	member, err := ds.GuildMember(guildID, userID)
 	if err != nil {
 		log.Printf("error getting user's member, %s", err)
 		return false
 	}
 
-	guildMods, ok := config.ModeratorRoles[dm.GuildID]
+	guildMods, ok := config.ModeratorRoles[guildID]
 	if !ok {
-		log.Printf("Could not find guild roles, %s", dm.GuildID)
+		log.Printf("Could not find guild roles, %s", guildID)
 		return false
 	}
 
@@ -0,0 +1,215 @@
+package mux
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+)
+
+// MessageLog One create, edit or delete of a message.
+// Logs expire at ExpiresAt if the channel or guild has a retention set, see LogRetention.
+type MessageLog struct {
+	GuildID         string `bson:"guildid,omitempty"`
+	ChannelID       string `bson:"channelid,omitempty"`
+	ChannelName     string
+	ThreadID        string `bson:"threadid,omitempty"`
+	UserID          string `bson:"userid,omitempty"`
+	UserName        string
+	Content         string
+	OriginalContent string                         `bson:"originalContent,omitempty"`
+	Attachments     []*discordgo.MessageAttachment `bson:"attachments,omitempty"`
+	ReplyToID       string                         `bson:"replytoid,omitempty"`
+	MessageID       string
+	Action          string
+	Time            time.Time
+	ExpiresAt       time.Time `bson:"expiresat,omitempty"`
+}
+
+// Threads are channels of their own, under the channel they were started in
+const (
+	channelTypeNewsThread    = 10
+	channelTypePublicThread  = 11
+	channelTypePrivateThread = 12
+)
+
+// logChannel the channel a message was sent in, and its thread if it was sent in one
+func logChannel(ds *discordgo.Session, channelID string) (string, string, string) {
+	channel, err := ds.State.Channel(channelID)
+	if err != nil {
+		channel, err = ds.Channel(channelID)
+	}
+	if err != nil {
+		return channelID, "", ""
+	}
+
+	switch channel.Type {
+	case channelTypeNewsThread, channelTypePublicThread, channelTypePrivateThread:
+		return channel.ParentID, channel.ID, channel.Name
+	}
+	return channel.ID, "", channel.Name
+}
+
+// logExpiry when a log of a message in the channel should be removed, never if no retention is set
+func logExpiry(guildID, channelID string, now time.Time) time.Time {
+	days := config.LogRetentionDays(guildID, channelID)
+	if days <= 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, days)
+}
+
+// backfillLogExpiry give the logs of a channel or guild that were kept forever an expiry from a new retention.
+// A guild's retention leaves out channels with one of their own.
+func backfillLogExpiry(ds *discordgo.Session, db *mgo.Database, guildID, channelID string, days int) (int, error) {
+	query := bson.M{"expiresat": bson.M{"$exists": false}}
+	if channelID != "" {
+		query["channelid"] = channelID
+	} else {
+		query["guildid"] = guildID
+		var channels []*discordgo.Channel
+		guild, err := ds.State.Guild(guildID)
+		if err == nil {
+			channels = guild.Channels
+		} else {
+			channels, err = ds.GuildChannels(guildID)
+			if err != nil {
+				return 0, err
+			}
+		}
+		own := []string{}
+		for _, channel := range channels {
+			if _, ok := config.GetLogRetention(channel.ID); ok {
+				own = append(own, channel.ID)
+			}
+		}
+		query["channelid"] = bson.M{"$nin": own}
+	}
+
+	logCol := db.C("message_logs")
+	iter := logCol.Find(query).Select(bson.M{"_id": 1, "time": 1}).Iter()
+	ml := struct {
+		ID   bson.ObjectId `bson:"_id"`
+		Time time.Time     `bson:"time"`
+	}{}
+	count := 0
+	for iter.Next(&ml) {
+		err := logCol.UpdateId(ml.ID, bson.M{"$set": bson.M{"expiresat": ml.Time.AddDate(0, 0, days)}})
+		if err != nil {
+			iter.Close()
+			return count, err
+		}
+		count++
+	}
+	return count, iter.Close()
+}
+
+// lastMessageLog the latest log of a message, with the author and content it had last
+func lastMessageLog(db *mgo.Database, messageID string) (MessageLog, error) {
+	ml := MessageLog{}
+	err := db.C("message_logs").Find(bson.M{"messageid": messageID}).Sort("-time").One(&ml)
+	return ml, err
+}
+
+func (m *Mux) LogMessageCreate(db *mgo.Database, ds *discordgo.Session, mc *discordgo.MessageCreate, channelName *string) {
+	channelID, threadID, name := logChannel(ds, mc.ChannelID)
+	if channelName != nil {
+		name = *channelName
+	}
+
+	now := time.Now()
+	ml := MessageLog{
+		GuildID:     mc.GuildID,
+		ChannelID:   channelID,
+		ChannelName: name,
+		ThreadID:    threadID,
+		UserID:      mc.Author.ID,
+		UserName:    mc.Author.Username + "#" + mc.Author.Discriminator,
+		Content:     mc.Content,
+		Attachments: mc.Attachments,
+		MessageID:   mc.ID,
+		Action:      "create",
+		Time:        now,
+		ExpiresAt:   logExpiry(mc.GuildID, channelID, now),
+	}
+	if mc.MessageReference != nil {
+		ml.ReplyToID = mc.MessageReference.MessageID
+	}
+
+	err := db.C("message_logs").Insert(ml)
+	if err != nil {
+		log.Printf("Failed to log message %s, %s", mc.ID, err)
+	}
+}
+
+// LogMessageUpdate log the new content of an edited message, next to what it said before
+func (m *Mux) LogMessageUpdate(db *mgo.Database, ds *discordgo.Session, msg *discordgo.Message) (MessageLog, MessageLog) {
+	previous, err := lastMessageLog(db, msg.ID)
+	if err != nil {
+		fmt.Println("Failed to get edited message: " + err.Error())
+	}
+
+	// Updates without an author only add link previews, the message itself didn't change
+	if msg.Author == nil {
+		return previous, MessageLog{}
+	}
+
+	channelID, threadID, name := logChannel(ds, msg.ChannelID)
+	now := time.Now()
+	ml := MessageLog{
+		GuildID:         msg.GuildID,
+		ChannelID:       channelID,
+		ChannelName:     name,
+		ThreadID:        threadID,
+		UserID:          msg.Author.ID,
+		UserName:        msg.Author.Username + "#" + msg.Author.Discriminator,
+		Content:         msg.Content,
+		OriginalContent: previous.Content,
+		Attachments:     msg.Attachments,
+		ReplyToID:       previous.ReplyToID,
+		MessageID:       msg.ID,
+		Action:          "edit",
+		Time:            now,
+		ExpiresAt:       logExpiry(msg.GuildID, channelID, now),
+	}
+
+	err = db.C("message_logs").Insert(ml)
+	if err != nil {
+		log.Printf("Failed to log edit of message %s, %s", msg.ID, err)
+	}
+	return previous, ml
+}
+
+// LogMessageDelete log that a message was deleted, returning what it said last
+func (m *Mux) LogMessageDelete(db *mgo.Database, ds *discordgo.Session, guildID, channelID, messageID string) MessageLog {
+	deleted, err := lastMessageLog(db, messageID)
+	if err != nil {
+		fmt.Println("Failed to get deleted message: " + err.Error())
+	}
+
+	channelID, threadID, name := logChannel(ds, channelID)
+	now := time.Now()
+	err = db.C("message_logs").Insert(MessageLog{
+		GuildID:     guildID,
+		ChannelID:   channelID,
+		ChannelName: name,
+		ThreadID:    threadID,
+		UserID:      deleted.UserID,
+		UserName:    deleted.UserName,
+		Content:     deleted.Content,
+		Attachments: deleted.Attachments,
+		ReplyToID:   deleted.ReplyToID,
+		MessageID:   messageID,
+		Action:      "delete",
+		Time:        now,
+		ExpiresAt:   logExpiry(guildID, channelID, now),
+	})
+	if err != nil {
+		log.Printf("Failed to log delete of message %s, %s", messageID, err)
+	}
+	return deleted
+}
@@ -0,0 +1,416 @@
+package mux
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/config"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+	"github.com/w8kerr/delubot/utils"
+)
+
+// Discord doesn't allow timeouts longer than this
+var maxTimeout = 28 * 24 * time.Hour
+
+// How often expired mutes and timeouts are lifted
+var muteExpiryInterval = 30 * time.Second
+
+// ErrNoMuteRole the guild has no mute role to mute people with
+var ErrNoMuteRole = errors.New("this server doesn't have a mute role, set one with `-db muterole <role>`")
+
+var caseColors = map[string]int{
+	models.CaseWarn:    15844367,
+	models.CaseMute:    15105570,
+	models.CaseUnmute:  3066993,
+	models.CaseTimeout: 15105570,
+	models.CaseKick:    15158332,
+	models.CaseBan:     10038562,
+}
+
+var casePastTense = map[string]string{
+	models.CaseWarn:    "warned",
+	models.CaseMute:    "muted",
+	models.CaseUnmute:  "unmuted",
+	models.CaseTimeout: "timed out",
+	models.CaseKick:    "kicked",
+	models.CaseBan:     "banned",
+}
+
+// How an action reads in a DM to the person it was taken against, "You were ... <server>"
+var caseVerbs = map[string]string{
+	models.CaseWarn:    "warned in",
+	models.CaseMute:    "muted in",
+	models.CaseUnmute:  "unmuted in",
+	models.CaseTimeout: "timed out in",
+	models.CaseKick:    "kicked from",
+	models.CaseBan:     "banned from",
+}
+
+// ModAction A moderation action to take against someone
+type ModAction struct {
+	GuildID   string
+	Action    string
+	Target    *discordgo.User
+	Moderator *discordgo.User
+	Reason    string
+	Duration  time.Duration
+}
+
+// parseDuration a duration like 30m, 12h, 3d or 1w, only durations above zero that fit are valid
+func parseDuration(value string) (time.Duration, bool) {
+	match := agoRE.FindStringSubmatch(value)
+	if match == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	unit := map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[match[2]]
+	if err != nil || n <= 0 || n > int64(math.MaxInt64/unit) {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// formatDuration a duration the way people write it, like 3d 12h
+func formatDuration(d time.Duration) string {
+	parts := []string{}
+	if days := int(d / (24 * time.Hour)); days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+		d -= time.Duration(days) * 24 * time.Hour
+	}
+	if hours := int(d / time.Hour); hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+		d -= time.Duration(hours) * time.Hour
+	}
+	if minutes := int(d / time.Minute); minutes > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// nextCaseNumber hand out the next case number of a guild
+func nextCaseNumber(db *mgo.Database, guildID string) (int, error) {
+	counter := models.CaseCounter{}
+	_, err := db.C("case_counters").FindId(guildID).Apply(mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"last": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &counter)
+	return counter.Last, err
+}
+
+func guildName(ds *discordgo.Session, guildID string) string {
+	guild, err := ds.State.Guild(guildID)
+	if err != nil {
+		guild, err = ds.Guild(guildID)
+	}
+	if err != nil {
+		return "the server"
+	}
+	return guild.Name
+}
+
+// notifyCase DM the person a case is about, false if they can't be DMed
+func notifyCase(ds *discordgo.Session, mc models.ModCase) bool {
+	channel, err := ds.UserChannelCreate(mc.UserID)
+	if err != nil {
+		return false
+	}
+
+	msg := fmt.Sprintf("You were %s **%s**", caseVerbs[mc.Action], guildName(ds, mc.GuildID))
+	if mc.Duration > 0 {
+		msg += " for " + formatDuration(mc.Duration)
+	}
+	if mc.Reason != "" {
+		msg += "\nReason: " + mc.Reason
+	}
+	_, err = ds.ChannelMessageSend(channel.ID, msg)
+	return err == nil
+}
+
+// notifyCaseFailed take back a DM about a case whose action didn't go through
+func notifyCaseFailed(ds *discordgo.Session, mc models.ModCase) {
+	channel, err := ds.UserChannelCreate(mc.UserID)
+	if err != nil {
+		return
+	}
+
+	msg := fmt.Sprintf("Disregard the last message, you weren't %s **%s** after all", caseVerbs[mc.Action], guildName(ds, mc.GuildID))
+	_, err = ds.ChannelMessageSend(channel.ID, msg)
+	if err != nil {
+		log.Printf("Failed to correct the DM about case %s of %s, %s", mc.Action, mc.UserID, err)
+	}
+}
+
+// applyCase take the action of a case on Discord
+func applyCase(ds *discordgo.Session, mc models.ModCase) error {
+	switch mc.Action {
+	case models.CaseWarn:
+		return nil
+	case models.CaseMute:
+		roleID := config.MuteRole(mc.GuildID)
+		if roleID == "" {
+			return ErrNoMuteRole
+		}
+		return ds.GuildMemberRoleAdd(mc.GuildID, mc.UserID, roleID)
+	case models.CaseUnmute:
+		roleID := config.MuteRole(mc.GuildID)
+		if roleID != "" {
+			err := ds.GuildMemberRoleRemove(mc.GuildID, mc.UserID, roleID)
+			if err != nil {
+				return err
+			}
+		}
+		// Lifting the timeout of someone who doesn't have one doesn't hurt
+		return utils.GuildMemberTimeout(ds, mc.GuildID, mc.UserID, nil)
+	case models.CaseTimeout:
+		until := mc.ExpiresAt
+		return utils.GuildMemberTimeout(ds, mc.GuildID, mc.UserID, &until)
+	case models.CaseKick:
+		return ds.GuildMemberDeleteWithReason(mc.GuildID, mc.UserID, mc.Reason)
+	case models.CaseBan:
+		return ds.GuildBanCreateWithReason(mc.GuildID, mc.UserID, mc.Reason, 0)
+	}
+	return fmt.Errorf("there's no action %s", mc.Action)
+}
+
+// recordCase number a case, save it and post it to the guild's log channel
+func recordCase(db *mgo.Database, ds *discordgo.Session, mc models.ModCase) (models.ModCase, error) {
+	var err error
+	mc.CaseNumber, err = nextCaseNumber(db, mc.GuildID)
+	if err != nil {
+		return mc, err
+	}
+	err = db.C("cases").Insert(mc)
+	if err != nil {
+		return mc, err
+	}
+
+	if logChannel, ok := config.LogChannels[mc.GuildID]; ok {
+		_, err := ds.ChannelMessageSendEmbed(logChannel, caseEmbed(mc))
+		if err != nil {
+			log.Printf("Failed to post case %d of %s to the log channel, %s", mc.CaseNumber, mc.GuildID, err)
+		}
+	}
+	return mc, nil
+}
+
+// Moderate take a moderation action against someone, tell them why, and record it as a case
+func (m *Mux) Moderate(db *mgo.Database, ds *discordgo.Session, action ModAction) (models.ModCase, error) {
+	now := time.Now()
+	mc := models.ModCase{
+		GuildID:       action.GuildID,
+		Action:        action.Action,
+		UserID:        action.Target.ID,
+		UserName:      action.Target.String(),
+		ModeratorID:   action.Moderator.ID,
+		ModeratorName: action.Moderator.String(),
+		Reason:        action.Reason,
+		Duration:      action.Duration,
+		CreatedAt:     now,
+	}
+	if mc.Duration > 0 {
+		mc.ExpiresAt = now.Add(mc.Duration)
+	}
+	// Mutes and timeouts are in effect until they expire or are lifted
+	mc.Active = mc.Action == models.CaseMute || mc.Action == models.CaseTimeout
+
+	// Once they're gone they may not share a server with the bot anymore, so they're told first
+	leaving := mc.Action == models.CaseKick || mc.Action == models.CaseBan
+	if leaving {
+		mc.Notified = notifyCase(ds, mc)
+	}
+
+	err := applyCase(ds, mc)
+	if err != nil {
+		if leaving && mc.Notified {
+			notifyCaseFailed(ds, mc)
+		}
+		return mc, err
+	}
+
+	if !leaving {
+		mc.Notified = notifyCase(ds, mc)
+	}
+
+	// A new mute or timeout replaces the one before it, an unmute lifts both
+	lifts := []string{}
+	switch mc.Action {
+	case models.CaseMute, models.CaseTimeout:
+		lifts = []string{mc.Action}
+	case models.CaseUnmute:
+		lifts = []string{models.CaseMute, models.CaseTimeout}
+	}
+	if len(lifts) > 0 {
+		_, err = db.C("cases").UpdateAll(bson.M{
+			"guild_id": mc.GuildID,
+			"user_id":  mc.UserID,
+			"action":   bson.M{"$in": lifts},
+			"active":   true,
+		}, bson.M{"$set": bson.M{"active": false}})
+		if err != nil {
+			log.Printf("Failed to lift the earlier cases of %s, %s", mc.UserID, err)
+		}
+	}
+
+	mc, err = recordCase(db, ds, mc)
+	if err != nil {
+		return mc, fmt.Errorf("it was done, but the case couldn't be recorded: %s", err)
+	}
+	return mc, nil
+}
+
+// caseEmbed a case as it's shown in the log channel and by `-db case`
+func caseEmbed(mc models.ModCase) *discordgo.MessageEmbed {
+	reason := mc.Reason
+	if reason == "" {
+		reason = "*no reason given*"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Case #%d | %s", mc.CaseNumber, strings.Title(mc.Action)),
+		Color: caseColors[mc.Action],
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "User",
+				Value:  fmt.Sprintf("<@%s> (%s)", mc.UserID, mc.UserName),
+				Inline: true,
+			},
+			{
+				Name:   "Moderator",
+				Value:  fmt.Sprintf("<@%s> (%s)", mc.ModeratorID, mc.ModeratorName),
+				Inline: true,
+			},
+			{
+				Name:  "Reason",
+				Value: truncateField(reason, 1024),
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("User ID: %s", mc.UserID),
+		},
+		Timestamp: mc.CreatedAt.Format(time.RFC3339),
+	}
+
+	if mc.Duration > 0 {
+		state := fmt.Sprintf("expires <t:%d:R>", mc.ExpiresAt.Unix())
+		if !mc.Active {
+			state = "over"
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Duration",
+			Value: fmt.Sprintf("%s, %s", formatDuration(mc.Duration), state),
+		})
+	} else if mc.Action == models.CaseMute && !mc.Active {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Duration",
+			Value: "until unmuted, over",
+		})
+	}
+	if !mc.Notified {
+		embed.Footer.Text += " | Couldn't be DMed"
+	}
+	return embed
+}
+
+// InitMuteExpiry lift mutes and timeouts once they expire
+func (m *Mux) InitMuteExpiry(ds *discordgo.Session) {
+	for {
+		time.Sleep(muteExpiryInterval)
+		m.ExpireMutes(ds)
+	}
+}
+
+// ExpireMutes lift the mutes and timeouts whose time is up
+func (m *Mux) ExpireMutes(ds *discordgo.Session) {
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	expired := []models.ModCase{}
+	err := db.C("cases").Find(bson.M{"active": true, "expires_at": bson.M{"$lte": time.Now()}}).All(&expired)
+	if err != nil {
+		log.Printf("Failed to get the expired mutes, %s", err)
+		return
+	}
+
+	for _, mc := range expired {
+		err := db.C("cases").UpdateId(mc.OID, bson.M{"$set": bson.M{"active": false}})
+		if err != nil {
+			log.Printf("Failed to expire case %d of %s, %s", mc.CaseNumber, mc.GuildID, err)
+			continue
+		}
+		// Discord ends timeouts by itself
+		if mc.Action != models.CaseMute {
+			continue
+		}
+
+		roleID := config.MuteRole(mc.GuildID)
+		if roleID != "" {
+			err = ds.GuildMemberRoleRemove(mc.GuildID, mc.UserID, roleID)
+			if err != nil {
+				// They may have left, they won't be muted again when they come back since the mute is over
+				log.Printf("Failed to unmute %s after case %d expired, %s", mc.UserID, mc.CaseNumber, err)
+			}
+		}
+
+		unmute := models.ModCase{
+			GuildID:       mc.GuildID,
+			Action:        models.CaseUnmute,
+			UserID:        mc.UserID,
+			UserName:      mc.UserName,
+			ModeratorID:   ds.State.User.ID,
+			ModeratorName: ds.State.User.String(),
+			Reason:        fmt.Sprintf("Mute from case #%d expired", mc.CaseNumber),
+			CreatedAt:     time.Now(),
+		}
+		unmute.Notified = notifyCase(ds, unmute)
+		_, err = recordCase(db, ds, unmute)
+		if err != nil {
+			log.Printf("Failed to record the unmute of %s, %s", mc.UserID, err)
+		}
+	}
+}
+
+// isMuted whether someone has a mute in effect
+func isMuted(db *mgo.Database, guildID, userID string) bool {
+	n, err := db.C("cases").Find(bson.M{
+		"guild_id": guildID,
+		"user_id":  userID,
+		"action":   models.CaseMute,
+		"active":   true,
+	}).Count()
+	if err != nil {
+		log.Printf("Failed to check whether %s is muted, %s", userID, err)
+		return false
+	}
+	return n > 0
+}
+
+// OnGuildMemberAdd mute people again who left and came back while muted
+func (m *Mux) OnGuildMemberAdd(ds *discordgo.Session, ma *discordgo.GuildMemberAdd) {
+	roleID := config.MuteRole(ma.GuildID)
+	if roleID == "" {
+		return
+	}
+
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	db := session.DB(mongo.DB_NAME)
+
+	if isMuted(db, ma.GuildID, ma.User.ID) {
+		err := ds.GuildMemberRoleAdd(ma.GuildID, ma.User.ID, roleID)
+		if err != nil {
+			log.Printf("Failed to mute %s again, %s", ma.User.ID, err)
+		}
+	}
+}
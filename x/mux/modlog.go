@@ -0,0 +1,242 @@
+package mux
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/w8kerr/delubot/attachments"
+	"github.com/w8kerr/delubot/config"
+)
+
+// Colors of the mod log embeds
+const (
+	modLogDeleteColor = 15158332
+	modLogEditColor   = 3447003
+)
+
+// Discord adds deletes of the same author's messages in the same channel to one audit log entry,
+// counting them up, so the count an entry had last time tells whether it was just added to. By guild and entry ID.
+var seenDeleteEntries = struct {
+	sync.Mutex
+	counts map[string]map[string]int
+}{counts: make(map[string]map[string]int)}
+
+// How old a new audit log entry can be to still be about the message that was just deleted
+var deleteEntryMaxAge = 30 * time.Second
+
+func truncateField(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// findDeleter who deleted someone's message according to the audit log.
+// People deleting their own messages and bots deleting messages aren't in the audit log, so "" means one of those.
+func findDeleter(ds *discordgo.Session, guildID, channelID, authorID string) string {
+	if authorID == "" {
+		return ""
+	}
+	auditLog, err := ds.GuildAuditLog(guildID, "", "", int(discordgo.AuditLogActionMessageDelete), 10)
+	if err != nil {
+		log.Printf("Failed to read the audit log of %s, %s", guildID, err)
+		return ""
+	}
+
+	seenDeleteEntries.Lock()
+	defer seenDeleteEntries.Unlock()
+
+	deleter := ""
+	counts := make(map[string]int)
+	for _, entry := range auditLog.AuditLogEntries {
+		if entry.Options == nil {
+			continue
+		}
+		count, _ := strconv.Atoi(entry.Options.Count)
+		lastCount, seen := seenDeleteEntries.counts[guildID][entry.ID]
+		counts[entry.ID] = count
+
+		if deleter != "" || entry.TargetID != authorID || entry.Options.ChannelID != channelID {
+			continue
+		}
+		created, _ := discordgo.SnowflakeTimestamp(entry.ID)
+		if (!seen && time.Since(created) < deleteEntryMaxAge) || (seen && count > lastCount) {
+			deleter = entry.UserID
+		}
+	}
+	// Only the latest entries can still be added to
+	seenDeleteEntries.counts[guildID] = counts
+	return deleter
+}
+
+// modLogChannel the channel deletes and edits in a channel are posted to, "" if they aren't,
+// messages in a thread are ignored along with its channel
+func modLogChannel(ds *discordgo.Session, guildID, channelID string) string {
+	parentID, _, _ := logChannel(ds, channelID)
+	if parentID != channelID && config.ModLogChannel(guildID, parentID) == "" {
+		return ""
+	}
+	return config.ModLogChannel(guildID, channelID)
+}
+
+// ModLogDelete post a deleted message to the guild's log channel, with the archived copies of its attachments
+func (m *Mux) ModLogDelete(db *mgo.Database, ds *discordgo.Session, guildID, channelID string, deleted MessageLog) {
+	logChannel := modLogChannel(ds, guildID, channelID)
+	if logChannel == "" || deleted.MessageID == "" || deleted.UserID == ds.State.User.ID {
+		return
+	}
+
+	desc := fmt.Sprintf("**Message by <@%s> deleted in <#%s>**\n%s", deleted.UserID, channelID, deleted.Content)
+	embed := &discordgo.MessageEmbed{
+		Color: modLogDeleteColor,
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: deleted.UserName,
+		},
+		Description: truncateField(desc, 2048),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("User ID: %s | Message ID: %s", deleted.UserID, deleted.MessageID),
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	files := []*discordgo.File{}
+	if len(deleted.Attachments) > 0 {
+		archived, err := attachments.ForMessage(db, deleted.MessageID)
+		if err != nil {
+			log.Printf("Failed to look up the archived attachments of message %s, %s", deleted.MessageID, err)
+		}
+		var notes map[string]string
+		files, notes = archivedFiles(archived)
+
+		// The CDN links stop working once the message is gone, they're only there for what wasn't archived
+		lines := []string{}
+		for _, att := range deleted.Attachments {
+			if note, ok := notes[att.ID]; ok {
+				lines = append(lines, fmt.Sprintf("`%s` %s", att.Filename, note))
+			} else {
+				lines = append(lines, fmt.Sprintf("[%s](%s) not archived", att.Filename, att.URL))
+			}
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Attachments",
+			Value: truncateField(strings.Join(lines, "\n"), 1024),
+		})
+	}
+
+	deleter := findDeleter(ds, guildID, channelID, deleted.UserID)
+	if deleter != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Deleted by",
+			Value: fmt.Sprintf("<@%s>", deleter),
+		})
+	}
+
+	_, err := ds.ChannelMessageSendComplex(logChannel, &discordgo.MessageSend{
+		Embed: embed,
+		Files: files,
+	})
+	if err != nil {
+		log.Printf("Failed to post deleted message %s to the mod log, %s", deleted.MessageID, err)
+	}
+}
+
+// ModLogEdit post an edited message, before and after, to the guild's log channel
+func (m *Mux) ModLogEdit(ds *discordgo.Session, before, after MessageLog) {
+	channelID := after.ChannelID
+	if after.ThreadID != "" {
+		channelID = after.ThreadID
+	}
+
+	logChannel := modLogChannel(ds, after.GuildID, channelID)
+	if logChannel == "" || after.MessageID == "" || after.UserID == ds.State.User.ID || before.Content == after.Content {
+		return
+	}
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", after.GuildID, channelID, after.MessageID)
+
+	beforeContent := before.Content
+	if beforeContent == "" {
+		beforeContent = "*unknown*"
+	}
+	afterContent := after.Content
+	if afterContent == "" {
+		afterContent = "*empty*"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Color: modLogEditColor,
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: after.UserName,
+		},
+		Description: fmt.Sprintf("**Message by <@%s> edited in <#%s>** [Jump to message](%s)", after.UserID, channelID, link),
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "Before",
+				Value: truncateField(beforeContent, 1024),
+			},
+			{
+				Name:  "After",
+				Value: truncateField(afterContent, 1024),
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("User ID: %s | Message ID: %s", after.UserID, after.MessageID),
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	_, err := ds.ChannelMessageSendEmbed(logChannel, embed)
+	if err != nil {
+		log.Printf("Failed to post edited message %s to the mod log, %s", after.MessageID, err)
+	}
+}
+
+// ModLogBulkDelete post a summary of a bulk delete to the guild's log channel, with the messages as a transcript
+func (m *Mux) ModLogBulkDelete(ds *discordgo.Session, guildID, channelID string, deleted []MessageLog) {
+	logChannel := modLogChannel(ds, guildID, channelID)
+	if logChannel == "" || len(deleted) == 0 {
+		return
+	}
+
+	sort.Slice(deleted, func(i, j int) bool {
+		return deleted[i].Time.Before(deleted[j].Time)
+	})
+
+	transcript := strings.Builder{}
+	known := 0
+	for _, ml := range deleted {
+		if ml.MessageID == "" {
+			continue
+		}
+		known++
+		transcript.WriteString(formatMessageLog(ml, config.Loc) + "\n")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Color:       modLogDeleteColor,
+		Description: fmt.Sprintf("**%d messages deleted in <#%s>**\n%d of them were logged, see the transcript", len(deleted), channelID, known),
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+
+	send := &discordgo.MessageSend{
+		Embed: embed,
+	}
+	if known > 0 {
+		send.Files = []*discordgo.File{{
+			Name:        fmt.Sprintf("deleted-%s.txt", time.Now().In(config.Loc).Format("20060102-150405")),
+			ContentType: "text/plain",
+			Reader:      strings.NewReader(transcript.String()),
+		}}
+	}
+	_, err := ds.ChannelMessageSendComplex(logChannel, send)
+	if err != nil {
+		log.Printf("Failed to post bulk delete in %s to the mod log, %s", channelID, err)
+	}
+}
@@ -8,14 +8,11 @@ import (
 	"log"
 	"math/rand"
 	"strings"
-	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/globalsign/mgo"
-	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/attachments"
 	"github.com/w8kerr/delubot/config"
 	"github.com/w8kerr/delubot/mongo"
-	"github.com/w8kerr/delubot/utils"
 )
 
 // Route holds information about a specific message route handler
@@ -108,54 +105,6 @@ func (m *Mux) FuzzyMatch(msg string) (*Route, []string) {
 	return r, fields[fk:]
 }
 
-type MessageLog struct {
-	ChannelName     string
-	UserName        string
-	Content         string
-	OriginalContent string                         `bson:"originalContent,omitempty"`
-	Attachments     []*discordgo.MessageAttachment `bson:"attachments,omitempty"`
-	MessageID       string
-	Action          string
-	Time            time.Time
-}
-
-func ImageCopyEmbeds(ds *discordgo.Session, msg *discordgo.Message) []*discordgo.MessageEmbed {
-	channel, _ := ds.Channel(msg.ChannelID)
-
-	embeds := []*discordgo.MessageEmbed{}
-
-	for _, attachment := range msg.Attachments {
-		embed := &discordgo.MessageEmbed{
-			Color: 3066993,
-			Thumbnail: &discordgo.MessageEmbedThumbnail{
-				URL: msg.Author.AvatarURL("64"),
-			},
-			Author: &discordgo.MessageEmbedAuthor{
-				Name: msg.Author.Username,
-			},
-			Description: "File: " + attachment.URL + "\n\nMessage: " + config.MessageLink(msg),
-			Footer: &discordgo.MessageEmbedFooter{
-				Text: channel.Name,
-			},
-			Timestamp: string(msg.Timestamp),
-		}
-
-		embed.Image = &discordgo.MessageEmbedImage{
-			URL:      attachment.URL,
-			ProxyURL: attachment.ProxyURL,
-		}
-		embed.Video = &discordgo.MessageEmbedVideo{
-			URL: attachment.URL,
-		}
-		embeds = append(embeds, embed)
-	}
-
-	return embeds
-}
-
-var PublicTLChannel = "796526853442895915"
-var MembersTLChannel = "776620304888889374"
-
 // OnMessageCreate is a DiscordGo Event Handler function.  This must be
 // registered using the DiscordGo.Session.AddHandler function.  This function
 // will receive all Discord messages and parse them for matches to registered
@@ -170,22 +119,22 @@ func (m *Mux) OnMessageCreate(ds *discordgo.Session, mc *discordgo.MessageCreate
 		return
 	}
 
-	// Copy images so they can still be referenced
-	if mc.Author.ID != ds.State.User.ID && mc.GuildID == "755437328515989564" && len(mc.Message.Attachments) > 0 {
-		utils.PrintJSON(mc.Message)
-		embeds := ImageCopyEmbeds(ds, mc.Message)
-		for _, embed := range embeds {
-			imageDumpChannel := "840804942326530088"
-			ds.ChannelMessageSendEmbed(imageDumpChannel, embed)
-			// ds.ChannelMessageSendComplex(imageDumpChannel, &discordgo.MessageSend{
-			// 	Content: mc.Message.Attachments[0].URL,
-			// 	Embed:   embed,
-			// })
-		}
+	// Keep the attachments, their links stop working once the message is deleted
+	if mc.Author.ID != ds.State.User.ID && config.ArchiveEnabled[mc.GuildID] && len(mc.Message.Attachments) > 0 {
+		go func(msg *discordgo.Message) {
+			session := mongo.MDB.Clone()
+			defer session.Close()
+			attachments.ArchiveMessage(session.DB(mongo.DB_NAME), msg)
+		}(mc.Message)
 	}
 
 	m.LogMessageCreate(db, ds, mc, nil)
 
+	// Nothing else happens to a message automod deleted
+	if m.Automod(db, ds, mc.Message) {
+		return
+	}
+
 	// fmt.Println("Got message", mc.Message.MessageReference, mc.Message.MessageReference != nil)
 	if mc.Message.MessageReference != nil {
 		// Get the highest message up the reply chain, and check if it was the bot
@@ -220,18 +169,14 @@ func (m *Mux) OnMessageCreate(ds *discordgo.Session, mc *discordgo.MessageCreate
 		return
 	}
 
-	// Handle Youtube copy pipelines
+	// Handle Youtube copy pipelines, their queues put the chat messages back in order
 	for _, cp := range config.CopyPipelines {
-		if cp.ChannelID == mc.ChannelID {
-			go m.CopyMessageToYoutube(ds, mc.Message, cp)
+		if cp.ChannelID == mc.ChannelID && cp.Type == config.CopyToYoutube {
+			m.CopyMessageToYoutube(ds, mc.Message, cp)
 		}
 	}
 
-	// Handle public-to-private copy pipeline
-	if config.DoubleTL && mc.ChannelID == PublicTLChannel {
-		copyMsg := fmt.Sprintf("**%s:** %s", mc.Author.Username, mc.Content)
-		ds.ChannelMessageSend(MembersTLChannel, copyMsg)
-	}
+	m.MirrorMessage(ds, db, mc.Message)
 
 	// if mc.Content == config.Emoji("delucringe") {
 	// 	doDelete := false
@@ -392,162 +337,39 @@ func (m *Mux) OnMessageDelete(ds *discordgo.Session, md *discordgo.MessageDelete
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	db := session.DB(mongo.DB_NAME)
-	mlog := db.C("message_logs")
 
-	channelName := ""
-	channel, err := ds.Channel(md.ChannelID)
-	if err == nil {
-		channelName = channel.Name
-	} else {
-		channelName = err.Error()
-	}
-
-	deleted := MessageLog{}
-	err = mlog.Find(bson.M{"messageid": md.ID}).One(&deleted)
-	if err != nil {
-		fmt.Println("Failed to get deleted message: " + err.Error())
-	}
+	deleted := m.LogMessageDelete(db, ds, md.GuildID, md.ChannelID, md.ID)
 	log.Printf("DELETED MESSAGE - %s: %s", deleted.UserName, deleted.Content)
+	m.ModLogDelete(db, ds, md.GuildID, md.ChannelID, deleted)
 
-	mlog.Insert(MessageLog{
-		ChannelName: channelName,
-		UserName:    deleted.UserName,
-		Content:     deleted.Content,
-		MessageID:   md.ID,
-		Action:      "delete",
-		Time:        time.Now(),
-	})
-
-	if config.DoubleTL && md.ChannelID == PublicTLChannel {
-		msgs, err := ds.ChannelMessages(MembersTLChannel, 20, "", "", "")
-		if err != nil {
-			fmt.Println("DoubleTL - Failed to get messages!", md.ChannelID, err)
-			return
-		}
-
-		copyMsg := fmt.Sprintf("**%s:** %s", strings.Split(deleted.UserName, "#")[0], deleted.Content)
-		fmt.Println("Look for message to delete")
-		fmt.Println(copyMsg)
-		for _, msg := range msgs {
-			if msg.Content == copyMsg {
-				err = ds.ChannelMessageDelete(msg.ChannelID, msg.ID)
-				if err != nil {
-					fmt.Println("DoubleTL - Failed to delete message", msg.ChannelID, msg.ID)
-				}
-				return
-			}
-		}
-	}
+	m.MirrorMessageDelete(ds, db, md.ID)
 }
 
 func (m *Mux) OnMessageDeleteBulk(ds *discordgo.Session, mdb *discordgo.MessageDeleteBulk) {
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	db := session.DB(mongo.DB_NAME)
-	mlog := db.C("message_logs")
-
-	channelName := ""
-	channel, err := ds.Channel(mdb.ChannelID)
-	if err == nil {
-		channelName = channel.Name
-	} else {
-		channelName = err.Error()
-	}
 
+	deleted := []MessageLog{}
 	for _, msgID := range mdb.Messages {
-		deleted := MessageLog{}
-		err = mlog.Find(bson.M{"messageid": msgID}).One(&deleted)
-		if err != nil {
-			fmt.Println("Failed to get bulk deleted message: " + err.Error())
-		}
-		log.Printf("BULK DELETED MESSAGE - %s: %s", deleted.UserName, deleted.Content)
-
-		mlog.Insert(MessageLog{
-			ChannelName: channelName,
-			UserName:    deleted.UserName,
-			Content:     deleted.Content,
-			MessageID:   msgID,
-			Action:      "delete",
-			Time:        time.Now(),
-		})
+		ml := m.LogMessageDelete(db, ds, mdb.GuildID, mdb.ChannelID, msgID)
+		log.Printf("BULK DELETED MESSAGE - %s: %s", ml.UserName, ml.Content)
+		deleted = append(deleted, ml)
+
+		m.MirrorMessageDelete(ds, db, msgID)
 	}
+	m.ModLogBulkDelete(ds, mdb.GuildID, mdb.ChannelID, deleted)
 }
 
 func (m *Mux) OnMessageUpdate(ds *discordgo.Session, mu *discordgo.MessageUpdate) {
 	session := mongo.MDB.Clone()
 	defer session.Close()
 	db := session.DB(mongo.DB_NAME)
-	mlog := db.C("message_logs")
-
-	channelName := ""
-	channel, err := ds.Channel(mu.ChannelID)
-	if err == nil {
-		channelName = channel.Name
-	} else {
-		channelName = err.Error()
-	}
 
-	edited := MessageLog{}
-	err = mlog.Find(bson.M{"messageid": mu.ID}).One(&edited)
-	if err != nil {
-		fmt.Println("Failed to get edited message: " + err.Error())
-	}
-
-	mlog.Insert(MessageLog{
-		ChannelName: channelName,
-		UserName:    "#",
-		Content:     mu.Content,
-		MessageID:   mu.ID,
-		Action:      "edit",
-		Time:        time.Now(),
-	})
-
-	if config.DoubleTL && mu.ChannelID == PublicTLChannel {
-		msgs, err := ds.ChannelMessages(MembersTLChannel, 20, "", "", "")
-		if err != nil {
-			fmt.Println("DoubleTL - Failed to get messages!", mu.ChannelID, err)
-			return
-		}
-
-		copyMsg := fmt.Sprintf("**%s:** %s", strings.Split(edited.UserName, "#")[0], edited.Content)
-		fmt.Println("Look for message to edit")
-		fmt.Println(copyMsg)
-		updateMsg := fmt.Sprintf("**%s:** %s", mu.Author.Username, mu.Content)
-		for _, msg := range msgs {
-			fmt.Println(msg.Content)
-			if msg.Content == copyMsg {
-				_, err = ds.ChannelMessageEdit(msg.ChannelID, msg.ID, updateMsg)
-				if err != nil {
-					fmt.Println("DoubleTL - Failed to edit message", msg.ChannelID, msg.ID)
-				}
-				return
-			}
-		}
-	}
-}
-
-func (m *Mux) LogMessageCreate(db *mgo.Database, ds *discordgo.Session, mc *discordgo.MessageCreate, channelName *string) {
-	mlog := db.C("message_logs")
-	if channelName == nil {
-		name := ""
-		channel, err := ds.Channel(mc.ChannelID)
-		if err == nil {
-			name = channel.Name
-		} else {
-			name = err.Error()
-		}
-		channelName = &name
-	}
+	before, after := m.LogMessageUpdate(db, ds, mu.Message)
+	m.ModLogEdit(ds, before, after)
 
-	mlog.Insert(MessageLog{
-		ChannelName: *channelName,
-		UserName:    mc.Author.Username + "#" + mc.Author.Discriminator,
-		Content:     mc.Content,
-		Attachments: mc.Attachments,
-		MessageID:   mc.ID,
-		Action:      "create",
-		Time:        time.Now(),
-	})
+	m.MirrorMessageEdit(ds, db, mu.Message)
 }
 
 var Pushpin = "\U0001F4CC"
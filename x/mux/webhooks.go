@@ -0,0 +1,171 @@
+package mux
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/w8kerr/delubot/utils"
+)
+
+// The name of the webhooks the bot relays messages with, one per channel
+var relayWebhookName = "DeluBot Relay"
+
+var relayWebhooks = struct {
+	sync.Mutex
+	byChannel map[string]*discordgo.Webhook
+}{byChannel: make(map[string]*discordgo.Webhook)}
+
+// Nobody should be pinged by a relayed message, not even if the mention is allowed by accident
+var massMentionRE = regexp.MustCompile(`@(everyone|here)`)
+
+// Discord refuses webhook names containing these
+var reservedWebhookNames = regexp.MustCompile(`(?i)(discord|clyde)`)
+
+// ErrRelayWebhookGone the webhook a message was relayed with was deleted, so the message can't be edited anymore
+var ErrRelayWebhookGone = errors.New("the webhook that relayed the message is gone")
+
+// Relay A message to post under someone else's name
+type Relay struct {
+	Content string
+	Embeds  []*discordgo.MessageEmbed
+	Files   []*discordgo.File
+}
+
+// ChannelWebhook the webhook the bot relays messages into a channel with, created if there isn't one yet
+func ChannelWebhook(ds *discordgo.Session, channelID string) (*discordgo.Webhook, error) {
+	relayWebhooks.Lock()
+	defer relayWebhooks.Unlock()
+
+	if wh, ok := relayWebhooks.byChannel[channelID]; ok {
+		return wh, nil
+	}
+
+	whs, err := ds.ChannelWebhooks(channelID)
+	if err != nil {
+		return nil, err
+	}
+	for _, wh := range whs {
+		if wh.Name == relayWebhookName && wh.Token != "" {
+			relayWebhooks.byChannel[channelID] = wh
+			return wh, nil
+		}
+	}
+
+	wh, err := ds.WebhookCreate(channelID, relayWebhookName, "")
+	if err != nil {
+		return nil, err
+	}
+	relayWebhooks.byChannel[channelID] = wh
+	return wh, nil
+}
+
+// forgetChannelWebhook drop a webhook someone deleted, so the next relay creates a new one
+func forgetChannelWebhook(channelID string) {
+	relayWebhooks.Lock()
+	defer relayWebhooks.Unlock()
+	delete(relayWebhooks.byChannel, channelID)
+}
+
+// IsRelayWebhook whether a message was relayed by the bot, which must not be relayed again
+func IsRelayWebhook(webhookID string) bool {
+	if webhookID == "" {
+		return false
+	}
+
+	relayWebhooks.Lock()
+	defer relayWebhooks.Unlock()
+	for _, wh := range relayWebhooks.byChannel {
+		if wh.ID == webhookID {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnknownWebhook(err error) bool {
+	rerr, ok := err.(*discordgo.RESTError)
+	return ok && rerr.Message != nil && rerr.Message.Code == discordgo.ErrCodeUnknownWebhook
+}
+
+// SanitizeMentions defuse mass mentions in relayed text
+func SanitizeMentions(content string) string {
+	return massMentionRE.ReplaceAllString(content, "@\u200b$1")
+}
+
+// RelayName the name a message is relayed under, the author's nickname on the server if they have one
+func RelayName(author *discordgo.User, member *discordgo.Member) string {
+	name := author.Username
+	if member != nil && member.Nick != "" {
+		name = member.Nick
+	}
+
+	name = reservedWebhookNames.ReplaceAllStringFunc(name, func(s string) string {
+		return s[:1] + "\u200b" + s[1:]
+	})
+	runes := []rune(strings.TrimSpace(name))
+	if len(runes) > 80 {
+		runes = runes[:80]
+	}
+	if len(runes) == 0 {
+		return "Unknown"
+	}
+	return string(runes)
+}
+
+// RelayMessage post a message into a channel under the author's name and avatar
+func RelayMessage(ds *discordgo.Session, channelID string, author *discordgo.User, member *discordgo.Member, relay Relay) (*discordgo.Message, *discordgo.Webhook, error) {
+	params := &discordgo.WebhookParams{
+		Content:   SanitizeMentions(relay.Content),
+		Username:  RelayName(author, member),
+		AvatarURL: author.AvatarURL(""),
+		Embeds:    relay.Embeds,
+		// Relays repeat what someone else said, they should never ping
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+
+	var msg *discordgo.Message
+	var wh *discordgo.Webhook
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		wh, err = ChannelWebhook(ds, channelID)
+		if err != nil {
+			return nil, nil, err
+		}
+		msg, err = utils.WebhookExecuteFiles(ds, wh.ID, wh.Token, params, relay.Files)
+		if !isUnknownWebhook(err) {
+			break
+		}
+		forgetChannelWebhook(channelID)
+	}
+	return msg, wh, err
+}
+
+// relayWebhookFor the webhook a relayed message was posted with, if it's still around
+func relayWebhookFor(ds *discordgo.Session, channelID, webhookID string) (*discordgo.Webhook, bool) {
+	wh, err := ChannelWebhook(ds, channelID)
+	if err != nil || wh.ID != webhookID {
+		return nil, false
+	}
+	return wh, true
+}
+
+// EditRelay replace the text of a relayed message
+func EditRelay(ds *discordgo.Session, channelID, webhookID, messageID, content string) error {
+	wh, ok := relayWebhookFor(ds, channelID, webhookID)
+	if !ok {
+		return ErrRelayWebhookGone
+	}
+	return utils.WebhookMessageEdit(ds, wh.ID, wh.Token, messageID, SanitizeMentions(content), &discordgo.MessageAllowedMentions{})
+}
+
+// DeleteRelay delete a relayed message, through its webhook if it's still around
+func DeleteRelay(ds *discordgo.Session, channelID, webhookID, messageID string) error {
+	wh, ok := relayWebhookFor(ds, channelID, webhookID)
+	if !ok {
+		return ds.ChannelMessageDelete(channelID, messageID)
+	}
+	return utils.WebhookMessageDelete(ds, wh.ID, wh.Token, messageID)
+}
@@ -0,0 +1,217 @@
+package youtubesvc
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/models"
+	"github.com/w8kerr/delubot/mongo"
+)
+
+// How long after a stream ends its view count keeps being updated
+var archiveViewWindow = 7 * 24 * time.Hour
+
+// MergeArchive fold the latest state of a stream into its archive entry
+func MergeArchive(arch models.StreamArchive, rec models.YoutubeStreamRecord, now time.Time) models.StreamArchive {
+	arch.VideoID = rec.YoutubeID
+	arch.ChannelID = rec.ChannelID
+	arch.ChannelTitle = rec.ChannelTitle
+	arch.ScheduledTime = rec.ScheduledTime
+
+	if arch.Title != "" && rec.StreamTitle != "" && arch.Title != rec.StreamTitle {
+		arch.TitleChanges = append(arch.TitleChanges, models.TitleChange{
+			Title:     arch.Title,
+			ChangedAt: now,
+		})
+	}
+	if rec.StreamTitle != "" {
+		arch.Title = rec.StreamTitle
+	}
+
+	if !rec.StartedAt.IsZero() {
+		arch.StartedAt = rec.StartedAt
+	}
+	if !rec.EndedAt.IsZero() {
+		arch.EndedAt = rec.EndedAt
+	}
+	if !arch.StartedAt.IsZero() && !arch.EndedAt.IsZero() {
+		arch.Duration = int(arch.EndedAt.Sub(arch.StartedAt).Seconds())
+	}
+
+	// Youtube only reports concurrent viewers while a stream is live, so the peak is whatever was seen
+	if rec.Viewers > arch.PeakViewers {
+		arch.PeakViewers = rec.Viewers
+	}
+	if rec.ViewCount > arch.ViewCount {
+		arch.ViewCount = rec.ViewCount
+	}
+
+	arch.UpdatedAt = now
+	return arch
+}
+
+// ArchiveStreams record the streams that started in the stream archive
+func ArchiveStreams(db *mgo.Database, recs []models.YoutubeStreamRecord) {
+	saCol := db.C("stream_archive")
+	now := time.Now()
+
+	for _, rec := range recs {
+		if rec.StartedAt.IsZero() {
+			continue
+		}
+
+		arch := models.StreamArchive{}
+		err := saCol.FindId(rec.YoutubeID).One(&arch)
+		if err != nil && err != mgo.ErrNotFound {
+			log.Printf("Failed to get the archive of %s, %s", rec.YoutubeID, err)
+			continue
+		}
+
+		arch = MergeArchive(arch, rec, now)
+		_, err = saCol.UpsertId(arch.VideoID, arch)
+		if err != nil {
+			log.Printf("Failed to archive %s, %s", rec.YoutubeID, err)
+		}
+	}
+}
+
+// Archiver keep the archive of recent streams up to date, catching stream ends and the view counts of the VODs
+func Archiver() {
+	sleepDuration := time.Hour
+	for {
+		time.Sleep(sleepDuration)
+		RefreshArchive()
+	}
+}
+
+// RefreshArchive look up the streams that are still live or ended recently and update their archive entries
+func RefreshArchive() {
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+
+	archs := []models.StreamArchive{}
+	err := db.C("stream_archive").Find(bson.M{"$or": []bson.M{
+		{"ended_at": time.Time{}},
+		{"ended_at": bson.M{"$gt": time.Now().Add(-archiveViewWindow)}},
+	}}).All(&archs)
+	if err != nil {
+		log.Printf("Failed to get recent streams from the archive, %s", err)
+		return
+	}
+	if len(archs) == 0 {
+		return
+	}
+
+	ids := []string{}
+	for _, arch := range archs {
+		ids = append(ids, arch.VideoID)
+	}
+
+	c := context.WithValue(context.Background(), "mgo", session)
+	svc, err := NewYoutubeService(c)
+	if err != nil {
+		log.Printf("Could not connect to Youtube, %s", err)
+		return
+	}
+	recs, err := svc.GetStreams(ids)
+	if err != nil {
+		log.Printf("Failed to refresh the stream archive, %s", err)
+		return
+	}
+
+	ArchiveStreams(db, recs)
+}
+
+// MonthStats Stream totals of one month
+type MonthStats struct {
+	Month       string
+	Streams     int
+	Duration    time.Duration
+	PeakViewers int
+	Views       int
+}
+
+// StreamStats Totals and streaks over the archived streams
+type StreamStats struct {
+	Months        []MonthStats
+	LongestStreak int
+	LongestEnd    time.Time
+	CurrentStreak int
+}
+
+// ComputeStreamStats total the archived streams by month, and find the runs of consecutive days with a stream.
+// Days are counted in loc, by when the stream started.
+func ComputeStreamStats(archs []models.StreamArchive, now time.Time, loc *time.Location) StreamStats {
+	stats := StreamStats{}
+
+	months := make(map[string]*MonthStats)
+	days := make(map[string]bool)
+	for _, arch := range archs {
+		if arch.StartedAt.IsZero() {
+			continue
+		}
+		start := arch.StartedAt.In(loc)
+
+		key := start.Format("2006-01")
+		ms, ok := months[key]
+		if !ok {
+			ms = &MonthStats{Month: key}
+			months[key] = ms
+		}
+		ms.Streams++
+		ms.Duration += time.Duration(arch.Duration) * time.Second
+		ms.Views += arch.ViewCount
+		if arch.PeakViewers > ms.PeakViewers {
+			ms.PeakViewers = arch.PeakViewers
+		}
+
+		days[start.Format("2006-01-02")] = true
+	}
+
+	for _, ms := range months {
+		stats.Months = append(stats.Months, *ms)
+	}
+	sort.Slice(stats.Months, func(i, j int) bool {
+		return stats.Months[i].Month < stats.Months[j].Month
+	})
+
+	sorted := []time.Time{}
+	for day := range days {
+		t, _ := time.ParseInLocation("2006-01-02", day, loc)
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Before(sorted[j])
+	})
+
+	streak := 0
+	for i, day := range sorted {
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(day) {
+			streak++
+		} else {
+			streak = 1
+		}
+		if streak >= stats.LongestStreak {
+			stats.LongestStreak = streak
+			stats.LongestEnd = day
+		}
+	}
+
+	// The current streak is still alive if the last stream was today or yesterday
+	if len(sorted) > 0 {
+		now = now.In(loc)
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		last := sorted[len(sorted)-1]
+		if last.Equal(today) || last.Equal(today.AddDate(0, 0, -1)) {
+			stats.CurrentStreak = streak
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,82 @@
+package youtubesvc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/w8kerr/delubot/models"
+)
+
+func Test_MergeArchive(t *testing.T) {
+	start := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	rec := models.YoutubeStreamRecord{
+		YoutubeID:   "1Mm2VgxI-nA",
+		StreamTitle: "Minecraft",
+		StartedAt:   start,
+		Viewers:     1200,
+	}
+	arch := MergeArchive(models.StreamArchive{}, rec, start.Add(time.Minute))
+
+	rec.StreamTitle = "Minecraft (collab)"
+	rec.Viewers = 900
+	arch = MergeArchive(arch, rec, start.Add(time.Hour))
+
+	rec.EndedAt = start.Add(2 * time.Hour)
+	rec.Viewers = 0
+	rec.ViewCount = 15000
+	arch = MergeArchive(arch, rec, start.Add(2*time.Hour))
+
+	if arch.VideoID != "1Mm2VgxI-nA" || arch.Title != "Minecraft (collab)" {
+		t.Errorf("unexpected archive %+v", arch)
+	}
+	if len(arch.TitleChanges) != 1 || arch.TitleChanges[0].Title != "Minecraft" {
+		t.Errorf("the old title should be kept, got %+v", arch.TitleChanges)
+	}
+	if arch.PeakViewers != 1200 {
+		t.Errorf("peak viewers should be 1200, got %d", arch.PeakViewers)
+	}
+	if arch.Duration != 7200 || arch.ViewCount != 15000 {
+		t.Errorf("unexpected duration %d or view count %d", arch.Duration, arch.ViewCount)
+	}
+}
+
+func Test_ComputeStreamStats(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2021, month, d, 20, 0, 0, 0, time.UTC)
+	}
+	archs := []models.StreamArchive{
+		{StartedAt: day(2, 27), Duration: 3600, PeakViewers: 500, ViewCount: 1000},
+		{StartedAt: day(2, 28), Duration: 7200, PeakViewers: 800, ViewCount: 2000},
+		{StartedAt: day(3, 1), Duration: 3600, PeakViewers: 600, ViewCount: 1500},
+		{StartedAt: day(3, 5), Duration: 1800, PeakViewers: 700, ViewCount: 500},
+		{StartedAt: day(3, 6), Duration: 1800, PeakViewers: 400, ViewCount: 500},
+		{StartedAt: day(3, 6), Duration: 1800, PeakViewers: 300, ViewCount: 500},
+		{ScheduledTime: day(3, 7)},
+	}
+
+	stats := ComputeStreamStats(archs, day(3, 7), time.UTC)
+
+	if len(stats.Months) != 2 {
+		t.Fatalf("expected 2 months, got %+v", stats.Months)
+	}
+	feb, mar := stats.Months[0], stats.Months[1]
+	if feb.Month != "2021-02" || feb.Streams != 2 || feb.Duration != 3*time.Hour || feb.PeakViewers != 800 || feb.Views != 3000 {
+		t.Errorf("unexpected February %+v", feb)
+	}
+	if mar.Month != "2021-03" || mar.Streams != 4 || mar.Duration != 150*time.Minute {
+		t.Errorf("unexpected March %+v", mar)
+	}
+
+	if stats.LongestStreak != 3 || !stats.LongestEnd.Equal(time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("the longest streak should be 3 days ending on March 1st, got %d ending %s", stats.LongestStreak, stats.LongestEnd)
+	}
+	if stats.CurrentStreak != 2 {
+		t.Errorf("the current streak should be 2, got %d", stats.CurrentStreak)
+	}
+
+	stats = ComputeStreamStats(archs, day(3, 9), time.UTC)
+	if stats.CurrentStreak != 0 {
+		t.Errorf("the streak should be broken, got %d", stats.CurrentStreak)
+	}
+}
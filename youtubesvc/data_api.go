@@ -32,7 +32,7 @@ func NewUserYoutubeService(token string, refreshToken *string) (*UserYoutubeServ
 	ctx := context.Background()
 
 	log := logrus.WithField("svc", "YoutubeService")
-	log.WithField("token", token).WithField("refreshToken", *refreshToken).Info("Initializing user Youtube service")
+	log.Info("Initializing user Youtube service")
 
 	// Service account based oauth2 two legged integration
 	tokenObj := &oauth2.Token{
@@ -42,7 +42,6 @@ func NewUserYoutubeService(token string, refreshToken *string) (*UserYoutubeServ
 		tokenObj.RefreshToken = *refreshToken
 		tokenObj.Expiry = time.Now().Add(-10 * time.Minute)
 	}
-	log.WithField("token", token).WithField("refreshToken", tokenObj.RefreshToken).Info("Initialized user Youtube service")
 
 	credentialsJSON, err := json.Marshal(config.GoogleOauthCredentials)
 	if err != nil {
@@ -97,6 +96,7 @@ func (usvc *UserYoutubeService) SendChatMessage(livechatID string, content strin
 		},
 	}
 	sent, err := usvc.service.LiveChatMessages.Insert([]string{"snippet"}, msg).Do()
+	spend("liveChatMessages.insert", CostInsert)
 	if err != nil {
 		log.Printf("Failed to send chat message: %s", err)
 		log.Println(sent, err)
@@ -133,6 +133,7 @@ func NewYoutubeService(ctx context.Context) (*YoutubeService, error) {
 
 func (svc *YoutubeService) GetStreamInfo(videoID string) (time.Time, *time.Time, *youtube.VideoSnippet, error) {
 	resp, err := svc.service.Videos.List([]string{"liveStreamingDetails,snippet"}).Id(videoID).Do()
+	spend("videos.list", CostList)
 	if err != nil {
 		return time.Time{}, nil, nil, errors.New("Failed to get video info")
 	}
@@ -170,7 +171,8 @@ func (svc *YoutubeService) GetStreamInfo(videoID string) (time.Time, *time.Time,
 
 func (svc *YoutubeService) ListUpcomingStreams(channelID string) ([]models.YoutubeStreamRecord, error) {
 	liveRecs := []models.YoutubeStreamRecord{}
-	resp, err := svc.service.Search.List([]string{"id,snippet"}).ChannelId("UC7YXqPO3eUnxbJ6rN0z2z1Q").Type("video").EventType("upcoming").Do()
+	resp, err := svc.service.Search.List([]string{"id,snippet"}).ChannelId(channelID).Type("video").EventType("upcoming").Do()
+	spend("search.list", CostSearch)
 	if err != nil {
 		return liveRecs, err
 	}
@@ -179,45 +181,129 @@ func (svc *YoutubeService) ListUpcomingStreams(channelID string) ([]models.Youtu
 		return liveRecs, nil
 	}
 
+	ids := []string{}
 	for _, live := range resp.Items {
-		vids, err := svc.service.Videos.List([]string{"liveStreamingDetails,snippet"}).Id(live.Id.VideoId).Do()
+		ids = append(ids, live.Id.VideoId)
+	}
+
+	return svc.GetStreams(ids)
+}
+
+// GetStreams look up the current state of several streams.
+// Recently fetched videos are served from the cache, the rest are looked up 50 videos at a time.
+func (svc *YoutubeService) GetStreams(videoIDs []string) ([]models.YoutubeStreamRecord, error) {
+	recs := []models.YoutubeStreamRecord{}
+	stale := []string{}
+	for _, id := range videoIDs {
+		rec, isStream, ok := cachedVideo(id)
+		if !ok {
+			stale = append(stale, id)
+		} else if isStream {
+			recs = append(recs, rec)
+		}
+	}
+
+	for start := 0; start < len(stale); start += 50 {
+		end := start + 50
+		if end > len(stale) {
+			end = len(stale)
+		}
+
+		vids, err := svc.service.Videos.List([]string{"liveStreamingDetails,snippet,statistics"}).Id(stale[start:end]...).Do()
+		spend("videos.list", CostList)
 		if err != nil {
-			return liveRecs, err
+			return recs, err
+		}
+
+		found := make(map[string]bool)
+		for _, vid := range vids.Items {
+			found[vid.Id] = true
+			if vid.LiveStreamingDetails == nil {
+				cacheVideo(vid.Id, models.YoutubeStreamRecord{}, false)
+				continue
+			}
+			rec := VideoToStreamRecord(vid)
+			cacheVideo(vid.Id, rec, true)
+			recs = append(recs, rec)
 		}
-		vid := vids.Items[0]
-
-		t, _ := time.Parse(time.RFC3339, vid.LiveStreamingDetails.ScheduledStartTime)
-
-		rec := models.YoutubeStreamRecord{
-			PostTitle:       vid.Snippet.ChannelTitle,
-			PostLink:        "https://www.youtube.com/watch?v=" + vids.Items[0].Id,
-			PostPlan:        0,
-			YoutubeID:       vids.Items[0].Id,
-			Completed:       false,
-			ScheduledTime:   t,
-			StreamTitle:     vid.Snippet.Title,
-			StreamThumbnail: vid.Snippet.Thumbnails.High.Url,
+		// Deleted or private videos aren't returned at all
+		for _, id := range stale[start:end] {
+			if !found[id] {
+				cacheMissingVideo(id)
+			}
 		}
+	}
+
+	return recs, nil
+}
 
-		liveRecs = append(liveRecs, rec)
+// VideoToStreamRecord convert a video with live streaming details to a stream record
+func VideoToStreamRecord(vid *youtube.Video) models.YoutubeStreamRecord {
+	t, _ := time.Parse(time.RFC3339, vid.LiveStreamingDetails.ScheduledStartTime)
+
+	rec := models.YoutubeStreamRecord{
+		PostTitle:       vid.Snippet.ChannelTitle,
+		PostLink:        "https://www.youtube.com/watch?v=" + vid.Id,
+		ChannelID:       vid.Snippet.ChannelId,
+		ChannelTitle:    vid.Snippet.ChannelTitle,
+		PostPlan:        0,
+		YoutubeID:       vid.Id,
+		Completed:       false,
+		ScheduledTime:   t,
+		StreamTitle:     vid.Snippet.Title,
+		StreamThumbnail: vid.Snippet.Thumbnails.High.Url,
 	}
 
-	return liveRecs, nil
+	if vid.LiveStreamingDetails.ActualStartTime != "" {
+		rec.StartedAt, _ = time.Parse(time.RFC3339, vid.LiveStreamingDetails.ActualStartTime)
+	}
+	if vid.LiveStreamingDetails.ActualEndTime != "" {
+		rec.EndedAt, _ = time.Parse(time.RFC3339, vid.LiveStreamingDetails.ActualEndTime)
+		rec.Completed = true
+	}
+	rec.Viewers = int(vid.LiveStreamingDetails.ConcurrentViewers)
+	if vid.Statistics != nil {
+		rec.ViewCount = int(vid.Statistics.ViewCount)
+	}
+
+	return rec
+}
+
+// GetChannelTitle look up the name of a Youtube channel, which also checks that it exists
+func (svc *YoutubeService) GetChannelTitle(channelID string) (string, error) {
+	resp, err := svc.service.Channels.List([]string{"snippet"}).Id(channelID).Do()
+	spend("channels.list", CostList)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Items) == 0 {
+		return "", errors.New("Channel not found")
+	}
+
+	return resp.Items[0].Snippet.Title, nil
 }
 
+// Reasons a video has no live chat to connect to
+var (
+	ErrNoVideo = errors.New("No video")
+	ErrNotLive = errors.New("Video is not live")
+)
+
 func (svc *YoutubeService) GetLivechatID(videoID string) (string, string, error) {
 	resp, err := svc.service.Videos.List([]string{"liveStreamingDetails,snippet"}).Id(videoID).Do()
+	spend("videos.list", CostList)
 	if err != nil {
 		return "", "", errors.New("Failed to get video info")
 	}
 
 	if len(resp.Items) == 0 {
-		return "", "", errors.New("No video")
+		return "", "", ErrNoVideo
 	}
 
 	vid := resp.Items[0]
-	if vid.LiveStreamingDetails.ActiveLiveChatId == "" {
-		return "", vid.Snippet.Title, errors.New("Video is not live")
+	if vid.LiveStreamingDetails == nil || vid.LiveStreamingDetails.ActiveLiveChatId == "" {
+		return "", vid.Snippet.Title, ErrNotLive
 	}
 
 	fmt.Println("LIVE STREAMING DETAILS")
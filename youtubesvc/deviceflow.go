@@ -0,0 +1,148 @@
+package youtubesvc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/w8kerr/delubot/config"
+	"golang.org/x/oauth2"
+)
+
+// DeviceCode What the person linking an account needs to enter, and what the bot polls with
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+type deviceTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+	Error        string `json:"error"`
+}
+
+var linkHTTPClient = &http.Client{
+	Timeout: 20 * time.Second,
+}
+
+// RequestDeviceCode start linking an account with Google's device authorization flow
+func RequestDeviceCode() (DeviceCode, error) {
+	dc := DeviceCode{}
+
+	resp, err := linkHTTPClient.PostForm(config.GoogleDeviceCodeURL, url.Values{
+		"client_id": {config.GoogleClientID},
+		"scope":     {strings.Join(linkScopes, " ")},
+	})
+	if err != nil {
+		return dc, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return dc, fmt.Errorf("device code request returned %s", resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&dc)
+	if err != nil {
+		return dc, err
+	}
+	if dc.Interval <= 0 {
+		dc.Interval = 5
+	}
+	return dc, nil
+}
+
+// PollDeviceToken wait for the account to be linked, returning its token and email address
+func PollDeviceToken(ctx context.Context, dc DeviceCode) (*oauth2.Token, string, error) {
+	interval := time.Duration(dc.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(dc.ExpiresIn) * time.Second)
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-time.After(interval):
+		}
+
+		resp, err := linkHTTPClient.PostForm(config.GoogleTokenURL, url.Values{
+			"client_id":     {config.GoogleClientID},
+			"client_secret": {config.GoogleSecret},
+			"device_code":   {dc.DeviceCode},
+			"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+		})
+		if err != nil {
+			continue
+		}
+		tr := deviceTokenResponse{}
+		err = json.NewDecoder(resp.Body).Decode(&tr)
+		resp.Body.Close()
+		if err != nil {
+			return nil, "", err
+		}
+
+		switch tr.Error {
+		case "":
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		case "access_denied":
+			return nil, "", errors.New("linking was declined")
+		case "expired_token":
+			return nil, "", errors.New("the code expired")
+		default:
+			return nil, "", fmt.Errorf("linking failed, %s", tr.Error)
+		}
+
+		if tr.RefreshToken == "" {
+			return nil, "", errors.New("Google didn't return a refresh token")
+		}
+		email, err := emailFromIDToken(tr.IDToken)
+		if err != nil {
+			return nil, "", err
+		}
+		return &oauth2.Token{
+			AccessToken:  tr.AccessToken,
+			RefreshToken: tr.RefreshToken,
+			Expiry:       time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second),
+		}, email, nil
+	}
+
+	return nil, "", errors.New("the code expired")
+}
+
+// emailFromIDToken read the account's email address from the ID token.
+// It came straight from the token endpoint, so its signature isn't checked.
+func emailFromIDToken(idToken string) (string, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("Google didn't say which account was linked")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return "", err
+	}
+
+	claims := struct {
+		Email string `json:"email"`
+	}{}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return "", err
+	}
+	if claims.Email == "" {
+		return "", errors.New("Google didn't say which account was linked")
+	}
+	return claims.Email, nil
+}
@@ -0,0 +1,282 @@
+package youtubesvc
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+// ErrChatEnded the live chat is over, or gone
+var ErrChatEnded = errors.New("the live chat has ended")
+
+// Roles a chat relay can be filtered on
+const (
+	ChatRoleOwner     = "owner"
+	ChatRoleModerator = "moderators"
+	ChatRoleMember    = "members"
+	ChatRoleVerified  = "verified"
+	ChatRoleSuperChat = "superchats"
+)
+
+// ChatRoles every role a chat relay can be filtered on
+var ChatRoles = []string{ChatRoleOwner, ChatRoleModerator, ChatRoleMember, ChatRoleVerified, ChatRoleSuperChat}
+
+// ChatMessage A message from a Youtube live chat
+type ChatMessage struct {
+	ID              string
+	AuthorName      string
+	AuthorChannelID string
+	AuthorImage     string
+	Text            string
+	SuperChat       string
+	IsOwner         bool
+	IsModerator     bool
+	IsMember        bool
+	IsVerified      bool
+	PublishedAt     time.Time
+}
+
+// Badges the emoji marking what the author is in the chat
+func (msg *ChatMessage) Badges() string {
+	badges := ""
+	if msg.IsOwner {
+		badges += "🎙"
+	}
+	if msg.IsModerator {
+		badges += "🔧"
+	}
+	if msg.IsMember {
+		badges += "⭐"
+	}
+	if msg.IsVerified {
+		badges += "✔"
+	}
+	return badges
+}
+
+// ChatPage One poll of a live chat
+type ChatPage struct {
+	Messages        []ChatMessage
+	NextPageToken   string
+	PollingInterval time.Duration
+}
+
+// ListChatMessages get the chat messages after pageToken, or the most recent ones without it
+func (svc *YoutubeService) ListChatMessages(livechatID, pageToken string) (ChatPage, error) {
+	page := ChatPage{}
+
+	call := svc.service.LiveChatMessages.List(livechatID, []string{"snippet", "authorDetails"}).MaxResults(2000)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	resp, err := call.Do()
+	spend("liveChatMessages.list", CostLiveChatList)
+	if err != nil {
+		if gerr, ok := err.(*googleapi.Error); ok {
+			for _, e := range gerr.Errors {
+				if e.Reason == "liveChatEnded" || e.Reason == "liveChatNotFound" || e.Reason == "liveChatDisabled" {
+					return page, ErrChatEnded
+				}
+			}
+		}
+		return page, err
+	}
+
+	page.NextPageToken = resp.NextPageToken
+	page.PollingInterval = time.Duration(resp.PollingIntervalMillis) * time.Millisecond
+	ended := false
+	for _, item := range resp.Items {
+		if item.Snippet == nil {
+			continue
+		}
+		if item.Snippet.Type == "chatEndedEvent" {
+			ended = true
+			continue
+		}
+		msg, ok := toChatMessage(item)
+		if ok {
+			page.Messages = append(page.Messages, msg)
+		}
+	}
+
+	if ended {
+		return page, ErrChatEnded
+	}
+	return page, nil
+}
+
+func toChatMessage(item *youtube.LiveChatMessage) (ChatMessage, bool) {
+	msg := ChatMessage{
+		ID:   item.Id,
+		Text: item.Snippet.DisplayMessage,
+	}
+	msg.PublishedAt, _ = time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+
+	switch item.Snippet.Type {
+	case "textMessageEvent":
+	case "superChatEvent":
+		if item.Snippet.SuperChatDetails != nil {
+			msg.SuperChat = item.Snippet.SuperChatDetails.AmountDisplayString
+			msg.Text = item.Snippet.SuperChatDetails.UserComment
+		}
+	case "superStickerEvent":
+		if item.Snippet.SuperStickerDetails != nil {
+			msg.SuperChat = item.Snippet.SuperStickerDetails.AmountDisplayString
+			msg.Text = ""
+		}
+	default:
+		// Memberships, polls and the like aren't chat
+		return msg, false
+	}
+
+	if item.AuthorDetails != nil {
+		msg.AuthorName = item.AuthorDetails.DisplayName
+		msg.AuthorChannelID = item.AuthorDetails.ChannelId
+		msg.AuthorImage = item.AuthorDetails.ProfileImageUrl
+		msg.IsOwner = item.AuthorDetails.IsChatOwner
+		msg.IsModerator = item.AuthorDetails.IsChatModerator
+		msg.IsMember = item.AuthorDetails.IsChatSponsor
+		msg.IsVerified = item.AuthorDetails.IsVerified
+	}
+	return msg, true
+}
+
+// ChatFilter Which live chat messages get relayed.
+// A message is relayed if its author has one of the roles, or it contains one of the keywords.
+type ChatFilter struct {
+	Roles    []string
+	Keywords []string
+}
+
+// Matches whether a message passes the filter
+func (f *ChatFilter) Matches(msg ChatMessage) bool {
+	for _, role := range f.Roles {
+		switch role {
+		case ChatRoleOwner:
+			if msg.IsOwner {
+				return true
+			}
+		case ChatRoleModerator:
+			if msg.IsModerator {
+				return true
+			}
+		case ChatRoleMember:
+			if msg.IsMember {
+				return true
+			}
+		case ChatRoleVerified:
+			if msg.IsVerified {
+				return true
+			}
+		case ChatRoleSuperChat:
+			if msg.SuperChat != "" {
+				return true
+			}
+		}
+	}
+
+	text := strings.ToLower(msg.Text)
+	for _, keyword := range f.Keywords {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// How a failed chat message send should be handled
+const (
+	SendRateLimited = "ratelimited"
+	SendRetry       = "retry"
+	SendRefresh     = "refresh"
+	SendEnded       = "ended"
+	SendBanned      = "banned"
+	SendDrop        = "drop"
+)
+
+// ClassifySendError decide what to do about an error from sending a chat message.
+// Rate limited accounts rest, server and network errors are retried, auth errors need a fresh token, an account that was banned
+// from the chat can't post there anymore, and a closed chat ends the pipeline.
+func ClassifySendError(err error) string {
+	// Signing in again won't go better right away, whatever the cause
+	var serr *signInError
+	if errors.As(err, &serr) {
+		return SendRefresh
+	}
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		for _, e := range gerr.Errors {
+			switch e.Reason {
+			case "liveChatEnded", "liveChatNotFound", "liveChatDisabled":
+				return SendEnded
+			case "rateLimitExceeded", "userRateLimitExceeded":
+				return SendRateLimited
+			case "backendError":
+				return SendRetry
+			case "forbidden":
+				return SendBanned
+			}
+		}
+		switch {
+		case gerr.Code == 401:
+			return SendRefresh
+		case gerr.Code == 429:
+			return SendRateLimited
+		case gerr.Code >= 500:
+			return SendRetry
+		default:
+			return SendDrop
+		}
+	}
+
+	var rerr *oauth2.RetrieveError
+	if errors.As(err, &rerr) {
+		return SendRefresh
+	}
+
+	// Anything else is most likely the network
+	return SendRetry
+}
+
+// SplitChatText split text into chat messages of at most limit characters, between words where possible
+func SplitChatText(text string, limit int) []string {
+	if limit <= 0 {
+		return []string{}
+	}
+
+	parts := []string{}
+	current := []rune{}
+	for _, word := range strings.Fields(text) {
+		runes := []rune(word)
+
+		if len(current) > 0 && len(current)+1+len(runes) > limit {
+			parts = append(parts, string(current))
+			current = []rune{}
+		}
+
+		// Words longer than a whole message are cut wherever they have to be
+		for len(runes) > limit {
+			if len(current) > 0 {
+				parts = append(parts, string(current))
+				current = []rune{}
+			}
+			parts = append(parts, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, runes...)
+	}
+	if len(current) > 0 {
+		parts = append(parts, string(current))
+	}
+
+	return parts
+}
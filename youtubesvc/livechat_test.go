@@ -0,0 +1,93 @@
+package youtubesvc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+func Test_ChatFilter(t *testing.T) {
+	filter := ChatFilter{
+		Roles:    []string{ChatRoleModerator, ChatRoleSuperChat},
+		Keywords: []string{"Delu"},
+	}
+
+	tests := []struct {
+		msg  ChatMessage
+		want bool
+	}{
+		{ChatMessage{Text: "hello"}, false},
+		{ChatMessage{Text: "hello", IsMember: true}, false},
+		{ChatMessage{Text: "hello", IsModerator: true}, true},
+		{ChatMessage{Text: "", SuperChat: "¥500"}, true},
+		{ChatMessage{Text: "I love delutaya"}, true},
+	}
+
+	for _, test := range tests {
+		if got := filter.Matches(test.msg); got != test.want {
+			t.Errorf("Matches(%+v) = %v, want %v", test.msg, got, test.want)
+		}
+	}
+
+	empty := ChatFilter{}
+	if empty.Matches(ChatMessage{Text: "hello", IsOwner: true}) {
+		t.Errorf("an empty filter shouldn't relay anything")
+	}
+}
+
+func Test_ChatBadges(t *testing.T) {
+	msg := ChatMessage{IsOwner: true, IsMember: true}
+	if msg.Badges() != "🎙⭐" {
+		t.Errorf("unexpected badges %s", msg.Badges())
+	}
+}
+
+func Test_SplitChatText(t *testing.T) {
+	parts := SplitChatText("こんにちは みなさん hello", 11)
+	if len(parts) != 2 || parts[0] != "こんにちは みなさん" || parts[1] != "hello" {
+		t.Errorf("unexpected split %q", parts)
+	}
+
+	parts = SplitChatText("a ああああああああ b", 4)
+	if len(parts) != 4 || parts[0] != "a" || parts[1] != "ああああ" || parts[2] != "ああああ" || parts[3] != "b" {
+		t.Errorf("unexpected split %q", parts)
+	}
+
+	for _, part := range SplitChatText("デルタヤ "+strings.Repeat("デ", 450), 200) {
+		if utf8.RuneCountInString(part) > 200 {
+			t.Errorf("part is %d characters long", utf8.RuneCountInString(part))
+		}
+	}
+
+	if len(SplitChatText("   ", 200)) != 0 {
+		t.Errorf("blank text shouldn't be sent")
+	}
+}
+
+func Test_ClassifySendError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "liveChatEnded"}}}, SendEnded},
+		{&googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, SendRateLimited},
+		{&googleapi.Error{Code: 429}, SendRateLimited},
+		{&googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}, SendBanned},
+		{&googleapi.Error{Code: 401}, SendRefresh},
+		{&googleapi.Error{Code: 503}, SendRetry},
+		{&googleapi.Error{Code: 400, Errors: []googleapi.ErrorItem{{Reason: "messageTextTooLong"}}}, SendDrop},
+		{&oauth2.RetrieveError{}, SendRefresh},
+		{errors.New("connection reset"), SendRetry},
+		{&signInError{errors.New("cipher: message authentication failed")}, SendRefresh},
+	}
+
+	for _, test := range tests {
+		if got := ClassifySendError(test.err); got != test.want {
+			t.Errorf("ClassifySendError(%v) = %s, want %s", test.err, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package youtubesvc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/w8kerr/delubot/models"
+)
+
+var feedHTTPClient = &http.Client{
+	Timeout: 20 * time.Second,
+}
+
+// ChannelFeedURL the public Atom feed of a channel's latest uploads and scheduled streams
+func ChannelFeedURL(channelID string) string {
+	return "https://www.youtube.com/feeds/videos.xml?channel_id=" + channelID
+}
+
+type channelFeed struct {
+	Entries []struct {
+		VideoID string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+	} `xml:"entry"`
+}
+
+// ParseChannelFeed read the video IDs out of a channel's Atom feed
+func ParseChannelFeed(r io.Reader) ([]string, error) {
+	feed := channelFeed{}
+	err := xml.NewDecoder(r).Decode(&feed)
+	if err != nil {
+		return []string{}, err
+	}
+
+	ids := []string{}
+	for _, entry := range feed.Entries {
+		if entry.VideoID != "" {
+			ids = append(ids, entry.VideoID)
+		}
+	}
+	return ids, nil
+}
+
+// DiscoverVideos list a channel's latest videos from its feed, which doesn't cost any quota
+func DiscoverVideos(channelID string) ([]string, error) {
+	req, err := http.NewRequest("GET", ChannelFeedURL(channelID), nil)
+	if err != nil {
+		return []string{}, err
+	}
+	req.Header.Set("User-Agent", "DeluBot")
+
+	resp, err := feedHTTPClient.Do(req)
+	if err != nil {
+		return []string{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return []string{}, fmt.Errorf("channel feed returned %s", resp.Status)
+	}
+
+	return ParseChannelFeed(resp.Body)
+}
+
+// ListChannelStreams the upcoming and live streams among a channel's latest videos.
+// Only videos that aren't cached are looked up, so polling this often is cheap.
+func (svc *YoutubeService) ListChannelStreams(channelID string) ([]models.YoutubeStreamRecord, error) {
+	ids, err := DiscoverVideos(channelID)
+	if err != nil {
+		return []models.YoutubeStreamRecord{}, err
+	}
+
+	recs, err := svc.GetStreams(ids)
+	if err != nil {
+		return recs, err
+	}
+
+	res := []models.YoutubeStreamRecord{}
+	for _, rec := range recs {
+		if !rec.Completed {
+			res = append(res, rec)
+		}
+	}
+	return res, nil
+}
+
+// How long a video the API didn't return is skipped, it may only be private or processing for now
+var missingVideoTTL = 10 * time.Minute
+
+type cachedRecord struct {
+	rec       models.YoutubeStreamRecord
+	isStream  bool
+	missing   bool
+	fetchedAt time.Time
+}
+
+var videoCache = struct {
+	sync.Mutex
+	videos map[string]cachedRecord
+}{videos: make(map[string]cachedRecord)}
+
+// videoTTL how long a looked up video can be trusted, shorter the closer it is to changing state
+func videoTTL(rec models.YoutubeStreamRecord, isStream bool, now time.Time) time.Duration {
+	if !isStream || rec.Completed {
+		return 24 * time.Hour
+	}
+	if rec.IsLive() {
+		return time.Minute
+	}
+
+	untilStart := rec.ScheduledTime.Sub(now)
+	switch {
+	case untilStart < 15*time.Minute:
+		return time.Minute
+	case untilStart < 2*time.Hour:
+		return 5 * time.Minute
+	default:
+		return 30 * time.Minute
+	}
+}
+
+func cachedVideo(videoID string) (models.YoutubeStreamRecord, bool, bool) {
+	videoCache.Lock()
+	defer videoCache.Unlock()
+
+	cr, ok := videoCache.videos[videoID]
+	if !ok {
+		return models.YoutubeStreamRecord{}, false, false
+	}
+
+	now := time.Now()
+	ttl := videoTTL(cr.rec, cr.isStream, now)
+	if cr.missing {
+		ttl = missingVideoTTL
+	}
+	if now.Sub(cr.fetchedAt) > ttl {
+		delete(videoCache.videos, videoID)
+		return models.YoutubeStreamRecord{}, false, false
+	}
+	return cr.rec, cr.isStream, true
+}
+
+func cacheVideo(videoID string, rec models.YoutubeStreamRecord, isStream bool) {
+	videoCache.Lock()
+	defer videoCache.Unlock()
+
+	videoCache.videos[videoID] = cachedRecord{
+		rec:       rec,
+		isStream:  isStream,
+		fetchedAt: time.Now(),
+	}
+}
+
+// cacheMissingVideo remember for a short while that the API didn't return a video
+func cacheMissingVideo(videoID string) {
+	videoCache.Lock()
+	defer videoCache.Unlock()
+
+	videoCache.videos[videoID] = cachedRecord{
+		missing:   true,
+		fetchedAt: time.Now(),
+	}
+}
+
+// CachedVideos the number of videos currently cached
+func CachedVideos() int {
+	videoCache.Lock()
+	defer videoCache.Unlock()
+	return len(videoCache.videos)
+}
@@ -0,0 +1,68 @@
+package youtubesvc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/w8kerr/delubot/models"
+)
+
+func Test_ParseChannelFeed(t *testing.T) {
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+ <title>Delutaya</title>
+ <entry>
+  <id>yt:video:1Mm2VgxI-nA</id>
+  <yt:videoId>1Mm2VgxI-nA</yt:videoId>
+  <title>Stream</title>
+ </entry>
+ <entry>
+  <id>yt:video:abcdefghijk</id>
+  <yt:videoId>abcdefghijk</yt:videoId>
+  <title>Upload</title>
+ </entry>
+</feed>`
+
+	ids, err := ParseChannelFeed(strings.NewReader(feed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != "1Mm2VgxI-nA" || ids[1] != "abcdefghijk" {
+		t.Errorf("unexpected video IDs %v", ids)
+	}
+}
+
+func Test_VideoTTL(t *testing.T) {
+	now := time.Now()
+
+	if videoTTL(models.YoutubeStreamRecord{}, false, now) != 24*time.Hour {
+		t.Errorf("plain videos should be cached for a day")
+	}
+	live := models.YoutubeStreamRecord{StartedAt: now.Add(-time.Hour)}
+	if videoTTL(live, true, now) != time.Minute {
+		t.Errorf("live streams should be refreshed every minute")
+	}
+	soon := models.YoutubeStreamRecord{ScheduledTime: now.Add(time.Hour)}
+	if videoTTL(soon, true, now) != 5*time.Minute {
+		t.Errorf("unexpected TTL for a stream starting soon")
+	}
+	later := models.YoutubeStreamRecord{ScheduledTime: now.Add(48 * time.Hour)}
+	if videoTTL(later, true, now) != 30*time.Minute {
+		t.Errorf("unexpected TTL for a stream in two days")
+	}
+}
+
+func Test_MissingVideoCache(t *testing.T) {
+	cacheMissingVideo("missing-now")
+	if _, isStream, ok := cachedVideo("missing-now"); !ok || isStream {
+		t.Errorf("a missing video should be cached as not a stream for a while")
+	}
+
+	videoCache.Lock()
+	videoCache.videos["missing-before"] = cachedRecord{missing: true, fetchedAt: time.Now().Add(-missingVideoTTL - time.Minute)}
+	videoCache.Unlock()
+	if _, _, ok := cachedVideo("missing-before"); ok {
+		t.Errorf("a missing video should be looked up again after %s", missingVideoTTL)
+	}
+}
@@ -0,0 +1,236 @@
+package youtubesvc
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/w8kerr/delubot/config"
+)
+
+// ErrNoPoster none of the accounts can post to the chat right now
+var ErrNoPoster = errors.New("no Youtube account can post to this chat right now")
+
+// How long an account rests after being rate limited, or after its sign in failed
+var (
+	posterRateLimitRest = time.Minute
+	posterSignInRest    = 10 * time.Minute
+)
+
+// PosterAccount A Youtube account chat messages are posted with, and how it's doing
+type PosterAccount struct {
+	Email       string
+	Sent        int
+	Units       int
+	Day         string
+	Failures    int
+	LastError   string
+	LastErrorAt time.Time
+	RestUntil   time.Time
+	Banned      map[string]bool
+
+	cred config.YoutubeCredential
+	svc  *UserYoutubeService
+	// Whether svc was signed in for the last message, and hasn't posted yet
+	fresh bool
+}
+
+// signInError an account couldn't be signed in, so nothing was sent
+type signInError struct {
+	err error
+}
+
+func (e *signInError) Error() string {
+	return "sign in failed, " + e.err.Error()
+}
+
+func (e *signInError) Unwrap() error {
+	return e.err
+}
+
+// Available whether the account can post to a chat at the given time
+func (pa *PosterAccount) Available(livechatID string, now time.Time) bool {
+	return !pa.Banned[livechatID] && !now.Before(pa.RestUntil)
+}
+
+var posters = struct {
+	sync.Mutex
+	accounts []*PosterAccount
+	next     int
+}{}
+
+// loadPosters build the account list from the stored credentials, keeping the state of known accounts
+func loadPosters() {
+	known := make(map[string]*PosterAccount)
+	for _, pa := range posters.accounts {
+		known[pa.Email] = pa
+	}
+
+	accounts := []*PosterAccount{}
+	for _, cred := range config.YoutubeCredentials {
+		pa, ok := known[cred.Email]
+		if !ok {
+			pa = &PosterAccount{
+				Email:  cred.Email,
+				Banned: make(map[string]bool),
+			}
+		} else if !pa.cred.LinkedAt.Equal(cred.LinkedAt) || pa.cred.RefreshToken != cred.RefreshToken {
+			// Linked again, sign in with the new token
+			pa.svc = nil
+			pa.RestUntil = time.Time{}
+		}
+		pa.cred = cred
+		accounts = append(accounts, pa)
+	}
+	posters.accounts = accounts
+}
+
+// ReloadPosters pick up changes to the stored credentials
+func ReloadPosters() {
+	posters.Lock()
+	defer posters.Unlock()
+	loadPosters()
+}
+
+// pickPoster the preferred account if it can post, otherwise the next account in turn that can
+func pickPoster(accounts []*PosterAccount, next int, livechatID, preferred string, now time.Time) (int, bool) {
+	for i, pa := range accounts {
+		if preferred != "" && pa.Email == preferred && pa.Available(livechatID, now) {
+			return i, true
+		}
+	}
+	for i := 0; i < len(accounts); i++ {
+		n := (next + i) % len(accounts)
+		if accounts[n].Available(livechatID, now) {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// PickPoster choose the account to post the next message to a chat with, rotating through the accounts
+func PickPoster(livechatID, preferred string) (*PosterAccount, error) {
+	posters.Lock()
+	defer posters.Unlock()
+
+	if len(posters.accounts) != len(config.YoutubeCredentials) {
+		loadPosters()
+	}
+	if len(posters.accounts) == 0 {
+		return nil, errors.New("no Youtube accounts are linked")
+	}
+
+	n, ok := pickPoster(posters.accounts, posters.next, livechatID, preferred, time.Now())
+	if !ok {
+		return nil, ErrNoPoster
+	}
+	posters.next = n + 1
+	return posters.accounts[n], nil
+}
+
+// SendChatMessage post a chat message with the account, signing it in first if needed
+func (pa *PosterAccount) SendChatMessage(livechatID, text string) error {
+	posters.Lock()
+	svc := pa.svc
+	cred := pa.cred
+	posters.Unlock()
+
+	if svc == nil {
+		var err error
+		svc, err = NewCredentialService(cred)
+		if err != nil {
+			return &signInError{err}
+		}
+		posters.Lock()
+		pa.svc = svc
+		pa.fresh = true
+		posters.Unlock()
+	}
+
+	_, err := svc.SendChatMessage(livechatID, text)
+
+	posters.Lock()
+	defer posters.Unlock()
+	day := quotaDay(time.Now())
+	if pa.Day != day {
+		pa.Day = day
+		pa.Sent = 0
+		pa.Units = 0
+	}
+	pa.Units += CostInsert
+	if err == nil {
+		pa.Sent++
+		pa.Failures = 0
+		pa.fresh = false
+	}
+	return err
+}
+
+// Failed record why a message couldn't be posted with the account.
+// A rate limited account rests and one banned from the chat is left out of it, so the next message goes to another account.
+func (pa *PosterAccount) Failed(livechatID, kind string, err error) {
+	posters.Lock()
+	defer posters.Unlock()
+
+	now := time.Now()
+	pa.Failures++
+	pa.LastError = err.Error()
+	pa.LastErrorAt = now
+
+	switch kind {
+	case SendRateLimited:
+		pa.RestUntil = now.Add(posterRateLimitRest)
+	case SendBanned:
+		pa.Banned[livechatID] = true
+	case SendRefresh:
+		// Sign in again next time, and give it a rest if it couldn't sign in or that was already the fresh sign in
+		if pa.svc == nil || pa.fresh {
+			pa.RestUntil = now.Add(posterSignInRest)
+		}
+		pa.svc = nil
+		pa.fresh = false
+	}
+	log.Printf("Youtube account %s failed to post, %s", pa.Email, kind)
+}
+
+// PosterStatus A snapshot of an account's health
+type PosterStatus struct {
+	Email       string
+	Sent        int
+	Units       int
+	Failures    int
+	LastError   string
+	LastErrorAt time.Time
+	RestUntil   time.Time
+	BannedChats int
+}
+
+// PosterStatuses the health of every account
+func PosterStatuses() []PosterStatus {
+	posters.Lock()
+	defer posters.Unlock()
+
+	if len(posters.accounts) != len(config.YoutubeCredentials) {
+		loadPosters()
+	}
+
+	today := quotaDay(time.Now())
+	res := []PosterStatus{}
+	for _, pa := range posters.accounts {
+		ps := PosterStatus{
+			Email:       pa.Email,
+			Failures:    pa.Failures,
+			LastError:   pa.LastError,
+			LastErrorAt: pa.LastErrorAt,
+			RestUntil:   pa.RestUntil,
+			BannedChats: len(pa.Banned),
+		}
+		if pa.Day == today {
+			ps.Sent = pa.Sent
+			ps.Units = pa.Units
+		}
+		res = append(res, ps)
+	}
+	return res
+}
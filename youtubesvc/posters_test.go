@@ -0,0 +1,72 @@
+package youtubesvc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_PickPoster(t *testing.T) {
+	now := time.Now()
+	accounts := []*PosterAccount{
+		{Email: "a@example.com", Banned: map[string]bool{"chat1": true}},
+		{Email: "b@example.com", Banned: map[string]bool{}, RestUntil: now.Add(time.Minute)},
+		{Email: "c@example.com", Banned: map[string]bool{}},
+	}
+
+	n, ok := pickPoster(accounts, 0, "chat1", "", now)
+	if !ok || n != 2 {
+		t.Errorf("expected c, the only account that can post to chat1, got %d %v", n, ok)
+	}
+
+	n, ok = pickPoster(accounts, 3, "chat2", "", now)
+	if !ok || n != 0 {
+		t.Errorf("expected the rotation to wrap around to a, got %d %v", n, ok)
+	}
+
+	n, ok = pickPoster(accounts, 0, "chat2", "c@example.com", now)
+	if !ok || n != 2 {
+		t.Errorf("expected the preferred account c, got %d %v", n, ok)
+	}
+
+	n, ok = pickPoster(accounts, 0, "chat1", "a@example.com", now)
+	if !ok || n != 2 {
+		t.Errorf("expected a failover from the banned preferred account, got %d %v", n, ok)
+	}
+
+	n, ok = pickPoster(accounts, 0, "chat2", "b@example.com", now.Add(2*time.Minute))
+	if !ok || n != 1 {
+		t.Errorf("expected b after its rest, got %d %v", n, ok)
+	}
+
+	accounts[2].Banned["chat1"] = true
+	if _, ok := pickPoster(accounts, 0, "chat1", "", now); ok {
+		t.Errorf("no account should be able to post to chat1")
+	}
+}
+
+func Test_PosterSignInRest(t *testing.T) {
+	pa := &PosterAccount{Email: "a@example.com", Banned: map[string]bool{}, svc: &UserYoutubeService{}}
+	authErr := errors.New("token expired")
+
+	// An expired token on a long running sign in is worth one more try
+	pa.Failed("chat1", SendRefresh, authErr)
+	if pa.svc != nil || !pa.RestUntil.IsZero() {
+		t.Errorf("expected a sign in next time without a rest, got %v %s", pa.svc, pa.RestUntil)
+	}
+
+	// The fresh sign in failing too means it's not going to work for a while
+	pa.svc = &UserYoutubeService{}
+	pa.fresh = true
+	pa.Failed("chat1", SendRefresh, authErr)
+	if pa.Available("chat1", time.Now()) {
+		t.Errorf("expected a rest after the fresh sign in failed")
+	}
+
+	// So does not being able to sign in at all
+	pa.RestUntil = time.Time{}
+	pa.Failed("chat1", SendRefresh, &signInError{authErr})
+	if pa.Available("chat1", time.Now()) {
+		t.Errorf("expected a rest after the sign in failed")
+	}
+}
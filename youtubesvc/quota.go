@@ -0,0 +1,93 @@
+package youtubesvc
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/w8kerr/delubot/mongo"
+)
+
+// DailyQuota the number of units the Youtube Data API allows per day
+const DailyQuota = 10000
+
+// Cost in quota units of each API call we make
+const (
+	CostList         = 1
+	CostSearch       = 100
+	CostInsert       = 50
+	CostLiveChatList = 5
+)
+
+// The quota resets at midnight Pacific time
+var quotaLoc, _ = time.LoadLocation("America/Los_Angeles")
+
+// QuotaUsage Quota units spent on one day, by API method
+type QuotaUsage struct {
+	Day      string         `json:"_id" bson:"_id"`
+	Units    int            `json:"units" bson:"units"`
+	ByMethod map[string]int `json:"by_method" bson:"by_method"`
+}
+
+var quota = struct {
+	sync.Mutex
+	usage QuotaUsage
+}{}
+
+func quotaDay(t time.Time) string {
+	return t.In(quotaLoc).Format("2006-01-02")
+}
+
+// spend record the quota used by an API call
+func spend(method string, units int) {
+	day := quotaDay(time.Now())
+
+	quota.Lock()
+	if quota.usage.Day != day {
+		quota.usage = QuotaUsage{Day: day, ByMethod: make(map[string]int)}
+	}
+	quota.usage.Units += units
+	quota.usage.ByMethod[method] += units
+	quota.Unlock()
+
+	if mongo.MDB == nil {
+		return
+	}
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+
+	_, err := session.DB(mongo.DB_NAME).C("youtube_quota").UpsertId(day, bson.M{"$inc": bson.M{
+		"units":               units,
+		"by_method." + method: units,
+	}})
+	if err != nil {
+		log.Printf("Failed to record Youtube quota, %s", err)
+	}
+}
+
+// QuotaToday the quota spent so far today, including what was spent before a restart
+func QuotaToday() QuotaUsage {
+	day := quotaDay(time.Now())
+
+	if mongo.MDB != nil {
+		session := mongo.MDB.Clone()
+		defer session.Close()
+		session.SetMode(mgo.Strong, false)
+
+		usage := QuotaUsage{}
+		err := session.DB(mongo.DB_NAME).C("youtube_quota").FindId(day).One(&usage)
+		if err == nil {
+			return usage
+		}
+	}
+
+	quota.Lock()
+	defer quota.Unlock()
+	if quota.usage.Day != day {
+		return QuotaUsage{Day: day, ByMethod: make(map[string]int)}
+	}
+	return quota.usage
+}
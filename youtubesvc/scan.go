@@ -2,8 +2,10 @@ package youtubesvc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -18,6 +20,16 @@ import (
 var SS *YoutubeService
 var DS *discordgo.Session
 
+// How often the watched videos are scanned
+var sweepInterval = 500 * time.Second
+
+// Threads are listed newest first, so a scan stops at the first page older than the last scan.
+// New replies to older threads are only found by a full scan, which is done once a day.
+var fullScanInterval = 24 * time.Hour
+
+// Comments can take a while to show up in the list, so a bit before the last scan is looked at again
+var scanOverlap = 10 * time.Minute
+
 func InitSweeper(ds *discordgo.Session) {
 	DS = ds
 	ctx := context.Background()
@@ -31,61 +43,271 @@ func InitSweeper(ds *discordgo.Session) {
 
 func Sweeper() {
 	clog := utils.GetChannelLogger(DS, "793361959046217778")
-	sleepDuration := 500 * time.Second
 	time.Sleep(3 * time.Second)
 	for {
-		session := mongo.MDB.Clone()
-		defer session.Close()
-		session.SetMode(mgo.Strong, false)
-		db := session.DB(mongo.DB_NAME)
-		wvCol := db.C("watched_videos")
-
-		wvs := []models.WatchedVideo{}
-		err := wvCol.Find(bson.M{}).All(&wvs)
+		sweep(clog)
+		time.Sleep(sweepInterval)
+	}
+}
+
+// sweep scan every watched video once, a video that fails is tried again next time
+func sweep(clog *log.Logger) {
+	session := mongo.MDB.Clone()
+	defer session.Close()
+	session.SetMode(mgo.Strong, false)
+	db := session.DB(mongo.DB_NAME)
+	wvCol := db.C("watched_videos")
+
+	wvs := []models.WatchedVideo{}
+	err := wvCol.Find(bson.M{}).All(&wvs)
+	if err != nil {
+		log.Println("Failed to get watched videos:", err)
+		return
+	}
+
+	for _, wv := range wvs {
+		start := time.Now()
+		full := start.Sub(wv.LastFullScan) >= fullScanInterval
+		err := Scan(db, wv, full)
+		if err != nil {
+			log.Printf("Failed to scan the comments of %s: %s", wv.VideoID, err)
+			continue
+		}
+
+		set := bson.M{"last_scan": start}
+		if full {
+			set["last_full_scan"] = start
+		}
+		err = wvCol.UpdateId(wv.OID, bson.M{"$set": set})
 		if err != nil {
-			log.Printf("Failed to get watched videos")
+			log.Println("Failed to update last scan time:", err)
+			clog.Println("Failed to update last scan time:", err)
 		}
+	}
+}
+
+// Scan post the comments on a watched video that weren't seen before and pass its filters.
+// The first scan of a video only takes note of the comments that are already there.
+func Scan(db *mgo.Database, wv models.WatchedVideo, full bool) error {
+	if SS == nil {
+		return errors.New("the Youtube service isn't initialized")
+	}
+	seenCol := db.C("youtube_comments")
+	post := !wv.LastScan.IsZero()
+	since := wv.LastScan.Add(-scanOverlap)
+
+	// Watches scanned before comments were remembered have none seen yet, they go through every comment
+	// to take note of them, posting only the ones published since the last scan
+	seen, err := seenCol.Find(bson.M{"watch_id": wv.OID}).Count()
+	if err != nil {
+		return err
+	}
+	priming := post && seen == 0
+	if priming {
+		full = true
+	}
+
+	pageToken := ""
+	for {
+		call := SS.service.CommentThreads.List([]string{"id", "snippet", "replies"}).VideoId(wv.VideoID).Order("time").MaxResults(100)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		spend("commentThreads.list", CostList)
+		if err != nil {
+			return err
+		}
+
+		older := true
+		for _, ct := range resp.Items {
+			if ct.Snippet == nil || ct.Snippet.TopLevelComment == nil || ct.Snippet.TopLevelComment.Snippet == nil {
+				continue
+			}
+			published, _ := time.Parse(time.RFC3339, ct.Snippet.TopLevelComment.Snippet.PublishedAt)
+			if published.After(since) {
+				older = false
+			}
 
-		for _, wv := range wvs {
-			start := time.Now()
-			Scan(wv)
-			err := wvCol.UpdateId(wv.OID, bson.M{"$set": bson.M{"last_scan": start}})
+			comments, err := newThreadComments(seenCol, wv, ct)
 			if err != nil {
-				log.Println("Failed to update last scan time:", err)
-				clog.Println("Failed to update last scan time:", err)
-				return
+				return err
+			}
+			if priming {
+				comments = publishedSince(comments, wv.LastScan)
+			}
+			if post {
+				postComments(wv, comments)
 			}
 		}
 
-		time.Sleep(sleepDuration)
+		pageToken = resp.NextPageToken
+		if pageToken == "" || (older && !full) {
+			return nil
+		}
 	}
 }
 
-func Scan(wv models.WatchedVideo) {
-	video, err := SS.service.Videos.List([]string{"snippet"}).Id(wv.VideoID).Do()
-	if err != nil {
-		log.Println("Failed to get video:", err)
-		return
+// newThreadComments the comments of a thread the watch hasn't seen yet, marking them seen
+func newThreadComments(seenCol *mgo.Collection, wv models.WatchedVideo, ct *youtube.CommentThread) ([]models.YoutubeComment, error) {
+	top := toYoutubeComment(ct.Snippet.TopLevelComment, nil)
+	comments := []models.YoutubeComment{}
+
+	seen := models.SeenComment{}
+	err := seenCol.Find(bson.M{"watch_id": wv.OID, "comment_id": top.ID}).One(&seen)
+	if err == mgo.ErrNotFound {
+		seen = models.SeenComment{
+			WatchID:   wv.OID,
+			CommentID: top.ID,
+			SeenAt:    time.Now(),
+		}
+		err = seenCol.Insert(seen)
+		if err != nil && !mgo.IsDup(err) {
+			return comments, err
+		}
+		comments = append(comments, top)
+	} else if err != nil {
+		return comments, err
+	}
+
+	// Replies cost quota to fetch, so they're only fetched when there are new ones
+	if wv.NewThreadsOnly || ct.Snippet.TotalReplyCount <= seen.ReplyCount {
+		return comments, nil
 	}
-	videoTitle := video.Items[0].Snippet.Title
 
-	now := time.Now()
-	comments, err := GetAllComments(wv.VideoID)
+	replies, err := threadReplies(ct)
 	if err != nil {
-		log.Println("Failed to get comment threads:", err)
-		return
+		return comments, err
+	}
+	for _, r := range replies {
+		err := seenCol.Insert(models.SeenComment{
+			WatchID:   wv.OID,
+			CommentID: r.ID,
+			SeenAt:    time.Now(),
+		})
+		if mgo.IsDup(err) {
+			continue
+		}
+		if err != nil {
+			return comments, err
+		}
+		comments = append(comments, r)
 	}
-	fmt.Println("Scraped", len(comments), "in", time.Now().Sub(now))
 
-	for _, c := range comments {
-		if c.UpdatedAt.After(wv.LastScan) {
-			embed := YoutubeCommentToEmbed(c, wv.VideoID, videoTitle)
-			_, err := DS.ChannelMessageSendEmbed(wv.ChannelID, embed)
-			if err != nil {
-				log.Println("Failed to send message:", err)
+	err = seenCol.Update(bson.M{"watch_id": wv.OID, "comment_id": top.ID}, bson.M{"$set": bson.M{"reply_count": ct.Snippet.TotalReplyCount}})
+	return comments, err
+}
+
+// threadReplies every reply in a thread, the listing only includes the first few
+func threadReplies(ct *youtube.CommentThread) ([]models.YoutubeComment, error) {
+	parent := ct.Snippet.TopLevelComment
+	replies := []models.YoutubeComment{}
+
+	if ct.Replies != nil && int64(len(ct.Replies.Comments)) >= ct.Snippet.TotalReplyCount {
+		for _, c := range ct.Replies.Comments {
+			replies = append(replies, toYoutubeComment(c, parent))
+		}
+		return replies, nil
+	}
+
+	pageToken := ""
+	for {
+		call := SS.service.Comments.List([]string{"id", "snippet"}).ParentId(ct.Id).MaxResults(100)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		cs, err := call.Do()
+		spend("comments.list", CostList)
+		if err != nil {
+			return replies, err
+		}
+		for _, c := range cs.Items {
+			replies = append(replies, toYoutubeComment(c, parent))
+		}
+
+		pageToken = cs.NextPageToken
+		if pageToken == "" {
+			return replies, nil
+		}
+	}
+}
+
+func toYoutubeComment(c *youtube.Comment, parent *youtube.Comment) models.YoutubeComment {
+	yc := models.YoutubeComment{
+		ID: c.Id,
+	}
+	if c.Snippet == nil {
+		return yc
+	}
+
+	yc.AuthorDisplayName = c.Snippet.AuthorDisplayName
+	yc.AuthorProfileImageURL = c.Snippet.AuthorProfileImageUrl
+	if c.Snippet.AuthorChannelId != nil {
+		yc.AuthorChannelID = c.Snippet.AuthorChannelId.Value
+	}
+	yc.Text = c.Snippet.TextOriginal
+	if yc.Text == "" {
+		yc.Text = c.Snippet.TextDisplay
+	}
+	yc.PublishedAt, _ = time.Parse(time.RFC3339, c.Snippet.PublishedAt)
+	yc.UpdatedAt, _ = time.Parse(time.RFC3339, c.Snippet.UpdatedAt)
+
+	if parent != nil {
+		yc.ParentID = parent.Id
+		if parent.Snippet != nil {
+			yc.ReplyDisplayName = parent.Snippet.AuthorDisplayName
+			yc.ReplyText = parent.Snippet.TextOriginal
+			if yc.ReplyText == "" {
+				yc.ReplyText = parent.Snippet.TextDisplay
 			}
 		}
 	}
+	return yc
+}
+
+// publishedSince the comments published after a time
+func publishedSince(comments []models.YoutubeComment, since time.Time) []models.YoutubeComment {
+	res := []models.YoutubeComment{}
+	for _, c := range comments {
+		if c.PublishedAt.After(since) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// CommentMatches whether a comment passes a watch's filters
+func CommentMatches(wv models.WatchedVideo, c models.YoutubeComment) bool {
+	if wv.NewThreadsOnly && c.IsReply() {
+		return false
+	}
+	if wv.TalentOnly && (wv.YoutubeChannelID == "" || c.AuthorChannelID != wv.YoutubeChannelID) {
+		return false
+	}
+	if len(wv.Keywords) == 0 {
+		return true
+	}
+
+	text := strings.ToLower(c.Text)
+	for _, keyword := range wv.Keywords {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+func postComments(wv models.WatchedVideo, comments []models.YoutubeComment) {
+	for _, c := range comments {
+		if !CommentMatches(wv, c) {
+			continue
+		}
+		embed := YoutubeCommentToEmbed(c, wv.VideoID, wv.VideoTitle)
+		_, err := DS.ChannelMessageSendEmbed(wv.ChannelID, embed)
+		if err != nil {
+			log.Println("Failed to send message:", err)
+		}
+	}
 }
 
 func YoutubeCommentToEmbed(c models.YoutubeComment, videoID, videoTitle string) *discordgo.MessageEmbed {
@@ -95,6 +317,11 @@ func YoutubeCommentToEmbed(c models.YoutubeComment, videoID, videoTitle string)
 		desc += fmt.Sprintf("\n──────────────\nIn reply to:\n%s\n\n%s", c.ReplyDisplayName, c.ReplyText)
 	}
 
+	url := "https://www.youtube.com/watch?v=" + videoID
+	if c.ID != "" {
+		url += "&lc=" + c.ID
+	}
+
 	embed := &discordgo.MessageEmbed{
 		Color: 3066993,
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
@@ -102,7 +329,7 @@ func YoutubeCommentToEmbed(c models.YoutubeComment, videoID, videoTitle string)
 		},
 		Author: &discordgo.MessageEmbedAuthor{
 			Name: c.AuthorDisplayName,
-			URL:  "https://www.youtube.com/watch?v=" + videoID,
+			URL:  url,
 		},
 		Description: desc,
 		Footer: &discordgo.MessageEmbedFooter{
@@ -114,65 +341,68 @@ func YoutubeCommentToEmbed(c models.YoutubeComment, videoID, videoTitle string)
 	return embed
 }
 
-func ProcessCommentThread(ct *youtube.CommentThread, comments *[]models.YoutubeComment) error {
-	t, _ := time.Parse(time.RFC3339, ct.Snippet.TopLevelComment.Snippet.UpdatedAt)
-	*comments = append(*comments, models.YoutubeComment{
-		AuthorDisplayName:     ct.Snippet.TopLevelComment.Snippet.AuthorDisplayName,
-		AuthorProfileImageURL: ct.Snippet.TopLevelComment.Snippet.AuthorProfileImageUrl,
-		Text:                  ct.Snippet.TopLevelComment.Snippet.TextOriginal,
-		UpdatedAt:             t,
-	})
-
-	if ct.Snippet.TotalReplyCount > 0 {
-		cs, err := SS.service.Comments.List([]string{"id", "snippet"}).ParentId(ct.Id).Do()
-		if err != nil {
-			return err
-		}
+// GetVideoSnippet the title and channel of a video
+func (svc *YoutubeService) GetVideoSnippet(videoID string) (*youtube.VideoSnippet, error) {
+	resp, err := svc.service.Videos.List([]string{"snippet"}).Id(videoID).Do()
+	spend("videos.list", CostList)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Items) == 0 || resp.Items[0].Snippet == nil {
+		return nil, errors.New("Video not found")
+	}
+	return resp.Items[0].Snippet, nil
+}
 
-		for _, c := range cs.Items {
-			t, _ := time.Parse(time.RFC3339, c.Snippet.UpdatedAt)
-			*comments = append(*comments, models.YoutubeComment{
-				AuthorDisplayName:     c.Snippet.AuthorDisplayName,
-				AuthorProfileImageURL: c.Snippet.AuthorProfileImageUrl,
-				Text:                  c.Snippet.TextOriginal,
-				ReplyDisplayName:      ct.Snippet.TopLevelComment.Snippet.AuthorDisplayName,
-				ReplyText:             ct.Snippet.TopLevelComment.Snippet.TextOriginal,
-				UpdatedAt:             t,
-			})
-		}
+// WatchVideo start watching a video's comments in a channel, or change the filters of an existing watch
+func WatchVideo(db *mgo.Database, wv models.WatchedVideo) (models.WatchedVideo, error) {
+	col := db.C("watched_videos")
+
+	existing := models.WatchedVideo{}
+	err := col.Find(bson.M{"channel_id": wv.ChannelID, "video_id": wv.VideoID}).One(&existing)
+	if err == mgo.ErrNotFound {
+		wv.OID = bson.NewObjectId()
+		wv.LastScan = time.Time{}
+		wv.LastFullScan = time.Time{}
+		return wv, col.Insert(wv)
+	}
+	if err != nil {
+		return wv, err
 	}
 
-	return nil
+	// The comments it already went through stay seen
+	wv.OID = existing.OID
+	wv.LastScan = existing.LastScan
+	wv.LastFullScan = existing.LastFullScan
+	wv.CreatedBy = existing.CreatedBy
+	wv.CreatedAt = existing.CreatedAt
+	return wv, col.UpdateId(wv.OID, wv)
 }
 
-func GetAllComments(videoID string) ([]models.YoutubeComment, error) {
-	var err error
-	var resp *youtube.CommentThreadListResponse
-	comments := []models.YoutubeComment{}
+// UnwatchVideo stop watching a video's comments in a channel
+func UnwatchVideo(db *mgo.Database, channelID, videoID string) (bool, error) {
+	col := db.C("watched_videos")
 
-	resp, err = SS.service.CommentThreads.List([]string{"id", "snippet"}).VideoId(videoID).Do()
-	if err != nil {
-		return comments, err
+	wv := models.WatchedVideo{}
+	err := col.Find(bson.M{"channel_id": channelID, "video_id": videoID}).One(&wv)
+	if err == mgo.ErrNotFound {
+		return false, nil
 	}
-	for _, ct := range resp.Items {
-		err = ProcessCommentThread(ct, &comments)
-		if err != nil {
-			return comments, err
-		}
+	if err != nil {
+		return false, err
 	}
 
-	for resp.NextPageToken != "" {
-		resp, err = SS.service.CommentThreads.List([]string{"id", "snippet"}).VideoId(videoID).PageToken(resp.NextPageToken).Do()
-		if err != nil {
-			return comments, err
-		}
-		for _, ct := range resp.Items {
-			err = ProcessCommentThread(ct, &comments)
-			if err != nil {
-				return comments, err
-			}
-		}
+	err = col.RemoveId(wv.OID)
+	if err != nil {
+		return false, err
 	}
+	_, err = db.C("youtube_comments").RemoveAll(bson.M{"watch_id": wv.OID})
+	return true, err
+}
 
-	return comments, nil
+// ChannelWatches the videos whose comments are posted in a channel
+func ChannelWatches(db *mgo.Database, channelID string) ([]models.WatchedVideo, error) {
+	wvs := []models.WatchedVideo{}
+	err := db.C("watched_videos").Find(bson.M{"channel_id": channelID}).Sort("created_at").All(&wvs)
+	return wvs, err
 }
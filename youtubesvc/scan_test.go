@@ -0,0 +1,48 @@
+package youtubesvc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/w8kerr/delubot/models"
+)
+
+func Test_CommentMatches(t *testing.T) {
+	thread := models.YoutubeComment{ID: "a", AuthorChannelID: "UCviewer", Text: "When is the next Minecraft stream?"}
+	reply := models.YoutubeComment{ID: "b", AuthorChannelID: "UCtalent", ParentID: "a", Text: "Tomorrow!"}
+
+	cases := []struct {
+		name    string
+		wv      models.WatchedVideo
+		comment models.YoutubeComment
+		want    bool
+	}{
+		{"no filters", models.WatchedVideo{}, reply, true},
+		{"threads only skips replies", models.WatchedVideo{NewThreadsOnly: true}, reply, false},
+		{"threads only keeps threads", models.WatchedVideo{NewThreadsOnly: true}, thread, true},
+		{"talent only keeps the talent", models.WatchedVideo{YoutubeChannelID: "UCtalent", TalentOnly: true}, reply, true},
+		{"talent only skips viewers", models.WatchedVideo{YoutubeChannelID: "UCtalent", TalentOnly: true}, thread, false},
+		{"talent only without a known channel", models.WatchedVideo{TalentOnly: true}, reply, false},
+		{"keyword matches any case", models.WatchedVideo{Keywords: []string{"minecraft"}}, thread, true},
+		{"keyword missing", models.WatchedVideo{Keywords: []string{"karaoke", "ASMR"}}, thread, false},
+	}
+	for _, c := range cases {
+		if got := CommentMatches(c.wv, c.comment); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func Test_PublishedSince(t *testing.T) {
+	lastScan := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	comments := []models.YoutubeComment{
+		{ID: "old", PublishedAt: lastScan.Add(-time.Hour)},
+		{ID: "edited", PublishedAt: lastScan.Add(-time.Hour), UpdatedAt: lastScan.Add(time.Hour)},
+		{ID: "new", PublishedAt: lastScan.Add(time.Minute)},
+	}
+
+	got := publishedSince(comments, lastScan)
+	if len(got) != 1 || got[0].ID != "new" {
+		t.Errorf("got %v, want only the new comment", got)
+	}
+}
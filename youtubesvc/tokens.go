@@ -0,0 +1,251 @@
+package youtubesvc
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/w8kerr/delubot/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+// TokenKeyEnv the environment variable with the key Youtube tokens are encrypted with.
+// It's kept out of the database so a copy of the database doesn't give the accounts away.
+const TokenKeyEnv = "DELUBOT_TOKEN_KEY"
+
+// ErrNoTokenKey the token key isn't set
+var ErrNoTokenKey = errors.New(TokenKeyEnv + " is not set")
+
+// The scopes a linked account grants, enough to post in live chats
+var linkScopes = []string{"https://www.googleapis.com/auth/youtube", "email"}
+
+func tokenKey() ([]byte, error) {
+	secret := os.Getenv(TokenKeyEnv)
+	if secret == "" {
+		return nil, ErrNoTokenKey
+	}
+	key := sha256.Sum256([]byte(secret))
+	return key[:], nil
+}
+
+// CanEncryptTokens whether tokens can be stored, which they only are encrypted
+func CanEncryptTokens() bool {
+	_, err := tokenKey()
+	return err == nil
+}
+
+// EncryptToken encrypt a token to be stored
+func EncryptToken(token string) (string, error) {
+	key, err := tokenKey()
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(token), nil)), nil
+}
+
+// DecryptToken decrypt a stored token
+func DecryptToken(encrypted string) (string, error) {
+	key, err := tokenKey()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("encrypted token is too short")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("could not decrypt token, was the key changed?")
+	}
+	return string(plain), nil
+}
+
+// LinkedCredential the credentials to store for an account that was just linked
+func LinkedCredential(email string, tok *oauth2.Token, linkedBy string) (config.YoutubeCredential, error) {
+	cred := config.YoutubeCredential{
+		Email:    email,
+		Expiry:   tok.Expiry,
+		LinkedBy: linkedBy,
+		LinkedAt: time.Now(),
+	}
+	err := setCredentialToken(&cred, tok)
+	return cred, err
+}
+
+func setCredentialToken(cred *config.YoutubeCredential, tok *oauth2.Token) error {
+	access, err := EncryptToken(tok.AccessToken)
+	if err != nil {
+		return err
+	}
+	refresh, err := EncryptToken(tok.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	cred.EncryptedOauthToken = access
+	cred.EncryptedRefreshToken = refresh
+	cred.Expiry = tok.Expiry
+	// Tokens stored by hand are dropped as soon as they can be stored encrypted
+	cred.OauthToken = ""
+	cred.RefreshToken = ""
+	return nil
+}
+
+// credentialToken the token stored for an account
+func credentialToken(cred config.YoutubeCredential) (*oauth2.Token, error) {
+	if cred.EncryptedRefreshToken == "" {
+		// Stored by hand, without a known expiry, so it's refreshed right away
+		return &oauth2.Token{
+			AccessToken:  cred.OauthToken,
+			RefreshToken: cred.RefreshToken,
+			Expiry:       time.Now().Add(-10 * time.Minute),
+		}, nil
+	}
+
+	access, err := DecryptToken(cred.EncryptedOauthToken)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := DecryptToken(cred.EncryptedRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		Expiry:       cred.Expiry,
+	}, nil
+}
+
+// linkOauthConfig the client accounts are linked and refreshed with
+func linkOauthConfig() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     config.GoogleClientID,
+		ClientSecret: config.GoogleSecret,
+		Endpoint: oauth2.Endpoint{
+			TokenURL:  config.GoogleTokenURL,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		Scopes: linkScopes,
+	}
+}
+
+// credentialOauthConfig the client that issued an account's token, which is the only one that can refresh it
+func credentialOauthConfig(cred config.YoutubeCredential) (*oauth2.Config, error) {
+	if !cred.LinkedAt.IsZero() {
+		return linkOauthConfig(), nil
+	}
+
+	credentialsJSON, err := json.Marshal(config.GoogleOauthCredentials)
+	if err != nil {
+		return nil, err
+	}
+	return google.ConfigFromJSON(credentialsJSON, "https://www.googleapis.com/auth/youtube.readonly https://www.googleapis.com/auth/userinfo.profile https://www.googleapis.com/auth/youtube.force-ssl openid")
+}
+
+var credentialSaveLock sync.Mutex
+
+// persistingTokenSource A token source that stores every refreshed token, so a restart doesn't have to refresh again
+type persistingTokenSource struct {
+	base  oauth2.TokenSource
+	email string
+	last  string
+}
+
+func (ts *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := ts.base.Token()
+	if err != nil {
+		return tok, err
+	}
+	if tok.AccessToken == ts.last {
+		return tok, nil
+	}
+	ts.last = tok.AccessToken
+
+	credentialSaveLock.Lock()
+	defer credentialSaveLock.Unlock()
+
+	cred := config.GetYoutubeCredential(ts.email)
+	if cred == nil {
+		return tok, nil
+	}
+	err = setCredentialToken(cred, tok)
+	if err == ErrNoTokenKey {
+		// Accounts stored by hand keep working without a key, the token just isn't saved
+		return tok, nil
+	}
+	if err == nil {
+		err = config.SetYoutubeCredential(*cred)
+	}
+	if err != nil {
+		log.Printf("Failed to save the refreshed token of %s, %s", ts.email, err)
+	}
+	return tok, nil
+}
+
+// NewCredentialService sign in to Youtube as a stored account
+func NewCredentialService(cred config.YoutubeCredential) (*UserYoutubeService, error) {
+	ctx := context.Background()
+
+	tok, err := credentialToken(cred)
+	if err != nil {
+		return &UserYoutubeService{}, err
+	}
+	conf, err := credentialOauthConfig(cred)
+	if err != nil {
+		return &UserYoutubeService{}, err
+	}
+
+	ts := &persistingTokenSource{
+		base:  conf.TokenSource(ctx, tok),
+		email: cred.Email,
+		last:  tok.AccessToken,
+	}
+	service, err := youtube.NewService(ctx, option.WithHTTPClient(oauth2.NewClient(ctx, ts)))
+	if err != nil {
+		return &UserYoutubeService{}, err
+	}
+
+	return &UserYoutubeService{
+		service: service,
+	}, nil
+}
@@ -0,0 +1,144 @@
+package youtubesvc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/w8kerr/delubot/config"
+	"golang.org/x/oauth2"
+)
+
+func Test_EncryptToken(t *testing.T) {
+	os.Setenv(TokenKeyEnv, "test key")
+	defer os.Unsetenv(TokenKeyEnv)
+
+	encrypted, err := EncryptToken("1//refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted == "1//refresh-token" {
+		t.Fatal("token wasn't encrypted")
+	}
+
+	again, _ := EncryptToken("1//refresh-token")
+	if again == encrypted {
+		t.Errorf("the same token should encrypt differently every time")
+	}
+
+	plain, err := DecryptToken(encrypted)
+	if err != nil || plain != "1//refresh-token" {
+		t.Errorf("round trip gave %q, %v", plain, err)
+	}
+
+	os.Setenv(TokenKeyEnv, "another key")
+	if _, err := DecryptToken(encrypted); err == nil {
+		t.Errorf("a token shouldn't decrypt with another key")
+	}
+
+	os.Unsetenv(TokenKeyEnv)
+	if _, err := EncryptToken("token"); err != ErrNoTokenKey {
+		t.Errorf("expected ErrNoTokenKey, got %v", err)
+	}
+}
+
+func Test_LinkedCredential(t *testing.T) {
+	os.Setenv(TokenKeyEnv, "test key")
+	defer os.Unsetenv(TokenKeyEnv)
+
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+	cred, err := LinkedCredential("bot@example.com", &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: expiry}, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.OauthToken != "" || cred.RefreshToken != "" {
+		t.Errorf("tokens shouldn't be stored in plain text")
+	}
+
+	tok, err := credentialToken(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "access" || tok.RefreshToken != "refresh" || !tok.Expiry.Equal(expiry) {
+		t.Errorf("unexpected token %+v", tok)
+	}
+
+	// Tokens never end up in what gets printed
+	printed, _ := json.Marshal(cred)
+	if strings.Contains(string(printed), cred.EncryptedOauthToken) || strings.Contains(string(printed), cred.EncryptedRefreshToken) {
+		t.Errorf("printed credential contains a token: %s", printed)
+	}
+}
+
+func Test_PollDeviceToken(t *testing.T) {
+	claims, _ := json.Marshal(map[string]string{"email": "bot@example.com"})
+	idToken := "header." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+
+	polls := 0
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/device/code":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"device_code":      "device",
+				"user_code":        "ABCD-EFGH",
+				"verification_url": "https://www.google.com/device",
+				"expires_in":       60,
+				"interval":         5,
+			})
+		case "/token":
+			if r.Form.Get("device_code") != "device" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			polls++
+			if polls < 2 {
+				w.WriteHeader(http.StatusPreconditionRequired)
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "access",
+				"refresh_token": "refresh",
+				"expires_in":    3599,
+				"id_token":      idToken,
+			})
+		}
+	}))
+	defer stub.Close()
+
+	deviceURL, tokenURL := config.GoogleDeviceCodeURL, config.GoogleTokenURL
+	defer func() {
+		config.GoogleDeviceCodeURL, config.GoogleTokenURL = deviceURL, tokenURL
+	}()
+	config.GoogleDeviceCodeURL = stub.URL + "/device/code"
+	config.GoogleTokenURL = stub.URL + "/token"
+
+	dc, err := RequestDeviceCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dc.UserCode != "ABCD-EFGH" || dc.Interval != 5 {
+		t.Errorf("unexpected device code %+v", dc)
+	}
+
+	// Don't wait the real interval
+	dc.Interval = 0
+	tok, email, err := PollDeviceToken(context.Background(), dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email != "bot@example.com" || tok.AccessToken != "access" || tok.RefreshToken != "refresh" {
+		t.Errorf("unexpected result %s %+v", email, tok)
+	}
+	if polls != 2 {
+		t.Errorf("expected to poll until authorized, polled %d times", polls)
+	}
+}